@@ -0,0 +1,299 @@
+package bot
+
+/*
+Package bot runs in-process bots.  A Bot registers commands and message listeners and then joins rooms.  In each room it is a room.Client so it shows up in who and recieves everything sent to the room.  Messages starting with Prefix are run as commands, for example "!roll 2d6".  Every message is also given to the bot's listeners.  Bots reply by sending messages to the room with Room.Send.
+
+Messages are queued and handled one at a time on the bot's own goroutine so a slow bot never blocks Room.Send.  When the queue is full messages are dropped.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//Prefix starts a bot command.
+const Prefix = "!"
+
+//queueSize is the number of messages a bot can have waiting.
+const queueSize = 100
+
+var ErrNotStarted = errors.New("bot: The bot has not been started.")
+var ErrNameTaken = errors.New("bot: The bot's name belongs to an account.")
+
+//HandlerFunc handles a command or a message for a bot.
+type HandlerFunc func(ctx *Context)
+
+//Context is passed to a handler with the message being handled.
+type Context struct {
+	Bot     *Bot
+	Room    *room.Room
+	Message message.Message
+	Sender  string   //name of the client that sent the message or "" if it has none
+	Text    string   //text of the message or "" if it has none
+	Command string   //command name without the prefix or "" if the message isn't a command
+	Args    []string //arguments following the command
+	Data    clientdata.ClientData
+}
+
+//Reply sends text to the room the message came from.
+func (ctx *Context) Reply(text string) {
+	ctx.Bot.Say(ctx.Room, text)
+}
+
+//command is a registered command.
+type command struct {
+	help    string
+	handler HandlerFunc
+}
+
+//event is a message recieved in a room.
+type event struct {
+	room *room.Room
+	msg  message.Message
+}
+
+//Bot is an in-process bot.
+type Bot struct {
+	name      string
+	commands  map[string]command
+	listeners []HandlerFunc
+	rooms     *room.RoomList
+	data      clientdata.Factory
+	joined    map[string]*member
+	lock      *sync.Mutex
+	inbox     chan event
+	done      chan bool
+	stop      *sync.Once
+}
+
+//New returns a bot named name with no commands.  Every bot answers help with its list of commands.
+func New(name string) *Bot {
+	b := new(Bot)
+	b.name = name
+	b.commands = make(map[string]command)
+	b.joined = make(map[string]*member)
+	b.lock = new(sync.Mutex)
+	b.inbox = make(chan event, queueSize)
+	b.done = make(chan bool)
+	b.stop = new(sync.Once)
+	b.Command("help", "lists commands", func(ctx *Context) { ctx.Reply(ctx.Bot.Help()) })
+	return b
+}
+
+//Name returns the bot's name.
+func (b *Bot) Name() string {
+	return b.name
+}
+
+//Command registers a command.  The handler is called when a message in one of the bot's rooms starts with Prefix followed by name.
+func (b *Bot) Command(name, help string, handler HandlerFunc) {
+	b.commands[strings.ToLower(name)] = command{help, handler}
+}
+
+//Listen registers a handler that is given every message sent to the bot's rooms including commands.
+func (b *Bot) Listen(handler HandlerFunc) {
+	b.listeners = append(b.listeners, handler)
+}
+
+//Help returns the bot's commands and their help text.
+func (b *Bot) Help() string {
+	names := make([]string, 0, len(b.commands))
+	for name := range b.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	s := b.name + " commands:"
+	for _, name := range names {
+		s += fmt.Sprintf("\r\n%v%v - %v", Prefix, name, b.commands[name].help)
+	}
+	return s
+}
+
+//attach sets the rooms and data the bot uses.
+func (b *Bot) attach(rooms *room.RoomList, data clientdata.Factory) {
+	b.rooms = rooms
+	b.data = data
+}
+
+//Start attaches the bot to rooms and data and starts handling messages.  The bot's name is reserved as a bot account so clients can't register it.  It returns ErrNameTaken without starting if the name already belongs to an account.
+func (b *Bot) Start(rooms *room.RoomList, data clientdata.Factory) error {
+	err := data.Create(clientdata.ServerRecord).CreateBot(b.name)
+	if err == clientdata.ErrClientExists {
+		owner, err := data.Create("").BotOwner(b.name)
+		switch {
+		case err == clientdata.ErrNotBot || (err == nil && owner != clientdata.ServerRecord):
+			return ErrNameTaken
+		case err != nil:
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	b.attach(rooms, data)
+	go b.run()
+	return nil
+}
+
+//Stop leaves all the bot's rooms and stops handling messages.
+func (b *Bot) Stop() {
+	b.lock.Lock()
+	for name, m := range b.joined {
+		m.room.Remove(m)
+		delete(b.joined, name)
+	}
+	b.lock.Unlock()
+	b.stop.Do(func() { close(b.done) })
+}
+
+//run handles queued messages until the bot is stopped.
+func (b *Bot) run() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case e := <-b.inbox:
+			b.handle(e.room, e.msg)
+		}
+	}
+}
+
+//drain handles every queued message and returns.  It is used instead of run when testing.
+func (b *Bot) drain() {
+	for {
+		select {
+		case e := <-b.inbox:
+			b.handle(e.room, e.msg)
+		default:
+			return
+		}
+	}
+}
+
+//Join adds the bot to the room name creating the room if it doesn't exist.
+func (b *Bot) Join(name string) error {
+	if b.rooms == nil {
+		return ErrNotStarted
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.joined[name]; ok {
+		return nil
+	}
+	rm := b.rooms.FindRoom(name)
+	if rm == nil {
+		rm = room.NewRoom(name)
+		err := b.rooms.Add(rm)
+		if err != nil {
+			return err
+		}
+	}
+	m := &member{b, rm}
+	b.joined[name] = m
+	rm.Add(m)
+	return nil
+}
+
+//Leave removes the bot from the room name.
+func (b *Bot) Leave(name string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if m, ok := b.joined[name]; ok {
+		m.room.Remove(m)
+		delete(b.joined, name)
+	}
+}
+
+//Say sends text to rm as the bot.
+func (b *Bot) Say(rm *room.Room, text string) {
+	rm.Send(message.NewSendMessage(text, b.name))
+}
+
+//enqueue queues a message from rm.  Messages sent by the bot are ignored.
+func (b *Bot) enqueue(rm *room.Room, m message.Message) {
+	if sender, _ := contents(m); sender == b.name {
+		return
+	}
+	select {
+	case b.inbox <- event{rm, m}:
+	default:
+		log.Printf("bot: %v queue full, dropping message in %v", b.name, rm.Name())
+	}
+}
+
+//handle runs the listeners and any command for a message.
+func (b *Bot) handle(rm *room.Room, m message.Message) {
+	ctx := &Context{Bot: b, Room: rm, Message: m}
+	ctx.Sender, ctx.Text = contents(m)
+	if b.data != nil {
+		ctx.Data = b.data.Create(b.name)
+	}
+	fields := strings.Fields(ctx.Text)
+	if len(fields) > 0 && strings.HasPrefix(fields[0], Prefix) && len(fields[0]) > len(Prefix) {
+		ctx.Command = strings.ToLower(fields[0][len(Prefix):])
+		ctx.Args = fields[1:]
+	}
+	for _, l := range b.listeners {
+		b.call(l, ctx)
+	}
+	if c, ok := b.commands[ctx.Command]; ok {
+		b.call(c.handler, ctx)
+	}
+}
+
+//call runs handler recovering from any panic so one bad handler can't take down the server.
+func (b *Bot) call(handler HandlerFunc, ctx *Context) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("bot: %v panic handling %q: %v", b.name, ctx.Text, r)
+		}
+	}()
+	handler(ctx)
+}
+
+//contents returns the sender and text of messages that have them.
+func contents(m message.Message) (sender, text string) {
+	switch msg := m.(type) {
+	case *message.SendMessage:
+		return msg.Sender, msg.Text
+	case *message.EmoteMessage:
+		return msg.Sender, msg.Text
+	case *message.RestMessage:
+		return msg.Name, msg.Text
+	case *message.WebhookMessage:
+		return msg.Name, msg.Text
+	case *message.TellMessage:
+		return msg.Sender, msg.Text
+	}
+	return "", ""
+}
+
+//member is the bot's presence in a room.
+type member struct {
+	bot  *Bot
+	room *room.Room
+}
+
+//Name returns the bot's name.
+func (m *member) Name() string {
+	return m.bot.name
+}
+
+//Equals returns true if other has the bot's name.
+func (m *member) Equals(other room.Client) bool {
+	return m.Name() == other.Name()
+}
+
+//Recieve queues the message for the bot.  Ephemeral messages are dropped.  It never blocks.
+func (m *member) Recieve(msg message.Message) {
+	if _, ok := msg.(message.Ephemeral); ok {
+		return
+	}
+	m.bot.enqueue(m.room, msg)
+}
@@ -0,0 +1,163 @@
+package bot
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"strings"
+	"testing"
+)
+
+func TestCommandAndListener(t *testing.T) {
+	b := New("Echo")
+	heard := 0
+	b.Listen(func(ctx *Context) { heard++ })
+	b.Command("echo", "repeats what you say", func(ctx *Context) {
+		ctx.Reply(ctx.Sender + " said " + strings.Join(ctx.Args, " "))
+	})
+	h := NewHarness(b)
+	if err := h.Join("Lobby"); err != nil {
+		t.Fatal("Error joining: ", err)
+	}
+	h.Say("Lobby", "Fred", "hello")
+	h.Say("Lobby", "Fred", "!ECHO hi there")
+	h.Say("Lobby", "Fred", "!unknown")
+	replies := h.Replies("Lobby")
+	if len(replies) != 1 || replies[0] != "Fred said hi there" {
+		t.Errorf("Wrong replies expected: [Fred said hi there], got: %q", replies)
+	}
+	if heard != 3 {
+		t.Errorf("Expected the listener to hear 3 messages but not the bot's own reply, got: %v", heard)
+	}
+}
+
+func TestJoinCreatesRoomAndShowsInWho(t *testing.T) {
+	h := NewHarness(New("Echo"))
+	if err := h.Join("Games"); err != nil {
+		t.Fatal("Error joining: ", err)
+	}
+	rm := h.Rooms.FindRoom("Games")
+	if rm == nil || !rm.Present("Echo") {
+		t.Fatal("Expected the bot to be in the new room")
+	}
+	h.Bot.Leave("Games")
+	if rm.Present("Echo") {
+		t.Error("Expected the bot to leave the room")
+	}
+}
+
+func TestHelp(t *testing.T) {
+	h := NewHarness(NewDice())
+	_ = h.Join("Lobby")
+	h.Say("Lobby", "Fred", "!help")
+	replies := h.Replies("Lobby")
+	if len(replies) != 1 || !strings.Contains(replies[0], "!roll") || !strings.Contains(replies[0], "!flip") {
+		t.Errorf("Expected help listing the commands, got: %q", replies)
+	}
+}
+
+func TestPanickingHandler(t *testing.T) {
+	b := New("Broken")
+	b.Command("boom", "panics", func(ctx *Context) { panic("boom") })
+	h := NewHarness(b)
+	_ = h.Join("Lobby")
+	h.Say("Lobby", "Fred", "!boom")
+	h.Say("Lobby", "Fred", "!help")
+	if len(h.Replies("Lobby")) != 1 {
+		t.Error("Expected the bot to keep working after a handler panicked")
+	}
+}
+
+func TestDice(t *testing.T) {
+	dice.Seed(1)
+	h := NewHarness(NewDice())
+	_ = h.Join("Lobby")
+	h.Say("Lobby", "Fred", "!roll 3d6")
+	h.Say("Lobby", "Fred", "!roll 500d6")
+	replies := h.Replies("Lobby")
+	if len(replies) != 2 {
+		t.Fatalf("Expected 2 replies, got: %q", replies)
+	}
+	if !strings.HasPrefix(replies[0], "Fred rolled 3d6: ") || strings.Count(replies[0], "+") != 2 {
+		t.Errorf("Wrong roll reply: %q", replies[0])
+	}
+	if !strings.Contains(replies[1], "can't roll") {
+		t.Errorf("Expected an error for too many dice, got: %q", replies[1])
+	}
+	var tests = []struct {
+		roll  string
+		n     int
+		sides int
+		ok    bool
+	}{
+		{"2d6", 2, 6, true},
+		{"d20", 1, 20, true},
+		{"12", 1, 12, true},
+		{"0d6", 0, 0, false},
+		{"2d1", 0, 0, false},
+		{"xd6", 0, 0, false},
+	}
+	for _, tt := range tests {
+		n, sides, err := parseDice(tt.roll)
+		if (err == nil) != tt.ok || n != tt.n || sides != tt.sides {
+			t.Errorf("parseDice(%q) => %v, %v, %v", tt.roll, n, sides, err)
+		}
+	}
+}
+
+func TestGreeter(t *testing.T) {
+	h := NewHarness(NewGreeter())
+	_ = h.Join("Lobby")
+	h.Send("Lobby", message.NewJoinMessage("Fred"))
+	h.Send("Lobby", message.NewLeaveMessage("Fred"))
+	replies := h.Replies("Lobby")
+	if len(replies) != 1 || replies[0] != "Welcome to Lobby, Fred!" {
+		t.Errorf("Wrong greeting, got: %q", replies)
+	}
+}
+
+func TestStartReservesName(t *testing.T) {
+	b := NewDice()
+	data := NewHarness(b).Data
+	if err := b.Start(b.rooms, data); err != nil {
+		t.Fatal("Error starting bot: ", err)
+	}
+	defer b.Stop()
+	owner, err := data.Create("").BotOwner(b.Name())
+	if err != nil || owner != clientdata.ServerRecord {
+		t.Errorf("Expected the bot's name to be reserved by the server, got: %q %v", owner, err)
+	}
+}
+
+//closedFactory makes client data with account creation disabled.
+type closedFactory struct {
+	data clientdata.DataStore
+}
+
+func (f closedFactory) Create(name string) clientdata.ClientData {
+	return clientdata.NewDataAccess(name, f.data, true)
+}
+
+func TestStartWithAccountCreationDisabled(t *testing.T) {
+	b := NewDice()
+	data := closedFactory{filedata.NewMemData()}
+	if err := b.Start(room.NewRoomList(10), data); err != nil {
+		t.Fatal("Error starting bot: ", err)
+	}
+	defer b.Stop()
+	if owner, err := data.Create("").BotOwner(b.Name()); err != nil || owner != clientdata.ServerRecord {
+		t.Errorf("Expected the bot's name to be reserved by the server, got: %q %v", owner, err)
+	}
+}
+
+func TestStartRefusesAccountName(t *testing.T) {
+	b := NewDice()
+	data := NewHarness(b).Data
+	if err := data.Create(b.Name()).NewClient("password"); err != nil {
+		t.Fatal("Error creating client: ", err)
+	}
+	if err := b.Start(b.rooms, data); err != ErrNameTaken {
+		t.Error("Expected ErrNameTaken, got: ", err)
+	}
+}
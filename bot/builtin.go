@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/message"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Builtins are the bots that come with the server by the kind used in the config.
+var Builtins = map[string]func() *Bot{
+	"dice":    NewDice,
+	"greeter": NewGreeter,
+}
+
+//Limits on a dice roll.
+const (
+	maxDice  = 100
+	maxSides = 1000
+)
+
+//lockedSource is a rand.Source that is safe to share between goroutines.
+type lockedSource struct {
+	lock *sync.Mutex
+	src  rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.src.Seed(seed)
+}
+
+//dice is the random number generator used by the dice bot.  Tests may seed it.
+var dice = rand.New(&lockedSource{new(sync.Mutex), rand.NewSource(time.Now().UnixNano())})
+
+//parseDice parses a roll like 2d6, d20 or 3.  A bare number is the number of sides of one die.
+func parseDice(s string) (n, sides int, err error) {
+	s = strings.ToLower(s)
+	n = 1
+	if i := strings.Index(s, "d"); i >= 0 {
+		if i > 0 {
+			n, err = strconv.Atoi(s[:i])
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+		s = s[i+1:]
+	}
+	sides, err = strconv.Atoi(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n < 1 || n > maxDice || sides < 2 || sides > maxSides {
+		return 0, 0, fmt.Errorf("roll out of range")
+	}
+	return n, sides, nil
+}
+
+//NewDice returns a bot that rolls dice and flips coins.
+func NewDice() *Bot {
+	b := New("Dice")
+	b.Command("roll", "rolls dice like 2d6 or d20, 1d6 if none are given", func(ctx *Context) {
+		roll := "1d6"
+		if len(ctx.Args) > 0 {
+			roll = ctx.Args[0]
+		}
+		n, sides, err := parseDice(roll)
+		if err != nil {
+			ctx.Reply(fmt.Sprintf("%v: I can't roll %q.  Try something like 2d6 with up to %v dice of up to %v sides.", ctx.Sender, roll, maxDice, maxSides))
+			return
+		}
+		rolls := make([]string, n)
+		total := 0
+		for i := range rolls {
+			r := dice.Intn(sides) + 1
+			total += r
+			rolls[i] = strconv.Itoa(r)
+		}
+		if n == 1 {
+			ctx.Reply(fmt.Sprintf("%v rolled %v: %v", ctx.Sender, roll, total))
+			return
+		}
+		ctx.Reply(fmt.Sprintf("%v rolled %v: %v = %v", ctx.Sender, roll, strings.Join(rolls, " + "), total))
+	})
+	b.Command("flip", "flips a coin", func(ctx *Context) {
+		side := "heads"
+		if dice.Intn(2) == 1 {
+			side = "tails"
+		}
+		ctx.Reply(fmt.Sprintf("%v flipped %v.", ctx.Sender, side))
+	})
+	return b
+}
+
+//NewGreeter returns a bot that welcomes clients when they join its rooms.
+func NewGreeter() *Bot {
+	b := New("Greeter")
+	b.Listen(func(ctx *Context) {
+		if join, ok := ctx.Message.(*message.JoinMessage); ok && !join.Left && join.Subject != ctx.Bot.Name() {
+			ctx.Reply(fmt.Sprintf("Welcome to %v, %v!", ctx.Room.Name(), join.Subject))
+		}
+	})
+	return b
+}
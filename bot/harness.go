@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+)
+
+//Harness runs a bot against its own rooms and in-memory data for testing.  Messages are handled as soon as they are sent instead of on the bot's goroutine so tests don't have to wait.
+type Harness struct {
+	Bot   *Bot
+	Rooms *room.RoomList
+	Data  clientdata.Factory
+	seen  map[string]*recorder
+}
+
+//NewHarness returns a Harness for b using filedata.NewMemDataFactory.
+func NewHarness(b *Bot) *Harness {
+	h := new(Harness)
+	h.Bot = b
+	h.Rooms = room.NewRoomList(100)
+	h.Data = filedata.NewMemDataFactory()
+	h.seen = make(map[string]*recorder)
+	b.attach(h.Rooms, h.Data)
+	return h
+}
+
+//Join adds the bot to the room name and starts recording what is sent there.
+func (h *Harness) Join(name string) error {
+	err := h.Bot.Join(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := h.seen[name]; !ok {
+		r := new(recorder)
+		h.seen[name] = r
+		h.Rooms.FindRoom(name).Add(r)
+	}
+	return nil
+}
+
+//Send sends m to the room name and has the bot handle it.
+func (h *Harness) Send(name string, m message.Message) {
+	rm := h.Rooms.FindRoom(name)
+	if rm == nil {
+		return
+	}
+	rm.Send(m)
+	h.Bot.drain()
+}
+
+//Say sends text to the room name from sender and has the bot handle it.
+func (h *Harness) Say(name, sender, text string) {
+	h.Send(name, message.NewSendMessage(text, sender))
+}
+
+//Messages returns every message sent to the room name since the bot joined it.
+func (h *Harness) Messages(name string) []message.Message {
+	if r, ok := h.seen[name]; ok {
+		return r.messages
+	}
+	return nil
+}
+
+//Replies returns the text of the messages the bot has sent to the room name.
+func (h *Harness) Replies(name string) []string {
+	replies := make([]string, 0)
+	for _, m := range h.Messages(name) {
+		if sender, text := contents(m); sender == h.Bot.Name() {
+			replies = append(replies, text)
+		}
+	}
+	return replies
+}
+
+//recorder is a room.Client that keeps the messages it recieves.
+type recorder struct {
+	messages []message.Message
+}
+
+func (r *recorder) Name() string {
+	return "@harness"
+}
+
+func (r *recorder) Equals(other room.Client) bool {
+	return r.Name() == other.Name()
+}
+
+func (r *recorder) Recieve(m message.Message) {
+	r.messages = append(r.messages, m)
+}
@@ -6,19 +6,29 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"github.com/DavidAFox/Chat/bot"
 	"github.com/DavidAFox/Chat/client"
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/clientdata/datafactory"
+	"github.com/DavidAFox/Chat/clientdata/postgres"
 	chathttp "github.com/DavidAFox/Chat/connections/http"
 	"github.com/DavidAFox/Chat/connections/telnet"
+	"github.com/DavidAFox/Chat/files"
+	"github.com/DavidAFox/Chat/filter"
+	"github.com/DavidAFox/Chat/lockout"
 	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
 	"github.com/DavidAFox/Chat/room"
+	"github.com/DavidAFox/Chat/scheduler"
+	"github.com/DavidAFox/Chat/webhook"
 	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -44,6 +54,16 @@ type config struct {
 	Origin               string
 	MaxRooms             int
 	DisableNewAccounts   bool
+	Admins               []string
+	Bots                 map[string][]string
+	IdleAwayMinutes      int
+	UploadDir            string
+	MaxUploadMB          int64
+	UploadTypes          []string
+	FileURL              string
+	Filter               filter.Config
+	RateLimits           client.Limits
+	RegistrationLimit    ratelimit.Rate
 }
 
 //configure loads the config file.
@@ -63,15 +83,16 @@ func configure(filename string) (c *config) {
 
 type telnetServer struct {
 	rooms       *room.RoomList
-	chatlog     io.WriteCloser
+	clients     *client.Factory
 	cls         chan bool
 	ln          net.Listener
 	done        bool
 	datafactory clientdata.Factory
+	lockout     *lockout.Tracker
 }
 
 //NewTelnetServerTLS creates a telnet server using TLS.
-func NewTelnetServerTLS(rooms *room.RoomList, chl io.WriteCloser, c *config, datafactory clientdata.Factory) *telnetServer {
+func NewTelnetServerTLS(rooms *room.RoomList, clients *client.Factory, c *config, datafactory clientdata.Factory, guard *lockout.Tracker) *telnetServer {
 	ts := new(telnetServer)
 	var err error
 	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
@@ -86,13 +107,14 @@ func NewTelnetServerTLS(rooms *room.RoomList, chl io.WriteCloser, c *config, dat
 	}
 	ts.cls = make(chan bool, 1)
 	ts.rooms = rooms
-	ts.chatlog = chl
+	ts.clients = clients
 	ts.done = false
 	ts.datafactory = datafactory
+	ts.lockout = guard
 	return ts
 }
 
-func NewTelnetServer(rooms *room.RoomList, chl io.WriteCloser, c *config, datafactory clientdata.Factory) *telnetServer {
+func NewTelnetServer(rooms *room.RoomList, clients *client.Factory, c *config, datafactory clientdata.Factory, guard *lockout.Tracker) *telnetServer {
 	ts := new(telnetServer)
 	var err error
 	ts.ln, err = net.Listen("tcp", net.JoinHostPort(c.ListeningIP, c.ListeningPort))
@@ -101,9 +123,10 @@ func NewTelnetServer(rooms *room.RoomList, chl io.WriteCloser, c *config, datafa
 	}
 	ts.cls = make(chan bool, 1)
 	ts.rooms = rooms
-	ts.chatlog = chl
+	ts.clients = clients
 	ts.done = false
 	ts.datafactory = datafactory
+	ts.lockout = guard
 	return ts
 }
 
@@ -125,80 +148,220 @@ Outerloop:
 			if err != nil && ts.done == false {
 				log.Println(err)
 			}
-			if conn != nil {
-				go telnet.TelnetLogin(conn, ts.rooms, ts.chatlog, ts.datafactory.Create(""))
+			if conn != nil && !ts.banned(conn) {
+				go telnet.TelnetLogin(conn, ts.rooms, ts.clients, ts.datafactory.Create(""), ts.lockout)
 			}
 		}
 	}
 }
 
+//banned returns true and closes the connection if its address is banned.
+func (ts *telnetServer) banned(conn net.Conn) bool {
+	ban, err := ts.datafactory.Create("").CheckBan("", conn.RemoteAddr().String())
+	if err != nil {
+		log.Println("Error checking ban: ", err)
+		return false
+	}
+	if ban == nil {
+		return false
+	}
+	_, err = io.WriteString(conn, "This address is banned from the server.\r\n")
+	if err != nil {
+		log.Println(err)
+	}
+	conn.Close()
+	return true
+}
+
+//fileStore returns the store for uploaded files or nil if uploads are turned off because there is no UploadDir.
+func fileStore(c *config) files.Store {
+	if c.UploadDir == "" {
+		return nil
+	}
+	store, err := files.NewDiskStore(c.UploadDir)
+	if err != nil {
+		log.Panic(err)
+	}
+	return store
+}
+
+//auditLockout returns the function the lockout tracker calls for repeated login failures and lockouts.  It records them in the audit log as done by the server to the account.
+func auditLockout(df clientdata.Factory) func(lockout.Event) {
+	return func(e lockout.Event) {
+		log.Println("audit:", e)
+		detail := strconv.Itoa(e.Failures) + " failures"
+		if !e.Until.IsZero() {
+			detail += " until " + e.Until.Format(time.RFC3339)
+		}
+		if _, err := df.Create(clientdata.ServerRecord).Audit(e.Kind, e.Name, e.Addr, detail); err != nil {
+			log.Println("Error auditing lockout: ", err)
+		}
+	}
+}
+
 //serverHTTPTLS sets up the http handlers and then runs ListenAndServeTLS.
-func serverHTTPTLS(rooms *room.RoomList, chl io.WriteCloser, c *config, df clientdata.Factory) {
+func serverHTTPTLS(rooms *room.RoomList, chl io.WriteCloser, c *config, df clientdata.Factory, clients *client.Factory, guard *lockout.Tracker, store files.Store, signer *files.Signer) {
 	mux := http.NewServeMux()
-	room := chathttp.NewRoomHandler(chathttp.Options{RoomList: rooms, ChatLog: chl, DataFactory: df, ClientFactory: client.NewFactory(rooms, chl, df), Origin: c.Origin})
+	room := chathttp.NewRoomHandler(chathttp.Options{RoomList: rooms, ChatLog: chl, DataFactory: df, ClientFactory: clients, Origin: c.Origin, Lockout: guard, Files: store, MaxUploadSize: c.MaxUploadMB << 20, UploadTypes: c.UploadTypes, BaseURL: c.FileURL, Signer: signer})
 	mux.Handle("/", room)
-	rest := newRestHandler(rooms, chl)
+	rest := newRestHandler(rooms, chl, df, clients)
 	mux.Handle("/rest/", rest)
-	err := http.ListenAndServeTLS(net.JoinHostPort(c.TLSHTTPListeningIP, c.TLSHTTPListeningPort), c.CertFile, c.KeyFile, mux)
+	mux.Handle(webhook.IncomingPath, incomingHandler(rooms, chl, df, clients))
+	err := http.ListenAndServeTLS(net.JoinHostPort(c.TLSHTTPListeningIP, c.TLSHTTPListeningPort), c.CertFile, c.KeyFile, chathttp.Compress(mux))
 	if err != nil {
 		log.Fatal("ListenAndServeTLS: ", err)
 	}
 }
 
 //serverHTTP sets up the http handlers and then runs ListenAndServe
-func serverHTTP(rooms *room.RoomList, chl io.WriteCloser, c *config, df clientdata.Factory) {
+func serverHTTP(rooms *room.RoomList, chl io.WriteCloser, c *config, df clientdata.Factory, clients *client.Factory, guard *lockout.Tracker, store files.Store, signer *files.Signer) {
 	mux := http.NewServeMux()
-	room := chathttp.NewRoomHandler(chathttp.Options{RoomList: rooms, ChatLog: chl, DataFactory: df, ClientFactory: client.NewFactory(rooms, chl, df), Origin: c.Origin})
+	room := chathttp.NewRoomHandler(chathttp.Options{RoomList: rooms, ChatLog: chl, DataFactory: df, ClientFactory: clients, Origin: c.Origin, Lockout: guard, Files: store, MaxUploadSize: c.MaxUploadMB << 20, UploadTypes: c.UploadTypes, BaseURL: c.FileURL, Signer: signer})
 	mux.Handle("/", room)
-	rest := newRestHandler(rooms, chl)
+	rest := newRestHandler(rooms, chl, df, clients)
 	mux.Handle("/rest/", rest)
-	err := http.ListenAndServe(net.JoinHostPort(c.HTTPListeningIP, c.HTTPListeningPort), mux)
+	mux.Handle(webhook.IncomingPath, incomingHandler(rooms, chl, df, clients))
+	err := http.ListenAndServe(net.JoinHostPort(c.HTTPListeningIP, c.HTTPListeningPort), chathttp.Compress(mux))
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
 
 }
 
-//restHandler is the http.Handler for handling the REST API
+//incomingHandler returns the handler for incoming webhooks with their messages rate limited and checked by the content filter.
+func incomingHandler(rooms *room.RoomList, chl io.WriteCloser, df clientdata.Factory, clients *client.Factory) *webhook.IncomingHandler {
+	h := webhook.NewIncomingHandler(rooms, chl, df)
+	h.SetLimiter(clients.APILimiter())
+	h.SetFilter(clients.CheckText)
+	return h
+}
+
+//restHandler is the http.Handler for handling the REST API.  Requests must carry an API key in the X-API-Key header or as "Authorization: Key <key>".
 type restHandler struct {
-	rooms *room.RoomList
-	chl   io.WriteCloser
+	rooms   *room.RoomList
+	chl     io.WriteCloser
+	data    clientdata.Factory
+	clients *client.Factory
+	limit   *ratelimit.Limiter
 }
 
-//newRestHandler initializes a new restHandler.
-func newRestHandler(rooms *room.RoomList, chl io.WriteCloser) *restHandler {
+//newRestHandler initializes a new restHandler.  Messages are checked with the settings of clients.
+func newRestHandler(rooms *room.RoomList, chl io.WriteCloser, df clientdata.Factory, clients *client.Factory) *restHandler {
 	m := new(restHandler)
 	m.rooms = rooms
 	m.chl = chl
+	m.data = df
+	m.clients = clients
+	m.limit = clients.APILimiter()
 	return m
 }
 
+//apiKey returns the key sent with the request or "" if there isn't one.
+func apiKey(rq *http.Request) string {
+	if key := rq.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	auth := rq.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Key ") {
+		return strings.TrimSpace(auth[len("Key "):])
+	}
+	return ""
+}
+
 //ServeHTTP handles the restServer's http requests.
 func (m *restHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 	roomName := rq.URL.Path[len("/rest/"):]
+	var command string
+	switch rq.Method {
+	case "GET":
+		command = clientdata.ScopeRead
+	case "POST":
+		command = clientdata.ScopeSend
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	key, err := m.data.Create("").AuthenticateAPIKey(apiKey(rq))
+	if err != nil {
+		log.Println("Error authenticating API key: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		w.Header().Set("WWW-Authenticate", "Key")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if !key.Allows(roomName, command) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	banned, err := m.banned(key.Name, rq.RemoteAddr)
+	if err != nil {
+		log.Println("Error checking ban: ", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if banned {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
 	room := m.rooms.FindRoom(roomName)
 	if room == nil {
 		log.Println("ServeHTTP: room not found ", roomName)
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
-	if rq.Method == "GET" {
-		m.getMessages(room, w)
+	if command == clientdata.ScopeRead {
+		m.getMessages(room, w, rq)
+	} else {
+		m.sendMessages(room, key, w, rq)
 	}
-	if rq.Method == "POST" {
-		m.sendMessages(room, w, rq)
+}
+
+//banned returns true if the account name, the client that owns it if it's a bot, or the address addr is banned.
+func (m *restHandler) banned(name, addr string) (bool, error) {
+	data := m.data.Create("")
+	ban, err := data.CheckBan(name, addr)
+	if err != nil || ban != nil {
+		return ban != nil, err
 	}
+	owner, err := data.BotOwner(name)
+	if err == clientdata.ErrNotBot || owner == clientdata.ServerRecord {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ban, err = data.CheckBan(owner, "")
+	return ban != nil, err
 }
 
-//sendMessages handles REST requests for messages and writes them to the response.
-func (m *restHandler) sendMessages(room *room.Room, w http.ResponseWriter, rq *http.Request) {
+//sendMessages handles REST requests to send a message to the room.  The message is sent as the account the API key belongs to.
+func (m *restHandler) sendMessages(room *room.Room, key *clientdata.APIKey, w http.ResponseWriter, rq *http.Request) {
+	if wait := m.limit.Take("key " + key.ID); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
 	dec := json.NewDecoder(rq.Body)
 	message := new(message.RestMessage)
 	err := dec.Decode(message)
 	if err != nil {
 		log.Println("Error decoding messages in sendMessages", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
+	message.Name = key.Name
 	message.Time = time.Now()
+	if m.clients.Shadowbans().Shadowbanned(key.Name) {
+		return
+	}
+	if message.Text, err = m.clients.CheckText(key.Name, room.Name(), message.Text); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
 	room.Send(message)
 	m.log(message.String())
 }
@@ -212,8 +375,11 @@ func (m *restHandler) log(s string) {
 	}
 }
 
-//GetMessage handles REST request for messages and writes them to the response.
-func (m *restHandler) getMessages(room *room.Room, w http.ResponseWriter) {
+//GetMessage handles REST request for messages and writes them to the response.  The room's message version is sent as the ETag so polling clients can use If-None-Match.
+func (m *restHandler) getMessages(room *room.Room, w http.ResponseWriter, rq *http.Request) {
+	if chathttp.NotModified(w, rq, "rest-"+room.MessageVersion()) {
+		return
+	}
 	enc := json.NewEncoder(w)
 	messages := room.GetMessages()
 	err := enc.Encode(messages)
@@ -233,10 +399,77 @@ func (nl NoLog) Close() error {
 	return nil
 }
 
+//migrate runs the migrate command on the Postgres database in the config.  up or no argument brings the schema up to date, down undoes the newest migration, status lists the migrations and a number migrates up or down to that version.
+func migrate(c *config, args []string) error {
+	if c.DatabaseType != "postgres" {
+		return fmt.Errorf("migrate: DatabaseType is %q but only postgres databases need migrating", c.DatabaseType)
+	}
+	db, err := postgres.Connect(c.DatabaseLogin, c.DatabasePassword, c.DatabaseName, c.DatabaseIP, c.DatabasePort)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	current, err := db.Version()
+	if err != nil {
+		return err
+	}
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	target := postgres.Latest
+	switch command {
+	case "up":
+	case "down":
+		if current == 0 {
+			return fmt.Errorf("migrate: there are no migrations to undo")
+		}
+		target = current - 1
+	case "status":
+		migrations, err := postgres.Migrations()
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := "pending"
+			if m.Version <= current {
+				state = "applied"
+			}
+			fmt.Printf("%4d %-20v %v\n", m.Version, m.Name, state)
+		}
+		return nil
+	default:
+		target, err = strconv.Atoi(command)
+		if err != nil || target < 0 {
+			return fmt.Errorf("migrate: unknown command %q, use up, down, status or a version number", command)
+		}
+	}
+	if err = db.Migrate(target); err != nil {
+		return err
+	}
+	current, err = db.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Schema is at version", current)
+	return nil
+}
+
 func main() {
 	loc := flag.String("config", "Config", "the location of the config file")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: chat [-config file] [migrate [up | down | status | N]]")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 	c := configure(*loc)
+	if flag.Arg(0) == "migrate" {
+		err := migrate(c, flag.Args()[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	rooms := room.NewRoomList(c.MaxRooms)
 	defer rooms.Close()
 	var chl io.WriteCloser
@@ -250,29 +483,77 @@ func main() {
 	} else {
 		chl = new(NoLog)
 	}
+	contentFilter, err := filter.New(c.Filter)
+	if err != nil {
+		log.Panic("Error in Filter config: ", err)
+	}
 	df, err := datafactory.New(c.DatabaseType, c.DatabaseLogin, c.DatabasePassword, c.DatabaseName, c.DatabaseIP, c.DatabasePort, c.DisableNewAccounts)
 	if err != nil {
 		log.Panic(err)
 	}
+	err = df.Create("").SetAdmins(c.Admins)
+	if err != nil {
+		log.Println("Error setting admins: ", err)
+	}
+	shadowbans := client.NewShadowList()
+	err = shadowbans.Load(df.Create(""))
+	if err != nil {
+		log.Println("Error loading shadowbans: ", err)
+	}
+	rooms.SetShadow(shadowbans)
+	hooks := webhook.New(df, webhook.Options{})
+	defer hooks.Close()
+	rooms.SetHook(hooks)
+	for kind, botRooms := range c.Bots {
+		newBot, ok := bot.Builtins[kind]
+		if !ok {
+			log.Println("Unknown bot: ", kind)
+			continue
+		}
+		b := newBot()
+		err = b.Start(rooms, df)
+		if err != nil {
+			log.Println("Error starting bot ", b.Name(), ": ", err)
+			continue
+		}
+		defer b.Stop()
+		for _, name := range botRooms {
+			err = b.Join(name)
+			if err != nil {
+				log.Println("Error joining bot ", b.Name(), " to ", name, ": ", err)
+			}
+		}
+	}
+	clients := client.NewFactory(rooms, chl, df, client.Options{Limits: c.RateLimits, Filter: contentFilter, Shadowbans: shadowbans, IdleAway: time.Duration(c.IdleAwayMinutes) * time.Minute})
+	sched := scheduler.New(rooms, chl, df, 0)
+	sched.SetGate(clients)
+	sched.Start()
+	defer sched.Stop()
+	guard := lockout.New(lockout.Options{Registrations: c.RegistrationLimit, Audit: auditLockout(df)})
+	store := fileStore(c)
+	signer, err := files.NewSigner(nil)
+	if err != nil {
+		log.Panic(err)
+	}
 	if c.ListeningPort != "" {
-		tserv := NewTelnetServer(rooms, chl, c, df)
+		tserv := NewTelnetServer(rooms, clients, c, df, guard)
 		fmt.Println("Starting Telnet Server on Port ", c.ListeningPort)
 		go tserv.Start()
 		defer tserv.Stop()
 	}
 	if c.TLSListeningPort != "" {
-		tlstserv := NewTelnetServerTLS(rooms, chl, c, df)
+		tlstserv := NewTelnetServerTLS(rooms, clients, c, df, guard)
 		fmt.Println("Starting TLS Telnet Server on Port ", c.TLSListeningPort)
 		go tlstserv.Start()
 		defer tlstserv.Stop()
 	}
 	if c.TLSHTTPListeningPort != "" {
 		fmt.Println("Starting TLS HTTP Server on Port ", c.TLSHTTPListeningPort)
-		go serverHTTPTLS(rooms, chl, c, df)
+		go serverHTTPTLS(rooms, chl, c, df, clients, guard, store, signer)
 	}
 	if c.HTTPListeningPort != "" {
 		fmt.Println("Starting HTTP Server on Port ", c.HTTPListeningPort)
-		go serverHTTP(rooms, chl, c, df)
+		go serverHTTP(rooms, chl, c, df, clients, guard, store, signer)
 	}
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
@@ -3,6 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/DavidAFox/Chat/client"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/lockout"
+	"github.com/DavidAFox/Chat/room"
+	"net/http"
+	"net/http/httptest"
 	//	"github.com/DavidAFox/Chat/chattest"
 	//	"github.com/DavidAFox/Chat/clientdata"
 	//	httpcon "github.com/DavidAFox/Chat/connections/http"
@@ -12,8 +19,10 @@ import (
 	//	"net/http"
 	//	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 //Server settings for running the test.
@@ -63,11 +72,82 @@ func TestConfigure(t *testing.T) {
 		fmt.Println("Error encoding in Testconfigure: ", err)
 	}
 	conf := configure("Config_test")
-	if *conf != fconf {
+	if !reflect.DeepEqual(*conf, fconf) {
 		t.Errorf("configure() %v => %v, want %v", fconf, conf, fconf)
 	}
 }
 
+func TestRestHandlerRequiresAPIKey(t *testing.T) {
+	rooms := room.NewRoomList(10)
+	defer rooms.Close()
+	if err := rooms.Add(room.NewRoom("Other")); err != nil {
+		t.Fatal("Error adding room in TestRestHandlerRequiresAPIKey: ", err)
+	}
+	df := filedata.NewMemDataFactory()
+	err := df.Create("Fred").CreateBot("Builder")
+	if err != nil {
+		t.Fatal("Error creating bot in TestRestHandlerRequiresAPIKey: ", err)
+	}
+	key, _, err := df.Create("Fred").CreateAPIKey("Builder", []string{"Lobby"}, []string{"send"})
+	if err != nil {
+		t.Fatal("Error creating key in TestRestHandlerRequiresAPIKey: ", err)
+	}
+	rest := newRestHandler(rooms, new(NoLog), df, client.NewFactory(rooms, new(NoLog), df, client.Options{}))
+	requests := []struct {
+		method string
+		room   string
+		key    string
+		status int
+	}{
+		{"POST", "Lobby", "", http.StatusUnauthorized},
+		{"POST", "Lobby", key + "0", http.StatusUnauthorized},
+		{"POST", "Other", key, http.StatusForbidden},
+		{"GET", "Lobby", key, http.StatusForbidden},
+		{"POST", "Lobby", key, http.StatusOK},
+	}
+	for _, tt := range requests {
+		rq := httptest.NewRequest(tt.method, "/rest/"+tt.room, strings.NewReader(`{"Name":"Mallory","Text":"build passed"}`))
+		rq.Header.Set("X-API-Key", tt.key)
+		w := httptest.NewRecorder()
+		rest.ServeHTTP(w, rq)
+		if w.Code != tt.status {
+			t.Errorf("%v %v with key %q => %v, want %v", tt.method, tt.room, tt.key, w.Code, tt.status)
+		}
+	}
+	messages := rooms.FindRoom("Lobby").GetMessages()
+	if len(messages) != 1 || !strings.Contains(messages[0], "[Builder]: build passed") {
+		t.Error("Expected message stamped with the key's account got: ", messages)
+	}
+	_ = df.Create("Admin").Ban(clientdata.BanAccount, "fred", "", time.Time{})
+	rq := httptest.NewRequest("POST", "/rest/Lobby", strings.NewReader(`{"Text":"still here"}`))
+	rq.Header.Set("X-API-Key", key)
+	w := httptest.NewRecorder()
+	rest.ServeHTTP(w, rq)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected a bot whose owner is banned to be refused, got: %v", w.Code)
+	}
+}
+
+func TestAuditLockout(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	guard := lockout.New(lockout.Options{AccountThreshold: 2, AuditThreshold: 1, Audit: auditLockout(df)})
+	guard.Failure("Fred", "10.0.0.1:5000")
+	guard.Failure("fred", "10.0.0.1:5000")
+	events, err := df.Create("").AuditLog(clientdata.AuditQuery{})
+	if err != nil {
+		t.Fatal("Error reading audit log: ", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected a failure and a lockout in the audit log, got: %+v", events)
+	}
+	if e := events[0]; e.Action != lockout.EventLockout || e.Actor != clientdata.ServerRecord || e.Target != "fred" || e.Source != "10.0.0.1" || !strings.HasPrefix(e.Detail, "2 failures until ") {
+		t.Errorf("Wrong lockout event: %+v", e)
+	}
+	if e := events[1]; e.Action != lockout.EventFailure || e.Target != "Fred" || e.Detail != "1 failures" {
+		t.Errorf("Wrong failure event: %+v", e)
+	}
+}
+
 /*
 //NewTestHTTPServer sets up an http test server with the roomhandler and resthandler.
 func NewTestHTTPServer(rooms *room.RoomList, chl *os.File, conf *config, df clientdata.Factory) *httptest.Server {
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"log"
+	"strings"
+)
+
+//CreateBot creates a bot account owned by the client.
+func (cl *Client) CreateBot(name string) *Response {
+	if name == "" {
+		return NewResponse(false, 22, "You must enter a name for the bot.", nil)
+	}
+	err := cl.data.CreateBot(name)
+	switch {
+	case err == clientdata.ErrInvalidName:
+		return NewResponse(false, 20, "Invalid name.  Name must be alphanumeric characters only.", nil)
+	case err == clientdata.ErrClientExists:
+		return NewResponse(false, 10, "A client with that name already exists.", nil)
+	case err == clientdata.ErrTooManyBots:
+		return NewResponse(false, 87, fmt.Sprintf("You can't own more than %v bots.", clientdata.MaxBots), nil)
+	case err == clientdata.ErrAccountCreationDisabled:
+		return NewResponse(false, 0, "Account creation has been disabled.", nil)
+	case err != nil:
+		log.Println("Error CreateBot: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		return NewResponse(true, 0, fmt.Sprintf("Bot %v created.  Use apikey create %v to make a key for it.", name, name), nil)
+	}
+}
+
+//Bots lists the bots the client owns.
+func (cl *Client) Bots() *Response {
+	list, err := cl.data.Bots()
+	if err != nil {
+		log.Println("Error Bots: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Bots:"
+	for _, i := range list {
+		sresp = sresp + "\r\n" + i
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//canManageKeys returns true if the client may manage the API keys for account.  Clients can manage their own keys and the keys of bots they own.  Admins can manage any keys.
+func (cl *Client) canManageKeys(account string) bool {
+	if account == cl.Name() || cl.isAdmin() {
+		return true
+	}
+	owner, err := cl.data.BotOwner(account)
+	if err != nil && err != clientdata.ErrNotBot {
+		log.Println("Error BotOwner: ", err)
+	}
+	return owner == cl.Name()
+}
+
+//APIKey runs the API key subcommands create, list and revoke.  Each may be given an account name first to manage one of the client's bots.
+func (cl *Client) APIKey(sub string, args []string) *Response {
+	account := cl.Name()
+	if len(args) > 0 && !strings.Contains(args[0], "=") && !(strings.ToLower(sub) == "revoke" && len(args) == 1) {
+		account = args[0]
+		args = args[1:]
+	}
+	if !cl.canManageKeys(account) {
+		return notPermitted()
+	}
+	switch strings.ToLower(sub) {
+	case "create":
+		return cl.CreateAPIKey(account, args)
+	case "", "list":
+		return cl.APIKeys(account)
+	case "revoke":
+		if len(args) == 0 {
+			return NewResponse(false, 22, "You must enter the id of the key to revoke.", nil)
+		}
+		return cl.RevokeAPIKey(account, args[0])
+	default:
+		return NewResponse(false, 70, "Invalid Command.  Use apikey create, list or revoke.", nil)
+	}
+}
+
+//CreateAPIKey makes a new key for account.  args may contain rooms=room1,room2 and commands=read,send to limit the key.
+func (cl *Client) CreateAPIKey(account string, args []string) *Response {
+	if ex, err := cl.data.ClientExists(account); !ex {
+		if err != nil {
+			log.Println(err)
+		}
+		return NewResponse(false, 42, "No client with that name exists.", nil)
+	}
+	var rooms, commands []string
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return NewResponse(false, 29, "Invalid scope.  Use rooms=room1,room2 and commands=read,send.", nil)
+		}
+		switch strings.ToLower(kv[0]) {
+		case "rooms":
+			rooms = strings.Split(kv[1], ",")
+		case "commands":
+			commands = strings.Split(strings.ToLower(kv[1]), ",")
+		default:
+			return NewResponse(false, 29, "Invalid scope.  Use rooms=room1,room2 and commands=read,send.", nil)
+		}
+	}
+	key, k, err := cl.data.CreateAPIKey(account, rooms, commands)
+	switch {
+	case err == clientdata.ErrInvalidName:
+		return NewResponse(false, 20, "Invalid room name.  Name may only contain alphanumeric characters.", nil)
+	case err == clientdata.ErrInvalidScope:
+		return NewResponse(false, 29, "Invalid scope.  Commands must be read or send.", nil)
+	case err != nil:
+		log.Println("Error CreateAPIKey: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	data := struct {
+		Key string
+		clientdata.APIKey
+	}{key, *k}
+	return NewResponse(true, 0, fmt.Sprintf("API key %v created for %v.  It will not be shown again:\r\n%v", k.ID, account, key), data)
+}
+
+//APIKeys lists the keys for account.
+func (cl *Client) APIKeys(account string) *Response {
+	list, err := cl.data.APIKeys(account)
+	if err != nil {
+		log.Println("Error APIKeys: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := fmt.Sprintf("API Keys for %v:", account)
+	for _, k := range list {
+		sresp += fmt.Sprintf("\r\n%v rooms: %v commands: %v", k.ID, scopeString(k.Rooms), scopeString(k.Commands))
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//RevokeAPIKey deletes the key id from account.
+func (cl *Client) RevokeAPIKey(account, id string) *Response {
+	err := cl.data.RevokeAPIKey(account, id)
+	switch {
+	case err == clientdata.ErrKeyNotFound:
+		return NewResponse(false, 45, "No API key with that id exists.", nil)
+	case err != nil:
+		log.Println("Error RevokeAPIKey: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		return NewResponse(true, 0, fmt.Sprintf("API key %v revoked.", id), nil)
+	}
+}
+
+//scopeString formats a key scope list for display.
+func scopeString(list []string) string {
+	if len(list) == 0 {
+		return "all"
+	}
+	return strings.Join(list, ",")
+}
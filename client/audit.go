@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/connections"
+	"log"
+	"strconv"
+	"strings"
+)
+
+//defaultAuditLimit is how many events /audit shows if no limit is given.
+const defaultAuditLimit = 20
+
+//source returns the address of the client's connection or an empty string if it doesn't have one.
+func (cl *Client) source() string {
+	if conn, ok := cl.connection.(connections.Addressed); ok {
+		return conn.RemoteAddr()
+	}
+	return ""
+}
+
+//audit records a privileged action the client did to target in the audit log.
+func (cl *Client) audit(action, target, detail string) {
+	if _, err := cl.data.Audit(action, target, cl.source(), detail); err != nil {
+		log.Printf("Error Audit %v %v %v: %v", cl.Name(), action, target, err)
+	}
+}
+
+//auditUsage is the response for an audit command that can't be understood.
+func auditUsage() *Response {
+	return NewResponse(false, 22, "Use audit [actor=name] [action=action] [target=target] [limit=number] or audit verify.", nil)
+}
+
+//Audit shows administrators the newest events in the audit log.  args may contain actor=, action=, target= and limit= to narrow them down or be verify to check that the log hasn't been changed.
+func (cl *Client) Audit(args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if len(args) > 0 && strings.ToLower(args[0]) == "verify" {
+		return cl.VerifyAudit()
+	}
+	q := clientdata.AuditQuery{Limit: defaultAuditLimit}
+	for _, arg := range args {
+		if arg == "" {
+			continue
+		}
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return auditUsage()
+		}
+		switch strings.ToLower(kv[0]) {
+		case "actor":
+			q.Actor = kv[1]
+		case "action":
+			q.Action = strings.ToLower(kv[1])
+		case "target":
+			q.Target = kv[1]
+		case "limit":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 0 {
+				return auditUsage()
+			}
+			q.Limit = n
+		default:
+			return auditUsage()
+		}
+	}
+	list, err := cl.data.AuditLog(q)
+	if err != nil {
+		log.Println("Error AuditLog: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Audit Log:"
+	for i := range list {
+		sresp = sresp + "\r\n" + list[i].String()
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//VerifyAudit checks that the audit log's hash chain is intact.
+func (cl *Client) VerifyAudit() *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	bad, err := cl.data.VerifyAudit()
+	switch {
+	case err == clientdata.ErrAuditTampered:
+		return NewResponse(false, 86, fmt.Sprintf("The audit log has been changed starting at event %v.", bad), bad)
+	case err != nil:
+		log.Println("Error VerifyAudit: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	head, err := cl.data.AuditLog(clientdata.AuditQuery{Limit: 1})
+	if err != nil {
+		log.Println("Error AuditLog: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	if len(head) == 0 {
+		return NewResponse(true, 0, "The audit log is empty.", 0)
+	}
+	return NewResponse(true, 0, fmt.Sprintf("The audit log is intact through event %v %v.  Check it against the last head in the server log.", head[0].Seq, head[0].Hash), 0)
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//isAdmin returns true if the client is a server administrator.
+func (cl *Client) isAdmin() bool {
+	admin, err := cl.data.IsAdmin()
+	if err != nil {
+		log.Println("Error IsAdmin: ", err)
+	}
+	return admin
+}
+
+//notPermitted is the response for non administrators trying to use an administrator command.
+func notPermitted() *Response {
+	return NewResponse(false, 71, "You do not have permission to do that.", nil)
+}
+
+//parseDuration parses a duration like time.ParseDuration but also accepts d for days and w for weeks.  "perm" and "permanent" return 0.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(s)
+	switch {
+	case s == "perm" || s == "permanent":
+		return 0, nil
+	case strings.HasSuffix(s, "d") || strings.HasSuffix(s, "w"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d := time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(s, "w") {
+			d *= 7
+		}
+		return d, nil
+	default:
+		return time.ParseDuration(s)
+	}
+}
+
+//banArgs splits the optional duration and reason out of the arguments to a ban command.  If the first argument isn't a duration the ban is permanent and all the arguments are the reason.
+func banArgs(args []string) (expires time.Time, reason string) {
+	if len(args) > 0 {
+		if d, err := parseDuration(args[0]); err == nil {
+			if d > 0 {
+				expires = time.Now().Add(d)
+			}
+			args = args[1:]
+		}
+	}
+	return expires, strings.TrimSpace(strings.Join(args, " "))
+}
+
+//Ban bans the account name from the server and disconnects them if they are online.  args may start with a duration followed by the reason.
+func (cl *Client) Ban(name string, args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if name == "" {
+		return NewResponse(false, 22, "You must enter a user to ban.", nil)
+	}
+	if !clientdata.ValidateName(name) {
+		return NewResponse(false, 20, "Invalid name.  Name must be alphanumeric characters only.", nil)
+	}
+	if strings.EqualFold(cl.Name(), name) {
+		return NewResponse(false, 34, "You can't ban yourself.", nil)
+	}
+	if ex, err := cl.data.ClientExists(name); !ex {
+		if err != nil {
+			log.Println(err)
+		}
+		return NewResponse(false, 42, "No client with that name exists.", nil)
+	}
+	expires, reason := banArgs(args)
+	err := cl.data.Ban(clientdata.BanAccount, name, reason, expires)
+	if err != nil {
+		log.Println("Error Ban: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.audit("ban", name, strings.TrimSpace(strings.Join(args, " ")))
+	if other, ok := cl.rooms.GetClient(name).(*Client); ok {
+		other.Recieve(message.NewServerMessage("You have been banned from the server."))
+		other.Quit()
+	}
+	return NewResponse(true, 0, fmt.Sprintf("%v has been banned.", name), nil)
+}
+
+//BanAddress bans an IP address or CIDR range from connecting to the server.  args may start with a duration followed by the reason.
+func (cl *Client) BanAddress(addr string, args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if addr == "" {
+		return NewResponse(false, 22, "You must enter an address to ban.", nil)
+	}
+	if !clientdata.ValidateBanTarget(clientdata.BanAddress, addr) {
+		return NewResponse(false, 20, "Invalid address.  Address must be an IP address or CIDR range.", nil)
+	}
+	expires, reason := banArgs(args)
+	err := cl.data.Ban(clientdata.BanAddress, addr, reason, expires)
+	if err != nil {
+		log.Println("Error BanAddress: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.audit("banip", addr, strings.TrimSpace(strings.Join(args, " ")))
+	return NewResponse(true, 0, fmt.Sprintf("%v has been banned.", addr), nil)
+}
+
+//Unban lifts a ban of kind on target.
+func (cl *Client) Unban(kind, target string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if target == "" {
+		return NewResponse(false, 22, "You must enter a ban to lift.", nil)
+	}
+	err := cl.data.Unban(kind, target)
+	switch {
+	case err == clientdata.ErrNotBanned:
+		return NewResponse(false, 33, fmt.Sprintf("%v is not banned.", target), nil)
+	case err != nil:
+		log.Println("Error Unban: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		if kind == clientdata.BanAddress {
+			cl.audit("unbanip", target, "")
+		} else {
+			cl.audit("unban", target, "")
+		}
+		return NewResponse(true, 0, fmt.Sprintf("%v is no longer banned.", target), nil)
+	}
+}
+
+//BanList provides a list of the bans currently in effect.
+func (cl *Client) BanList() *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	list, err := cl.data.BanList()
+	if err != nil {
+		log.Println("BanList: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Ban List:"
+	for i := range list {
+		sresp = sresp + "\r\n" + list[i].String()
+	}
+	return NewResponse(true, 0, sresp, list)
+}
@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/connections"
+	"github.com/DavidAFox/Chat/filter"
 	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
 	"github.com/DavidAFox/Chat/room"
 	"io"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,20 +22,55 @@ Codes - will be found in header under "code" if the action fails and indicates t
 20 Invalid Name
 21 User name and password don't match
 22 No argument provided
+23 Banned from the server
+24 Too many failed logins, try again later
+25 Two-factor code required
+26 Invalid two-factor code
+27 Two-factor authentication already enabled
+28 Two-factor authentication not enabled
+29 Invalid API key scope
 30 Already blocking that user
 31 Not blocking that user
 32 Can't block self
+33 Not banned
+34 Can't ban self
 35 Already friend that user
 36 Not friending that user
 37 Can't friend self
+38 Invalid display name
+39 Name is taken
 40 Not in a Room
 41 Room does not exist
 42 Client not found
 43 Client is blocking you
 44 Already at max rooms
+45 API key not found
+46 Webhook not found
+47 Invalid webhook URL
+48 Scheduled item not found
+49 Invalid time
 50 Server Error
 60 Unsupported Method
 70 Invalid Command
+71 Not permitted
+80 File is too large
+81 That type of file can't be uploaded
+82 Message blocked by the content filter
+83 Too many requests, try again later
+84 Message not found
+85 Can't report self
+86 The audit log has been changed
+87 Too many bots
+90 A poll is already open in the room
+91 No poll open in the room
+92 Already voted in the poll
+93 Invalid poll option
+94 Friend request already sent
+95 No friend request from that user
+96 Conversation not found
+97 Too many people in a conversation
+98 Report not found
+99 Report already resolved
 */
 
 //Response is used to reply to commands from the clients connection.
@@ -72,44 +110,92 @@ func NewResponse(success bool, code int, sresp string, data interface{}) *Respon
 //Client is used to represent the client in rooms and do server actions.
 type Client struct {
 	name       string
+	display    string
+	presence   presence
+	loggedOut  sync.Once
+	typing     typing
 	room       *room.Room
 	rooms      *room.RoomList
 	chatlog    io.Writer
 	data       clientdata.ClientData
 	connection connections.Connection
+	commands   *ratelimit.Bucket
+	idleAway   time.Duration
+	versions   *versions
+	filter     *filter.Pipeline
+	flood      *floodControl
+	shadow     *ShadowList
+}
+
+//Options configures the clients a Factory makes.
+type Options struct {
+	Limits     Limits           //flood control, see newFloodControl
+	Filter     *filter.Pipeline //checks the text clients send, nil if it isn't filtered
+	Shadowbans *ShadowList      //the shadowbanned accounts, which should also be passed to the rooms, or an empty list if nil
+	IdleAway   time.Duration    //how long a client can go without sending a command before they are shown as away, DefaultIdleAway if zero and off if negative
 }
 
 type Factory struct {
 	roomlist *room.RoomList
 	chatlog  io.Writer
 	data     clientdata.Factory
+	idleAway time.Duration
+	versions *versions
+	filter   *filter.Pipeline
+	flood    *floodControl
+	shadow   *ShadowList
 }
 
-func NewFactory(roomlist *room.RoomList, chatlog io.Writer, data clientdata.Factory) *Factory {
+//NewFactory returns a Factory for clients in roomlist.  Every client it makes shares the settings in options.
+func NewFactory(roomlist *room.RoomList, chatlog io.Writer, data clientdata.Factory, options Options) *Factory {
 	f := new(Factory)
 	f.roomlist = roomlist
 	f.chatlog = chatlog
 	f.data = data
+	f.versions = newVersions()
+	f.filter = options.Filter
+	f.flood = newFloodControl(options.Limits)
+	f.shadow = options.Shadowbans
+	if f.shadow == nil {
+		f.shadow = NewShadowList()
+	}
+	f.idleAway = options.IdleAway
+	if f.idleAway == 0 {
+		f.idleAway = DefaultIdleAway
+	}
 	return f
 }
 
 func (f *Factory) New(name string, connection connections.Connection) connections.Client {
-	return New(name, f.roomlist, f.chatlog, f.data.Create(name), connection)
+	return New(name, f, connection)
 }
 
-//New returns a new client.
-func New(name string, roomlist *room.RoomList, chatlog io.Writer, data clientdata.ClientData, connection connections.Connection) *Client {
+//New returns a new client made by f.
+func New(name string, f *Factory, connection connections.Connection) *Client {
 	cl := new(Client)
 	cl.name = name
-	cl.rooms = roomlist
-	cl.chatlog = chatlog
-	cl.data = data
+	cl.rooms = f.roomlist
+	cl.chatlog = f.chatlog
+	cl.data = f.data.Create(name)
+	cl.idleAway = f.idleAway
+	cl.versions = f.versions
+	cl.filter = f.filter
 	cl.connection = connection
+	cl.flood = f.flood
+	cl.shadow = f.shadow
+	cl.commands = ratelimit.NewBucket(f.flood.limits.Commands)
 	err := cl.data.UpdateOnline(time.Now())
 	if err != nil {
 		log.Println(err)
 	}
+	cl.display, err = cl.data.DisplayName()
+	if err != nil {
+		log.Println("Error DisplayName: ", err)
+	}
+	cl.loadPresence()
 	_ = cl.Join("Lobby")
+	cl.notifyFriends(message.NewFriendOnlineMessage(cl.Name()))
+	cl.deliverMail()
 	return cl
 }
 
@@ -119,6 +205,9 @@ func (cl *Client) Recieve(m message.Message) {
 		if cl.IsBlocked(msg.Name()) {
 			return
 		}
+		if _, ok := m.(*message.TypingMessage); ok && msg.Name() == cl.Name() {
+			return
+		}
 	}
 	cl.connection.SendMessage(m)
 }
@@ -146,11 +235,19 @@ func (cl *Client) Execute(command []string) connections.Response {
 		command = append(command, "")
 	}
 	command[0] = strings.ToLower(command[0])
+	if wait := cl.commands.Take(); wait > 0 {
+		cl.strike()
+		return throttled("You're sending commands too fast.", wait)
+	}
+	cl.touch(command[0])
 	switch command[0] {
 	case "messages":
 		log.Println("messages")
-		return cl.Send(command[1])
+		return cl.Send(strings.Join(command[1:], " "))
 	case "join":
+		if resp := cl.throttle(cl.flood.joins); resp != nil {
+			return resp
+		}
 		return cl.Join(command[1])
 	case "block":
 		return cl.Block(command[1])
@@ -165,7 +262,7 @@ func (cl *Client) Execute(command []string) connections.Response {
 	case "who":
 		return cl.Who(command[1])
 	case "send":
-		return cl.Send(command[1])
+		return cl.Send(strings.Join(command[1:], " "))
 	case "blocklist":
 		return cl.BlockList()
 	case "friend":
@@ -174,11 +271,100 @@ func (cl *Client) Execute(command []string) connections.Response {
 		return cl.Unfriend(command[1])
 	case "friendlist":
 		return cl.FriendList()
+	case "accept":
+		return cl.AcceptFriend(command[1])
+	case "decline":
+		return cl.DeclineFriend(command[1])
+	case "requests":
+		return cl.FriendRequests()
 	case "tell":
 		if len(command) < 3 {
 			command = append(command, "")
 		}
-		return cl.Tell(command[1], command[2])
+		return cl.Tell(command[1], strings.Join(command[2:], " "))
+	case "ban":
+		return cl.Ban(command[1], command[2:])
+	case "banip":
+		return cl.BanAddress(command[1], command[2:])
+	case "unban":
+		return cl.Unban(clientdata.BanAccount, command[1])
+	case "unbanip":
+		return cl.Unban(clientdata.BanAddress, command[1])
+	case "bans":
+		return cl.BanList()
+	case "shadowban":
+		return cl.Shadowban(command[1], strings.TrimSpace(strings.Join(command[2:], " ")))
+	case "unshadowban":
+		return cl.Unshadowban(command[1])
+	case "shadowbans":
+		return cl.ShadowbanList()
+	case "audit":
+		return cl.Audit(command[1:])
+	case "report":
+		return cl.Report(command[1], command[2:])
+	case "reports":
+		return cl.Reports(strings.ToLower(command[1]))
+	case "resolve":
+		if len(command) < 3 {
+			command = append(command, "")
+		}
+		return cl.Resolve(command[1], command[2], command[3:])
+	case "2fa":
+		return cl.TwoFactor(command[1], command[2:])
+	case "bot":
+		return cl.CreateBot(command[1])
+	case "bots":
+		return cl.Bots()
+	case "apikey":
+		return cl.APIKey(command[1], command[2:])
+	case "topic":
+		return cl.Topic(strings.TrimSpace(strings.Join(command[1:], " ")))
+	case "slow":
+		return cl.SlowMode(strings.ToLower(command[1]))
+	case "webhook":
+		return cl.Webhook(command[1], command[2:])
+	case "incoming":
+		return cl.IncomingWebhook(command[1], command[2:])
+	case "remind":
+		return cl.Remind(command[1], strings.Join(command[2:], " "))
+	case "schedule":
+		if len(command) < 3 {
+			command = append(command, "")
+		}
+		return cl.ScheduleMessage(command[1], command[2], strings.Join(command[3:], " "))
+	case "reminders":
+		return cl.Reminders()
+	case "unschedule":
+		return cl.Unschedule(command[1])
+	case "poll":
+		return cl.StartPoll(strings.Join(command[1:], " "))
+	case "vote":
+		return cl.Vote(strings.TrimSpace(strings.Join(command[1:], " ")))
+	case "closepoll":
+		return cl.ClosePoll()
+	case "dm":
+		if len(command) < 3 {
+			command = append(command, "")
+		}
+		return cl.DM(command[1], strings.Join(command[2:], " "))
+	case "dms":
+		return cl.Conversations()
+	case "dmhistory":
+		return cl.DMHistory(command[1], command[2:])
+	case "markread":
+		return cl.MarkRead(command[1])
+	case "unread":
+		return cl.Unread()
+	case "typing":
+		return cl.Typing(command[1])
+	case "me":
+		return cl.Emote(strings.TrimSpace(strings.Join(command[1:], " ")))
+	case "nick":
+		return cl.Nick(strings.TrimSpace(strings.Join(command[1:], " ")))
+	case "away":
+		return cl.Away(strings.TrimSpace(strings.Join(command[1:], " ")))
+	case "status":
+		return cl.Status(command[1:])
 	default:
 		return NewResponse(false, 70, "Invalid Command", nil)
 	}
@@ -256,7 +442,7 @@ func (cl *Client) Block(name string) *Response {
 	}
 }
 
-//Friend adds name to this clients friend list.
+//Friend asks name to be this client's friend.  If name already asked them they become friends.
 func (cl *Client) Friend(name string) *Response {
 	if name == "" {
 		return NewResponse(false, 22, "You must enter a user to friend.", nil)
@@ -273,19 +459,26 @@ func (cl *Client) Friend(name string) *Response {
 		}
 		return NewResponse(false, 42, "No client with that name exists.", nil)
 	}
-	err := cl.data.Friend(name)
+	accepted, err := cl.data.RequestFriend(name)
 	switch {
 	case err == clientdata.ErrFriend:
 		return NewResponse(false, 35, fmt.Sprintf("%v is already on your friends list.", name), nil)
+	case err == clientdata.ErrFriendRequested:
+		return NewResponse(false, 94, fmt.Sprintf("You have already asked %v to be your friend.", name), nil)
 	case err != nil:
 		log.Println("Error Friend: ", err)
 		return NewResponse(false, 50, "", nil)
-	default:
+	case accepted:
+		cl.versions.changed(cl.Name(), name)
+		cl.tellClient(name, message.NewServerMessage(fmt.Sprintf("%v accepted your friend request.", cl.Name())))
 		return NewResponse(true, 0, fmt.Sprintf("%v is now on your friends list.", name), nil)
+	default:
+		cl.tellClient(name, message.NewServerMessage(fmt.Sprintf("%v wants to be your friend.  Use /accept %v or /decline %v.", cl.Name(), cl.Name(), cl.Name())))
+		return NewResponse(true, 0, fmt.Sprintf("Asked %v to be your friend.", name), nil)
 	}
 }
 
-//Unfriend removes name from this clients friend list.
+//Unfriend removes name from this clients friend list and this client from theirs or cancels a friend request to name.
 func (cl *Client) Unfriend(name string) *Response {
 	if name == "" {
 		return NewResponse(false, 22, "You must enter a user to unfriend.", nil)
@@ -300,14 +493,17 @@ func (cl *Client) Unfriend(name string) *Response {
 	case err != nil:
 		return NewResponse(false, 50, "", nil)
 	default:
+		cl.versions.changed(cl.Name(), name)
 		return NewResponse(true, 0, fmt.Sprintf("%v is no longer on your friends list.", name), nil)
 	}
 }
 
-//Friend represents a person on your friends list.  room will instead be the last online string if they are not online now.
+//Friend represents a person on your friends list.  room will instead be the last online string if they are not online now.  State is their presence state and Status their status text.
 type Friend struct {
-	Name string
-	Room string
+	Name   string
+	Room   string
+	State  string
+	Status string
 }
 
 //FriendList gives a list of people on the clients friendlist and the room they are in or when they were last logged in.
@@ -318,7 +514,11 @@ func (cl *Client) FriendList() *Response {
 	}
 	flist := make([]Friend, len(list), len(list))
 	for i := range list {
-		flist[i] = Friend{Name: list[i], Room: cl.rooms.FindClientRoom(list[i])}
+		p := cl.presenceOf(list[i])
+		flist[i] = Friend{Name: list[i], State: p.State, Status: p.Status}
+		if p.State != PresenceOffline {
+			flist[i].Room = cl.rooms.FindClientRoom(list[i])
+		}
 		if flist[i].Room == "" {
 			lo, err := cl.data.LastOnline(flist[i].Name)
 			if err != nil && err != clientdata.ErrClientNotFound {
@@ -331,40 +531,82 @@ func (cl *Client) FriendList() *Response {
 			}
 		}
 	}
-	sresp := "Friend \t\t Room/Last Online"
+	sresp := "Friend \t\t Room/Last Online \t\t Status"
 	for i := range flist {
-		sresp = sresp + "\n\r" + flist[i].Name + "\t\t\t" + flist[i].Room
+		status := flist[i].State
+		if flist[i].Status != "" {
+			status = status + ": " + flist[i].Status
+		}
+		sresp = sresp + "\n\r" + flist[i].Name + "\t\t\t" + flist[i].Room + "\t\t\t" + status
 	}
 	return NewResponse(true, 0, sresp, flist)
 }
 
+//Tell sends m to name and saves it in the client's conversation with them.  If name is offline it is only saved.  Invisible clients are answered for as if they were offline but still get the tell.
 func (cl *Client) Tell(name, m string) *Response {
 	if name == "" {
 		return NewResponse(false, 42, "You must enter a name and a message.", nil)
 	}
 	other := cl.rooms.GetClient(name)
-	if other != nil {
-		if othc, ok := other.(*Client); ok {
-			if othc.IsBlocked(cl.Name()) {
-				return NewResponse(false, 43, fmt.Sprintf("%v is blocking you.", other.Name()), nil)
-			}
+	var hidden *Client
+	if othc, ok := other.(*Client); ok && othc.invisible() {
+		hidden, other = othc, nil
+	}
+	if othc, ok := other.(*Client); ok && othc.IsBlocked(cl.Name()) {
+		return NewResponse(false, 43, fmt.Sprintf("%v is blocking you.", other.Name()), nil)
+	}
+	if resp := cl.checkTell(); resp != nil {
+		return resp
+	}
+	m, rejected := cl.filterText("", m)
+	if rejected != nil {
+		return rejected
+	}
+	if cl.shadowbanned() {
+		if other == nil {
+			return cl.shadowTell(name, nil)
 		}
+		return cl.shadowTell(name, message.NewTellMessage(m, cl.Name(), other.Name(), false))
+	}
+	saved := m != "" && cl.recordTell(name, m)
+	if other != nil {
 		mess := message.NewTellMessage(m, cl.Name(), other.Name(), true)
 		other.Recieve(mess)
 		sentMessage := message.NewTellMessage(m, cl.Name(), other.Name(), false)
 		cl.Recieve(sentMessage)
+		if othc, ok := other.(*Client); ok {
+			othc.autoReply(cl)
+		}
 		return NewResponse(true, 0, "", nil)
 	}
+	if saved {
+		if hidden != nil {
+			hidden.Recieve(message.NewTellMessage(m, cl.Name(), hidden.Name(), true))
+		}
+		return NewResponse(true, 0, fmt.Sprintf("%v is offline.  They can read your message with /dmhistory %v.", name, cl.Name()), nil)
+	}
 	return NewResponse(false, 42, "Could not find a client with that name.", nil)
 }
 
-//LeaveRoom removes the client from its room. It is used for logging out.
+//LeaveRoom removes the client from its room and tells their friends they are offline. It is used for logging out.
 func (cl *Client) LeaveRoom() {
+	cl.leaveRoom()
+	cl.loggedOut.Do(func() { cl.notifyFriends(message.NewFriendOfflineMessage(cl.Name())) })
+}
+
+//leaveRoom removes the client from its room.  The room isn't told when an invisible client leaves.
+func (cl *Client) leaveRoom() {
+	cl.stopTyping()
 	if cl.room != nil {
+		if err := cl.markRead(cl.room); err != nil {
+			log.Println("Error MarkRead: ", err)
+		}
 		rm2 := cl.room
 		_ = cl.room.Remove(cl)
 		cl.room = nil
-		rm2.Send(message.NewLeaveMessage(cl.Name()))
+		if !cl.invisible() {
+			rm2.Send(message.NewLeaveMessage(cl.Name()))
+		}
 		cl.Recieve(message.NewLeaveMessage(cl.Name()))
 	}
 }
@@ -387,14 +629,55 @@ func (cl *Client) Quit() *Response {
 
 //Send sends the message to the clients room.
 func (cl *Client) Send(m string) *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return resp
+	}
+	m, rejected := cl.filterText(cl.room.Name(), m)
+	if rejected != nil {
+		return rejected
+	}
 	message := message.NewSendMessage(m, cl.Name())
-	if cl.room != nil {
-		cl.log(fmt.Sprint(message))
-		cl.room.Send(message)
-		return NewResponse(true, 0, "", nil)
-	} else {
+	message.Display = cl.display
+	cl.stopTyping()
+	cl.log(fmt.Sprint(message))
+	cl.room.Send(message)
+	return NewResponse(true, 0, "", nil)
+}
+
+//Topic shows the topic of the client's room or changes it if topic isn't empty.
+func (cl *Client) Topic(topic string) *Response {
+	if cl.room == nil {
 		return NewResponse(false, 40, "You are not in a room.", nil)
 	}
+	if topic == "" {
+		current := cl.room.Topic()
+		if current == "" {
+			return NewResponse(true, 0, fmt.Sprintf("%v has no topic.", cl.room.Name()), current)
+		}
+		return NewResponse(true, 0, fmt.Sprintf("Topic for %v: %v", cl.room.Name(), current), current)
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return resp
+	}
+	if topic == "none" || topic == "clear" {
+		topic = ""
+	} else {
+		var rejected *Response
+		if topic, rejected = cl.filterText(cl.room.Name(), topic); rejected != nil {
+			return rejected
+		}
+	}
+	if cl.shadowbanned() {
+		cl.Recieve(message.NewTopicMessage(topic, cl.Name()))
+		return NewResponse(true, 0, "", nil)
+	}
+	cl.room.SetTopic(topic, cl.Name())
+	cl.audit("topic", cl.room.Name(), topic)
+	cl.log(fmt.Sprint(message.NewTopicMessage(topic, cl.Name())))
+	return NewResponse(true, 0, "", nil)
 }
 
 func durationString(d time.Duration) string {
@@ -414,7 +697,7 @@ func durationString(d time.Duration) string {
 	}
 }
 
-//Join adds a client to a room or creates a room if it doesn't exist.
+//Join adds a client to a room or creates a room if it doesn't exist.  Only the client is told when an invisible client joins.
 func (cl *Client) Join(rmName string) *Response {
 	if rmName == "" {
 		return NewResponse(false, 22, "You must enter a room to join.", nil)
@@ -431,22 +714,27 @@ func (cl *Client) Join(rmName string) *Response {
 		} else if err != nil {
 			return NewResponse(false, 50, "Server Error while joining room.", nil)
 		}
-		cl.LeaveRoom()
+		cl.leaveRoom()
 		cl.room = newRoom
 		cl.room.Add(cl)
 	} else {
-		cl.LeaveRoom()
+		cl.leaveRoom()
 		cl.room = rm
 		rm.Add(cl)
 	}
-	cl.room.Send(message.NewJoinMessage(cl.Name()))
+	if cl.invisible() {
+		cl.Recieve(message.NewJoinMessage(cl.Name()))
+	} else {
+		cl.room.Send(message.NewJoinMessage(cl.Name()))
+	}
 	return NewResponse(true, 0, "", nil)
 }
 
-//WhoData is an object used to return the advanced format in a response from who.
+//WhoData is an object used to return the advanced format in a response from who.  Presence has the presence of each client in Clients in the same order.
 type WhoData struct {
-	Room    string
-	Clients []string
+	Room     string
+	Clients  []string
+	Presence []Presence
 }
 
 //Who sends the client a list of all the people in the room specified or the clients room if none is provided.
@@ -461,21 +749,34 @@ func (cl *Client) Who(rmName string) *Response {
 	if rm == nil {
 		return NewResponse(false, 41, "That room was not found.", nil)
 	}
-	clist := rm.Who()
-	data := WhoData{Room: rmName, Clients: clist}
+	data := WhoData{Room: rmName, Clients: make([]string, 0), Presence: make([]Presence, 0)}
 	sresp := fmt.Sprintf("Room: %v", rmName)
-	for i := range clist {
-		sresp = sresp + "\r\n" + clist[i]
+	for _, name := range rm.Who() {
+		p := cl.presenceOfClient(name, rm.GetClient(name))
+		if p.State == PresenceOffline {
+			continue
+		}
+		data.Clients = append(data.Clients, name)
+		data.Presence = append(data.Presence, p)
+		sresp = sresp + "\r\n" + p.String()
 	}
 	return NewResponse(true, 0, sresp, data)
 }
 
-//List sends to the client a list of the current open rooms.
+//List sends to the client a list of the current open rooms with how many unread messages each has.
 func (cl *Client) List() *Response {
 	rlist := cl.rooms.Who()
+	unread, err := cl.unreadCounts(rlist)
+	if err != nil {
+		log.Println("Error ReadPositions: ", err)
+		unread = make(map[string]int)
+	}
 	sresp := "Rooms:"
 	for i := range rlist {
 		sresp = sresp + "\r\n" + rlist[i]
+		if unread[rlist[i]] > 0 {
+			sresp = sresp + fmt.Sprintf(" (%v unread)", unread[rlist[i]])
+		}
 	}
 	return NewResponse(true, 0, sresp, rlist)
 }
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+	"strconv"
+	"strings"
+)
+
+//HistoryData is the advanced format of a response from dmhistory.  Before is the value to pass as before to get the page of messages before these or 0 if there aren't any.
+type HistoryData struct {
+	Conversation clientdata.Conversation
+	Messages     []clientdata.DirectMessage
+	Before       int
+}
+
+//conversationError returns the response for an error finding or starting a conversation.
+func conversationError(err error) *Response {
+	switch err {
+	case clientdata.ErrInvalidName:
+		return NewResponse(false, 20, "Invalid name.  Name must be alphanumeric characters only.", nil)
+	case clientdata.ErrClientNotFound:
+		return NewResponse(false, 42, "No client with that name exists.", nil)
+	case clientdata.ErrConversationBlocked:
+		return NewResponse(false, 43, "You can't message someone who is blocking you or that you are blocking.", nil)
+	case clientdata.ErrConversationNotFound:
+		return NewResponse(false, 96, "You have no conversation with that id.", nil)
+	case clientdata.ErrConversationSize:
+		return NewResponse(false, 97, fmt.Sprintf("A conversation can have up to %v people including you.", clientdata.MaxConversationMembers), nil)
+	}
+	log.Println("Error Conversation: ", err)
+	return NewResponse(false, 50, "", nil)
+}
+
+//conversation returns the conversation target refers to.  target is #id or a comma separated list of the other people in it.
+func (cl *Client) conversation(target string) (*clientdata.Conversation, error) {
+	if strings.HasPrefix(target, "#") {
+		return cl.data.ConversationByID(strings.TrimPrefix(target, "#"))
+	}
+	return cl.data.Conversation(strings.Split(target, ","))
+}
+
+//DM sends text to the conversation target which is #id or a comma separated list of the other people in it.  The conversation is started if it doesn't exist.
+func (cl *Client) DM(target, text string) *Response {
+	if target == "" || text == "" {
+		return NewResponse(false, 22, "You must enter who to message and a message.", nil)
+	}
+	c, err := cl.conversation(target)
+	if err != nil {
+		return conversationError(err)
+	}
+	if resp := cl.checkTell(); resp != nil {
+		return resp
+	}
+	text, rejected := cl.filterText("", text)
+	if rejected != nil {
+		return rejected
+	}
+	if cl.shadowbanned() {
+		return cl.shadowDM(c, text)
+	}
+	dm, err := cl.data.AddDirectMessage(c.ID, text)
+	if err != nil {
+		return conversationError(err)
+	}
+	msg := message.NewDirectMessage(c.ID, c.Members, dm.Seq, text, cl.Name())
+	for _, name := range c.Others(cl.Name()) {
+		cl.tellClient(name, msg)
+	}
+	cl.Recieve(msg)
+	return NewResponse(true, 0, "", dm)
+}
+
+//Conversations lists the client's conversations with the most recent first.
+func (cl *Client) Conversations() *Response {
+	list, err := cl.data.Conversations()
+	if err != nil {
+		log.Println("Error Conversations: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Conversations:"
+	for i := range list {
+		sresp += fmt.Sprintf("\r\n#%v %v - %v messages", list[i].ID, strings.Join(list[i].Others(cl.Name()), ", "), list[i].Count)
+		if list[i].Count > 0 {
+			sresp += ", last " + list[i].Updated.Format("Jan 2 3:04pm")
+		}
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//DMHistory returns messages from the conversation target.  args may have the sequence number to get the messages before and how many to get.
+func (cl *Client) DMHistory(target string, args []string) *Response {
+	if target == "" {
+		return NewResponse(false, 22, "You must enter the conversation or who it is with.", nil)
+	}
+	var before, limit int
+	var err error
+	if len(args) > 0 && args[0] != "" {
+		if before, err = strconv.Atoi(args[0]); err != nil || before < 0 {
+			return NewResponse(false, 22, "Use dmhistory _conversation_ [before] [limit] where before and limit are numbers.", nil)
+		}
+	}
+	if len(args) > 1 && args[1] != "" {
+		if limit, err = strconv.Atoi(args[1]); err != nil || limit < 0 {
+			return NewResponse(false, 22, "Use dmhistory _conversation_ [before] [limit] where before and limit are numbers.", nil)
+		}
+	}
+	c, err := cl.conversation(target)
+	if err != nil {
+		return conversationError(err)
+	}
+	list, err := cl.data.DirectMessages(c.ID, before, limit)
+	if err != nil {
+		return conversationError(err)
+	}
+	data := HistoryData{Conversation: *c, Messages: list}
+	if len(list) > 0 && list[0].Seq > 1 {
+		data.Before = list[0].Seq
+	}
+	sresp := fmt.Sprintf("Conversation #%v with %v:", c.ID, strings.Join(c.Others(cl.Name()), ", "))
+	for _, dm := range list {
+		sresp += fmt.Sprintf("\r\n%v %v [%v]: %v", dm.Seq, dm.Sent.Format("Jan 2 3:04pm"), dm.From, dm.Text)
+	}
+	if data.Before > 0 {
+		sresp += fmt.Sprintf("\r\nUse dmhistory #%v %v for older messages.", c.ID, data.Before)
+	}
+	return NewResponse(true, 0, sresp, data)
+}
+
+//recordTell saves a tell to name in the client's conversation with them.  It returns false if it couldn't.
+func (cl *Client) recordTell(name, text string) bool {
+	c, err := cl.data.Conversation([]string{name})
+	if err == nil {
+		_, err = cl.data.AddDirectMessage(c.ID, text)
+	}
+	switch err {
+	case nil:
+		return true
+	case clientdata.ErrClientNotFound, clientdata.ErrConversationBlocked, clientdata.ErrConversationSize, clientdata.ErrInvalidName:
+	default:
+		log.Println("Error recording tell: ", err)
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//versions counts changes to the data in a Factory's clients' responses.  presence counts changes to anyone's presence and data counts changes to each client's stored data that shows up in their friend list and room list keyed by client name.
+type versions struct {
+	presence uint64
+	lock     sync.Mutex
+	data     map[string]uint64
+}
+
+//newVersions returns versions with nothing changed.
+func newVersions() *versions {
+	return &versions{data: make(map[string]uint64)}
+}
+
+//changed records that the data of each of names changed.
+func (v *versions) changed(names ...string) {
+	v.lock.Lock()
+	for _, name := range names {
+		v.data[name]++
+	}
+	v.lock.Unlock()
+}
+
+//dataVersion returns the version of name's data.
+func (v *versions) dataVersion(name string) string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return strconv.FormatUint(v.data[name], 10)
+}
+
+//presenceChanged records that someone's presence changed.
+func (v *versions) presenceChanged() {
+	atomic.AddUint64(&v.presence, 1)
+}
+
+//presenceVersion returns the version of everyone's presence.
+func (v *versions) presenceVersion() string {
+	return strconv.FormatUint(atomic.LoadUint64(&v.presence), 10)
+}
+
+//timeVersion returns a version for data that changes with time like idle away and last online.  It changes every minute so that data can be up to a minute old.
+func timeVersion() string {
+	return strconv.FormatInt(time.Now().Unix()/60, 10)
+}
+
+//ETag returns an entity tag for the response command would give if it were executed now.  It is the empty string for commands whose responses aren't versioned.  list, who and friendlist are versioned so clients polling them can be told nothing changed.  The tags include the client's name since the responses depend on who asks.
+func (cl *Client) ETag(command []string) string {
+	if len(command) == 0 {
+		return ""
+	}
+	presence := cl.versions.presenceVersion()
+	switch strings.ToLower(command[0]) {
+	case "list":
+		return "list-" + cl.Name() + "-" + cl.rooms.Version() + "-" + cl.rooms.MessageVersion() + "-" + cl.versions.dataVersion(cl.Name())
+	case "who":
+		rmName := ""
+		if len(command) > 1 {
+			rmName = command[1]
+		}
+		if rmName == "" && cl.room != nil {
+			rmName = cl.room.Name()
+		}
+		rm := cl.rooms.FindRoom(rmName)
+		if rm == nil {
+			return ""
+		}
+		return "who-" + cl.Name() + "-" + rm.MemberVersion() + "-" + presence + "-" + timeVersion()
+	case "friendlist":
+		return "friendlist-" + cl.Name() + "-" + cl.rooms.Version() + "-" + presence + "-" + cl.versions.dataVersion(cl.Name()) + "-" + timeVersion()
+	}
+	return ""
+}
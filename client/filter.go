@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+	"strings"
+)
+
+//filterText runs text sent to room through the content filter.  room is empty for private messages.  It returns the text to send or a response if the message was rejected.  Flagged messages are saved as reports and the administrators that are online are told about them.
+func (cl *Client) filterText(room, text string) (string, *Response) {
+	r := cl.filter.Check(room, text)
+	if r.Rejected {
+		return "", NewResponse(false, 82, r.Reason, nil)
+	}
+	if len(r.Flags) > 0 {
+		note := flag(cl.data, cl.Name(), room, text, r.Flags)
+		for _, admin := range cl.onlineAdmins() {
+			admin.Recieve(message.NewServerMessage(note))
+		}
+	}
+	return r.Text, nil
+}
+
+//CheckText runs text name is sending to room from outside a client, like the REST API or a webhook, through the factory's content filter.  Flagged messages are saved as reports.  It returns the text to send or an error with the reason the message was rejected.
+func (f *Factory) CheckText(name, room, text string) (string, error) {
+	r := f.filter.Check(room, text)
+	if r.Rejected {
+		return "", errors.New(r.Reason)
+	}
+	if len(r.Flags) > 0 {
+		flag(f.data.Create(clientdata.ServerRecord), name, room, text, r.Flags)
+	}
+	return r.Text, nil
+}
+
+//flag saves a report of a flagged message for the moderators and logs it.  It returns the note for administrators.
+func flag(data clientdata.ClientData, name, room, text string, reasons []string) string {
+	where := "a private message"
+	if room != "" {
+		where = room
+	}
+	reason := strings.Join(reasons, " ")
+	note := fmt.Sprintf("Flagged message from %v in %v: %q (%v)", name, where, text, reason)
+	r, err := data.FlagMessage(name, room, text, reason)
+	if err != nil {
+		log.Println("Error FlagMessage: ", err)
+	} else {
+		note = fmt.Sprintf("New report #%v: %v", r.ID, note)
+	}
+	log.Println(note)
+	return note
+}
+
+//onlineAdmins returns the administrators that are online.
+func (cl *Client) onlineAdmins() []*Client {
+	admins := make([]*Client, 0)
+	for i := cl.rooms.Front(); i != nil; i = i.Next() {
+		rm, ok := i.Value.(*room.Room)
+		if !ok {
+			continue
+		}
+		for _, name := range rm.Who() {
+			if othc, ok := rm.GetClient(name).(*Client); ok && othc.isAdmin() {
+				admins = append(admins, othc)
+			}
+		}
+	}
+	return admins
+}
@@ -0,0 +1,261 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/lockout"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//MaxMute is the longest an automatic mute can last.
+const MaxMute = 24 * time.Hour
+
+//Limits configures flood control.  Messages, tells and joins are limited per user so logging in again doesn't reset them and commands are limited per connection.  A user who is throttled MuteAfter times within MuteWindowSeconds is muted for MuteSeconds, twice as long for each mute after that.
+type Limits struct {
+	Messages          ratelimit.Rate //room messages and emotes
+	Tells             ratelimit.Rate //tells and direct messages
+	Joins             ratelimit.Rate
+	Uploads           ratelimit.Rate
+	Commands          ratelimit.Rate
+	API               ratelimit.Rate //REST messages per API key and incoming webhook messages per webhook
+	MuteAfter         int
+	MuteWindowSeconds int
+	MuteSeconds       int
+}
+
+//DefaultLimits are the limits used for the ones Options leaves out.
+var DefaultLimits = Limits{
+	Messages:          ratelimit.Rate{PerMinute: 30, Burst: 10},
+	Tells:             ratelimit.Rate{PerMinute: 20, Burst: 10},
+	Joins:             ratelimit.Rate{PerMinute: 10, Burst: 5},
+	Uploads:           ratelimit.Rate{PerMinute: 5, Burst: 3},
+	Commands:          ratelimit.Rate{PerMinute: 300, Burst: 50},
+	API:               ratelimit.Rate{PerMinute: 60, Burst: 20},
+	MuteAfter:         10,
+	MuteWindowSeconds: 60,
+	MuteSeconds:       60,
+}
+
+//offense is a user's record of being throttled.
+type offense struct {
+	strikes int
+	first   time.Time
+	mutes   int
+	until   time.Time
+}
+
+//floodControl holds the limiters and offenses shared by a Factory's clients.
+type floodControl struct {
+	sync.Mutex
+	limits   Limits
+	messages *ratelimit.Limiter
+	tells    *ratelimit.Limiter
+	joins    *ratelimit.Limiter
+	uploads  *ratelimit.Limiter
+	api      *ratelimit.Limiter
+	offenses map[string]*offense
+}
+
+//newFloodControl returns flood control with the limits l.  Zero values are replaced with the defaults and a negative PerMinute turns that limit off.
+func newFloodControl(l Limits) *floodControl {
+	d := DefaultLimits
+	for _, r := range []struct{ rate, def *ratelimit.Rate }{{&l.Messages, &d.Messages}, {&l.Tells, &d.Tells}, {&l.Joins, &d.Joins}, {&l.Uploads, &d.Uploads}, {&l.Commands, &d.Commands}, {&l.API, &d.API}} {
+		if r.rate.PerMinute == 0 {
+			*r.rate = *r.def
+		}
+	}
+	if l.MuteAfter == 0 {
+		l.MuteAfter = d.MuteAfter
+	}
+	if l.MuteWindowSeconds == 0 {
+		l.MuteWindowSeconds = d.MuteWindowSeconds
+	}
+	if l.MuteSeconds == 0 {
+		l.MuteSeconds = d.MuteSeconds
+	}
+	fc := new(floodControl)
+	fc.limits = l
+	fc.messages = ratelimit.New(l.Messages)
+	fc.tells = ratelimit.New(l.Tells)
+	fc.joins = ratelimit.New(l.Joins)
+	fc.uploads = ratelimit.New(l.Uploads)
+	fc.api = ratelimit.New(l.API)
+	fc.offenses = make(map[string]*offense)
+	return fc
+}
+
+//APILimiter returns the limiter for messages sent with the REST API and incoming webhooks.  Keys are the API key or webhook they are sent with.
+func (f *Factory) APILimiter() *ratelimit.Limiter {
+	return f.flood.api
+}
+
+//throttled is the response for a request that has to wait.  The data is the wait in whole seconds.
+func throttled(text string, wait time.Duration) *Response {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	return NewResponse(false, 83, text+"  Try again in "+lockout.WaitString(wait)+".", seconds)
+}
+
+//throttle takes a token for the client from limiter.  It returns nil if the client may go ahead or the throttled response.
+func (cl *Client) throttle(limiter *ratelimit.Limiter) *Response {
+	if wait := limiter.Take(cl.Name()); wait > 0 {
+		cl.strike()
+		return throttled("You're doing that too often.", wait)
+	}
+	return nil
+}
+
+//strike records that the client was throttled and mutes them if it has happened too often.
+func (cl *Client) strike() {
+	d := cl.flood.strike(cl.Name())
+	if d == 0 {
+		return
+	}
+	log.Printf("Muted %v for %v for flooding.", cl.Name(), d)
+	cl.Recieve(message.NewServerMessage("You have been muted for " + lockout.WaitString(d) + " for sending too much."))
+}
+
+//strike records that name was throttled.  If it has happened too often they are muted and it returns how long for, otherwise 0.
+func (fc *floodControl) strike(name string) time.Duration {
+	fc.Lock()
+	now := time.Now()
+	o, ok := fc.offenses[name]
+	if !ok {
+		o = new(offense)
+		fc.offenses[name] = o
+	}
+	if now.Sub(o.first) > time.Duration(fc.limits.MuteWindowSeconds)*time.Second {
+		o.strikes = 0
+		o.first = now
+	}
+	o.strikes++
+	if o.strikes < fc.limits.MuteAfter {
+		fc.Unlock()
+		return 0
+	}
+	d := time.Duration(fc.limits.MuteSeconds) * time.Second
+	for i := 0; i < o.mutes && d < MaxMute; i++ {
+		d *= 2
+	}
+	if d > MaxMute {
+		d = MaxMute
+	}
+	o.strikes = 0
+	o.mutes++
+	fc.Unlock()
+	fc.mute(name, d)
+	return d
+}
+
+//mute keeps name from sending messages for d.
+func (fc *floodControl) mute(name string, d time.Duration) {
+	fc.Lock()
+	defer fc.Unlock()
+	o, ok := fc.offenses[name]
+	if !ok {
+		o = new(offense)
+		fc.offenses[name] = o
+	}
+	o.until = time.Now().Add(d)
+	fc.prune()
+}
+
+//prune forgets offenses that are no longer muted and haven't had a strike in the last day.  fc must be locked.
+func (fc *floodControl) prune() {
+	now := time.Now()
+	for name, o := range fc.offenses {
+		if now.After(o.until) && now.Sub(o.first) > MaxMute {
+			delete(fc.offenses, name)
+		}
+	}
+}
+
+//muteWait returns how much longer name is muted.  It is 0 or less if they aren't muted.
+func (fc *floodControl) muteWait(name string) time.Duration {
+	fc.Lock()
+	defer fc.Unlock()
+	if o, ok := fc.offenses[name]; ok {
+		return time.Until(o.until)
+	}
+	return 0
+}
+
+//muted returns the response for a muted client or nil if the client isn't muted.
+func (cl *Client) muted() *Response {
+	if wait := cl.flood.muteWait(cl.Name()); wait > 0 {
+		return throttled("You are muted.", wait)
+	}
+	return nil
+}
+
+//checkSend returns a response if the client can't send a message to rm right now because they are muted, sending too fast or the room is in slow mode.  Administrators aren't held to slow mode.
+func (cl *Client) checkSend(rm *room.Room) *Response {
+	if resp := cl.muted(); resp != nil {
+		return resp
+	}
+	if resp := cl.throttle(cl.flood.messages); resp != nil {
+		return resp
+	}
+	if rm.SlowMode() > 0 && !cl.isAdmin() {
+		if wait := rm.SlowWait(cl.Name()); wait > 0 {
+			return throttled(rm.Name()+" is in slow mode.", wait)
+		}
+	}
+	return nil
+}
+
+//checkChange returns a response if the client can't change something other clients see, like their display name, right now.  It is checkSend for the client's room or, if they aren't in one, the mute and message limit.
+func (cl *Client) checkChange() *Response {
+	if cl.room != nil {
+		return cl.checkSend(cl.room)
+	}
+	if resp := cl.muted(); resp != nil {
+		return resp
+	}
+	return cl.throttle(cl.flood.messages)
+}
+
+//checkTell returns a response if the client can't send a private message right now because they are muted or sending too fast.
+func (cl *Client) checkTell() *Response {
+	if resp := cl.muted(); resp != nil {
+		return resp
+	}
+	return cl.throttle(cl.flood.tells)
+}
+
+//SlowMode shows the slow mode of the client's room or, for administrators, changes it.  arg is the seconds between messages or off.
+func (cl *Client) SlowMode(arg string) *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if arg == "" {
+		d := cl.room.SlowMode()
+		if d == 0 {
+			return NewResponse(true, 0, cl.room.Name()+" is not in slow mode.", 0)
+		}
+		return NewResponse(true, 0, fmt.Sprintf("%v is in slow mode.  Everyone can send a message every %v.", cl.room.Name(), d), int(d/time.Second))
+	}
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	seconds := 0
+	if arg != "off" {
+		var err error
+		if seconds, err = strconv.Atoi(arg); err != nil || seconds < 0 {
+			return NewResponse(false, 22, "Use slow _seconds_ or slow off.", nil)
+		}
+	}
+	d := time.Duration(seconds) * time.Second
+	cl.room.SetSlowMode(d)
+	cl.audit("slow", cl.room.Name(), arg)
+	if d == 0 {
+		cl.room.Tell(fmt.Sprintf("%v turned off slow mode.", cl.Name()))
+	} else {
+		cl.room.Tell(fmt.Sprintf("%v turned on slow mode.  Everyone can send a message every %v.", cl.Name(), d))
+	}
+	return NewResponse(true, 0, "", int(d/time.Second))
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+)
+
+//FriendRequestData is the advanced format of a response from requests.
+type FriendRequestData struct {
+	Incoming []clientdata.FriendRequest
+	Outgoing []clientdata.FriendRequest
+}
+
+//AcceptFriend accepts name's friend request.
+func (cl *Client) AcceptFriend(name string) *Response {
+	if name == "" {
+		return NewResponse(false, 22, "You must enter the user whose request you are accepting.", nil)
+	}
+	err := cl.data.AcceptFriend(name)
+	switch {
+	case err == clientdata.ErrNoFriendRequest || err == clientdata.ErrInvalidName:
+		return NewResponse(false, 95, fmt.Sprintf("%v hasn't asked to be your friend.", name), nil)
+	case err != nil:
+		log.Println("Error AcceptFriend: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.tellClient(name, message.NewServerMessage(fmt.Sprintf("%v accepted your friend request.", cl.Name())))
+	cl.versions.changed(cl.Name(), name)
+	return NewResponse(true, 0, fmt.Sprintf("%v is now on your friends list.", name), nil)
+}
+
+//DeclineFriend declines name's friend request.  name isn't told.
+func (cl *Client) DeclineFriend(name string) *Response {
+	if name == "" {
+		return NewResponse(false, 22, "You must enter the user whose request you are declining.", nil)
+	}
+	err := cl.data.DeclineFriend(name)
+	switch {
+	case err == clientdata.ErrNoFriendRequest || err == clientdata.ErrInvalidName:
+		return NewResponse(false, 95, fmt.Sprintf("%v hasn't asked to be your friend.", name), nil)
+	case err != nil:
+		log.Println("Error DeclineFriend: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	return NewResponse(true, 0, fmt.Sprintf("Declined %v's friend request.", name), nil)
+}
+
+//FriendRequests lists the friend requests sent to the client and the ones they have sent that are still pending.
+func (cl *Client) FriendRequests() *Response {
+	incoming, outgoing, err := cl.data.FriendRequests()
+	if err != nil {
+		log.Println("Error FriendRequests: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Friend requests to you:"
+	for _, r := range incoming {
+		sresp += "\r\n" + r.From
+	}
+	sresp += "\r\nFriend requests you sent:"
+	for _, r := range outgoing {
+		sresp += "\r\n" + r.To
+	}
+	return NewResponse(true, 0, sresp, FriendRequestData{Incoming: incoming, Outgoing: outgoing})
+}
+
+//tellClient sends m to name if they are online.
+func (cl *Client) tellClient(name string, m message.Message) {
+	if other := cl.rooms.GetClient(name); other != nil {
+		other.Recieve(m)
+	}
+}
+
+//notifyFriends sends m to the client's friends that are online and have the client on their friends list.  Nothing is sent while the client is invisible.
+func (cl *Client) notifyFriends(m message.Message) {
+	if cl.invisible() {
+		return
+	}
+	list, err := cl.data.FriendList()
+	if err != nil {
+		log.Println("Error FriendList: ", err)
+		return
+	}
+	for _, name := range list {
+		other, ok := cl.rooms.GetClient(name).(*Client)
+		if !ok || other == cl {
+			continue
+		}
+		friend, err := other.data.IsFriend(cl.Name())
+		if err != nil {
+			log.Println("Error IsFriend: ", err)
+			continue
+		}
+		if friend {
+			other.Recieve(m)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+)
+
+//DisplayName returns the client's display name or their name if they haven't set one.
+func (cl *Client) DisplayName() string {
+	if cl.display == "" {
+		return cl.Name()
+	}
+	return cl.display
+}
+
+//Nick shows the client's display name or changes it if name isn't empty.  clear or none removes it.
+func (cl *Client) Nick(name string) *Response {
+	if name == "" {
+		return NewResponse(true, 0, fmt.Sprintf("Your display name is %v.", cl.DisplayName()), cl.DisplayName())
+	}
+	if resp := cl.checkChange(); resp != nil {
+		return resp
+	}
+	if name == "none" || name == "clear" {
+		name = ""
+	} else if filtered, rejected := cl.filterText("", name); rejected != nil {
+		return rejected
+	} else if filtered != name {
+		return NewResponse(false, 82, "That display name isn't allowed.", nil)
+	}
+	err := cl.data.SetDisplayName(name)
+	switch {
+	case err == clientdata.ErrInvalidDisplayName:
+		return NewResponse(false, 38, fmt.Sprintf("Invalid display name.  Display names can be up to %v letters and numbers with single spaces or .-_' between them and can't mix alphabets.", clientdata.MaxDisplayName), nil)
+	case err == clientdata.ErrNameTaken:
+		return NewResponse(false, 39, "That name is taken or looks too much like someone else's.", nil)
+	case err != nil:
+		log.Println("Error SetDisplayName: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.display = clientdata.NormalizeName(name)
+	return NewResponse(true, 0, fmt.Sprintf("Your display name is now %v.", cl.DisplayName()), cl.DisplayName())
+}
+
+//Emote sends an action like waves to the client's room.
+func (cl *Client) Emote(action string) *Response {
+	if action == "" {
+		return NewResponse(false, 22, "You must enter an action.", nil)
+	}
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return resp
+	}
+	action, rejected := cl.filterText(cl.room.Name(), action)
+	if rejected != nil {
+		return rejected
+	}
+	msg := message.NewEmoteMessage(action, cl.Name())
+	msg.Display = cl.display
+	cl.stopTyping()
+	cl.log(fmt.Sprint(msg))
+	cl.room.Send(msg)
+	return NewResponse(true, 0, "", nil)
+}
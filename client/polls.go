@@ -0,0 +1,141 @@
+package client
+
+import (
+	"errors"
+	"github.com/DavidAFox/Chat/room"
+	"strings"
+	"time"
+)
+
+//maxPollOptions is the most options a poll can have.
+const maxPollOptions = 10
+
+var errUnclosedQuote = errors.New("unclosed quote")
+
+//splitQuoted splits s on spaces keeping text inside double quotes together.  quoted reports for each field whether any of it was in quotes.
+func splitQuoted(s string) (fields []string, quoted []bool, err error) {
+	fields = make([]string, 0)
+	quoted = make([]bool, 0)
+	var field strings.Builder
+	inField, inQuote, wasQuoted := false, false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inField = true
+			wasQuoted = true
+		case r == ' ' && !inQuote:
+			if inField {
+				fields = append(fields, field.String())
+				quoted = append(quoted, wasQuoted)
+				field.Reset()
+				inField, wasQuoted = false, false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if inQuote {
+		return nil, nil, errUnclosedQuote
+	}
+	if inField {
+		fields = append(fields, field.String())
+		quoted = append(quoted, wasQuoted)
+	}
+	return fields, quoted, nil
+}
+
+//pollUsage is the response for a poll command that can't be understood.
+func pollUsage() *Response {
+	return NewResponse(false, 22, "Use poll [anon] [duration] \"question\" option1 option2 ... with between 2 and 10 options.", nil)
+}
+
+//StartPoll opens a poll in the client's room.  args may start with anon for an anonymous poll and a duration like 10m after which the poll closes itself.
+func (cl *Client) StartPoll(args string) *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	fields, quoted, err := splitQuoted(args)
+	if err != nil {
+		return pollUsage()
+	}
+	anonymous := false
+	var duration time.Duration
+	if len(fields) > 0 && !quoted[0] && strings.EqualFold(fields[0], "anon") {
+		anonymous = true
+		fields, quoted = fields[1:], quoted[1:]
+	}
+	if len(fields) > 0 && !quoted[0] {
+		if d, err := parseDuration(fields[0]); err == nil && d > 0 {
+			duration = d
+			fields, quoted = fields[1:], quoted[1:]
+		}
+	}
+	if len(fields) < 3 || len(fields) > maxPollOptions+1 || fields[0] == "" {
+		return pollUsage()
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return resp
+	}
+	for i := range fields {
+		var rejected *Response
+		if fields[i], rejected = cl.filterText(cl.room.Name(), fields[i]); rejected != nil {
+			return rejected
+		}
+	}
+	p := room.NewPoll(fields[0], cl.Name(), fields[1:], anonymous, duration)
+	if cl.shadowbanned() {
+		cl.Recieve(p.Message())
+		return NewResponse(true, 0, "", nil)
+	}
+	err = cl.room.StartPoll(p)
+	if err == room.ErrPollOpen {
+		return NewResponse(false, 90, "There is already a poll open in this room.", nil)
+	}
+	return NewResponse(true, 0, "", nil)
+}
+
+//Vote votes in the poll open in the client's room.  choice is the option's number or text.
+func (cl *Client) Vote(choice string) *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if choice == "" {
+		return NewResponse(false, 22, "You must enter the number of the option you are voting for.", nil)
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return resp
+	}
+	if cl.shadowbanned() {
+		return NewResponse(true, 0, "", nil)
+	}
+	err := cl.room.Vote(cl.Name(), choice)
+	switch {
+	case err == room.ErrNoPoll:
+		return NewResponse(false, 91, "There is no poll open in this room.", nil)
+	case err == room.ErrAlreadyVoted:
+		return NewResponse(false, 92, "You have already voted in this poll.", nil)
+	case err == room.ErrInvalidOption:
+		return NewResponse(false, 93, "That is not one of the options.", nil)
+	}
+	return NewResponse(true, 0, "", nil)
+}
+
+//ClosePoll closes the poll open in the client's room.  Only the poll's creator or an admin can close it.
+func (cl *Client) ClosePoll() *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	p := cl.room.Poll()
+	if p == nil {
+		return NewResponse(false, 91, "There is no poll open in this room.", nil)
+	}
+	if p.Creator != cl.Name() && !cl.isAdmin() {
+		return notPermitted()
+	}
+	if cl.room.ClosePoll() == room.ErrNoPoll {
+		return NewResponse(false, 91, "There is no poll open in this room.", nil)
+	}
+	return NewResponse(true, 0, "", nil)
+}
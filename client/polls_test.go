@@ -0,0 +1,38 @@
+package client
+
+import (
+	"testing"
+)
+
+func TestSplitQuoted(t *testing.T) {
+	var tests = []struct {
+		s      string
+		fields []string
+		quoted []bool
+	}{
+		{`"Lunch?" Pizza Tacos`, []string{"Lunch?", "Pizza", "Tacos"}, []bool{true, false, false}},
+		{`anon 10m "Where to?" "Thai place" Diner`, []string{"anon", "10m", "Where to?", "Thai place", "Diner"}, []bool{false, false, true, true, false}},
+		{`"10m" "is this long?" yes no`, []string{"10m", "is this long?", "yes", "no"}, []bool{true, true, false, false}},
+		{`  two   spaces  ""`, []string{"two", "spaces", ""}, []bool{false, false, true}},
+	}
+	for _, tt := range tests {
+		fields, quoted, err := splitQuoted(tt.s)
+		if err != nil {
+			t.Errorf("splitQuoted(%q) => %v", tt.s, err)
+			continue
+		}
+		if len(fields) != len(tt.fields) || len(quoted) != len(tt.quoted) {
+			t.Errorf("splitQuoted(%q) => %q %v, want %q %v", tt.s, fields, quoted, tt.fields, tt.quoted)
+			continue
+		}
+		for i := range fields {
+			if fields[i] != tt.fields[i] || quoted[i] != tt.quoted[i] {
+				t.Errorf("splitQuoted(%q) => %q %v, want %q %v", tt.s, fields, quoted, tt.fields, tt.quoted)
+				break
+			}
+		}
+	}
+	if _, _, err := splitQuoted(`"Lunch? Pizza Tacos`); err != errUnclosedQuote {
+		t.Errorf("unclosed quote => %v, want %v", err, errUnclosedQuote)
+	}
+}
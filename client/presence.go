@@ -0,0 +1,204 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Presence states.
+const (
+	PresenceOnline    = "online"
+	PresenceAway      = "away"
+	PresenceBusy      = "busy"
+	PresenceInvisible = "invisible" //online but shown to others as offline
+	PresenceOffline   = "offline"
+)
+
+//DefaultIdleAway is how long a client can go without sending a command before they are shown as away if Options doesn't say.
+const DefaultIdleAway = 10 * time.Minute
+
+//passiveCommands are commands that clients send on their own to refresh their view so they don't count as activity.
+var passiveCommands = map[string]bool{"who": true, "friendlist": true, "list": true, "unread": true}
+
+//Presence is a client's presence as shown to others.  Idle is true if they are away because they haven't done anything for a while.
+type Presence struct {
+	Name   string
+	State  string
+	Status string
+	Idle   bool
+}
+
+//presence is the client's presence state.  It is read by other clients so it has its own lock.
+type presence struct {
+	lock   sync.Mutex
+	state  string
+	status string
+	active time.Time
+}
+
+//loadPresence sets the client's presence to what they last set.  Away doesn't last past logging out but busy, invisible and the status text do.
+func (cl *Client) loadPresence() {
+	cl.presence.active = time.Now()
+	cl.presence.state = PresenceOnline
+	state, status, err := cl.data.Presence()
+	if err != nil {
+		log.Println("Error Presence: ", err)
+		return
+	}
+	if state == PresenceBusy || state == PresenceInvisible {
+		cl.presence.state = state
+	}
+	cl.presence.status = status
+}
+
+//touch records that the client did something which ends idle away.
+func (cl *Client) touch(command string) {
+	if passiveCommands[command] {
+		return
+	}
+	cl.presence.lock.Lock()
+	if cl.idleAway > 0 && time.Since(cl.presence.active) > cl.idleAway {
+		cl.versions.presenceChanged()
+	}
+	cl.presence.active = time.Now()
+	cl.presence.lock.Unlock()
+}
+
+//Presence returns the client's presence.
+func (cl *Client) Presence() Presence {
+	cl.presence.lock.Lock()
+	defer cl.presence.lock.Unlock()
+	p := Presence{Name: cl.Name(), State: cl.presence.state, Status: cl.presence.status}
+	if p.State == PresenceOnline && cl.idleAway > 0 && time.Since(cl.presence.active) > cl.idleAway {
+		p.State = PresenceAway
+		p.Idle = true
+	}
+	return p
+}
+
+//invisible returns true if the client is online but shown to others as offline.
+func (cl *Client) invisible() bool {
+	return cl.Presence().State == PresenceInvisible
+}
+
+//VisiblePresence returns the client's presence as other clients see it.  Invisible clients are shown as offline.
+func (cl *Client) VisiblePresence() Presence {
+	p := cl.Presence()
+	if p.State == PresenceInvisible {
+		return Presence{Name: p.Name, State: PresenceOffline}
+	}
+	return p
+}
+
+//String formats the presence as Name (state: status).  Online with no status is just the name.
+func (p Presence) String() string {
+	switch {
+	case p.State == PresenceOnline && p.Status == "":
+		return p.Name
+	case p.State == PresenceOnline:
+		return fmt.Sprintf("%v (%v)", p.Name, p.Status)
+	case p.Status == "":
+		return fmt.Sprintf("%v (%v)", p.Name, p.State)
+	default:
+		return fmt.Sprintf("%v (%v: %v)", p.Name, p.State, p.Status)
+	}
+}
+
+//setPresence changes and saves the client's presence.
+func (cl *Client) setPresence(state, status string) *Response {
+	err := cl.data.SetPresence(state, status)
+	if err != nil {
+		log.Println("Error SetPresence: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.presence.lock.Lock()
+	cl.presence.state = state
+	cl.presence.status = status
+	cl.presence.active = time.Now()
+	cl.presence.lock.Unlock()
+	cl.versions.presenceChanged()
+	p := cl.Presence()
+	return NewResponse(true, 0, "Your status is now "+p.String()+".", p)
+}
+
+//Away marks the client away with text as their status.  With no text it marks an away client back online.
+func (cl *Client) Away(text string) *Response {
+	if text == "" && cl.Presence().State == PresenceAway {
+		return cl.setPresence(PresenceOnline, "")
+	}
+	if text != "" {
+		var rejected *Response
+		if text, rejected = cl.filterText("", text); rejected != nil {
+			return rejected
+		}
+	}
+	return cl.setPresence(PresenceAway, text)
+}
+
+//Status shows the client's presence or changes it.  args may start with a state and the rest is the status text.  clear removes the status text.
+func (cl *Client) Status(args []string) *Response {
+	text := strings.TrimSpace(strings.Join(args, " "))
+	if text == "" {
+		p := cl.Presence()
+		return NewResponse(true, 0, "Your status is "+p.String()+".", p)
+	}
+	cl.presence.lock.Lock()
+	state, status := cl.presence.state, cl.presence.status
+	cl.presence.lock.Unlock()
+	switch first := strings.ToLower(args[0]); first {
+	case PresenceOnline, PresenceAway, PresenceBusy, PresenceInvisible:
+		state = first
+		status = strings.TrimSpace(strings.Join(args[1:], " "))
+	default:
+		status = text
+	}
+	if status == "clear" || status == "none" {
+		status = ""
+	} else if status != "" {
+		var rejected *Response
+		if status, rejected = cl.filterText("", status); rejected != nil {
+			return rejected
+		}
+	}
+	return cl.setPresence(state, status)
+}
+
+//autoReply tells from that the client is away or busy.
+func (cl *Client) autoReply(from *Client) {
+	p := cl.VisiblePresence()
+	if p.State != PresenceAway && p.State != PresenceBusy {
+		return
+	}
+	reply := fmt.Sprintf("%v is %v.", cl.Name(), p.State)
+	if p.Status != "" {
+		reply = fmt.Sprintf("%v is %v: %v", cl.Name(), p.State, p.Status)
+	}
+	from.Recieve(message.NewServerMessage(reply))
+}
+
+//presenceOf returns name's presence as this client sees it.  Clients that aren't online and invisible clients are offline.  Bots are always online.
+func (cl *Client) presenceOf(name string) Presence {
+	if name == cl.Name() {
+		return cl.Presence()
+	}
+	return cl.presenceOfClient(name, cl.rooms.GetClient(name))
+}
+
+//presenceOfClient returns the presence of other whose name is name as this client sees it.  other is nil if they aren't online.
+func (cl *Client) presenceOfClient(name string, other room.Client) Presence {
+	if name == cl.Name() {
+		return cl.Presence()
+	}
+	if other == nil {
+		return Presence{Name: name, State: PresenceOffline}
+	}
+	if othc, ok := other.(*Client); ok {
+		return othc.VisiblePresence()
+	}
+	return Presence{Name: name, State: PresenceOnline}
+}
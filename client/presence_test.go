@@ -0,0 +1,153 @@
+package client
+
+import (
+	"bytes"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//testConnection keeps the messages sent to a client.
+type testConnection struct {
+	lock     sync.Mutex
+	messages []message.Message
+}
+
+func (c *testConnection) SendMessage(m message.Message) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.messages = append(c.messages, m)
+}
+
+func (c *testConnection) Close() {
+}
+
+//takeMessages returns the messages sent since the last call.
+func (c *testConnection) takeMessages() []message.Message {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	m := c.messages
+	c.messages = nil
+	return m
+}
+
+//newTestFactory returns a factory with accounts for names.
+func newTestFactory(t *testing.T, options Options, names ...string) (*Factory, clientdata.Factory) {
+	df := filedata.NewMemDataFactory()
+	for _, name := range names {
+		if err := df.Create(name).NewClient(name + "sPassword"); err != nil {
+			t.Fatal("Error creating client: ", err)
+		}
+	}
+	return NewFactory(room.NewRoomList(100), new(bytes.Buffer), df, options), df
+}
+
+//newTestClient logs name in and returns the client and its connection.
+func newTestClient(f *Factory, name string) (*Client, *testConnection) {
+	conn := new(testConnection)
+	return New(name, f, conn), conn
+}
+
+func TestIdleAway(t *testing.T) {
+	f, _ := newTestFactory(t, Options{IdleAway: time.Hour}, "Fred")
+	fred, _ := newTestClient(f, "Fred")
+	if p := fred.Presence(); p.State != PresenceOnline || p.Idle {
+		t.Fatalf("Expected a new client to be online, got: %+v", p)
+	}
+	fred.presence.lock.Lock()
+	fred.presence.active = time.Now().Add(-2 * time.Hour)
+	fred.presence.lock.Unlock()
+	if p := fred.Presence(); p.State != PresenceAway || !p.Idle {
+		t.Errorf("Expected an idle client to be away, got: %+v", p)
+	}
+	for command := range passiveCommands {
+		fred.Execute([]string{command, ""})
+		if p := fred.Presence(); p.State != PresenceAway {
+			t.Errorf("Expected %v not to count as activity, got: %+v", command, p)
+		}
+	}
+	fred.Execute([]string{"send", "back"})
+	if p := fred.Presence(); p.State != PresenceOnline || p.Idle {
+		t.Errorf("Expected sending a message to end idle away, got: %+v", p)
+	}
+	f, _ = newTestFactory(t, Options{IdleAway: -1}, "Fred")
+	fred, _ = newTestClient(f, "Fred")
+	fred.presence.active = time.Now().Add(-24 * time.Hour)
+	if p := fred.Presence(); p.State != PresenceOnline {
+		t.Errorf("Expected idle away to be off, got: %+v", p)
+	}
+}
+
+func TestAutoReply(t *testing.T) {
+	f, _ := newTestFactory(t, Options{}, "Fred", "Bob")
+	fred, fredConn := newTestClient(f, "Fred")
+	bob, _ := newTestClient(f, "Bob")
+	var tests = []struct {
+		status []string
+		reply  string
+	}{
+		{[]string{"away", "lunch"}, "Bob is away: lunch"},
+		{[]string{"busy"}, "Bob is busy."},
+		{[]string{"online", "clear"}, ""},
+	}
+	for _, tt := range tests {
+		if r := bob.Status(tt.status); !r.Success() {
+			t.Fatalf("Error setting status %v: %v", tt.status, r)
+		}
+		fredConn.takeMessages()
+		if r := fred.Tell("Bob", "hi"); !r.Success() || r.String() != "" {
+			t.Errorf("Expected the tell to be sent, got: %v %q", r.Success(), r.String())
+		}
+		reply := ""
+		for _, m := range fredConn.takeMessages() {
+			if sm, ok := m.(*message.ServerMessage); ok {
+				reply = sm.Text
+			}
+		}
+		if reply != tt.reply {
+			t.Errorf("Auto reply with status %v => %q, want %q", tt.status, reply, tt.reply)
+		}
+	}
+}
+
+func TestInvisible(t *testing.T) {
+	f, df := newTestFactory(t, Options{}, "Fred", "Bob", "Sue")
+	_ = df.Create("Fred").Friend("Bob")
+	_ = df.Create("Fred").Friend("Sue")
+	fred, fredConn := newTestClient(f, "Fred")
+	bob, bobConn := newTestClient(f, "Bob")
+	bob.Status([]string{"invisible"})
+	who := fred.Who("Lobby").Data().(WhoData)
+	if len(who.Clients) != 1 || who.Clients[0] != "Fred" {
+		t.Errorf("Expected Bob to be left out of who, got: %v", who.Clients)
+	}
+	for _, friend := range fred.FriendList().Data().([]Friend) {
+		if friend.State != PresenceOffline {
+			t.Errorf("Expected %v to be shown offline, got: %+v", friend.Name, friend)
+		}
+	}
+	fredConn.takeMessages()
+	bobConn.takeMessages()
+	toBob, toSue := fred.Tell("Bob", "hi"), fred.Tell("Sue", "hi")
+	if toBob.String() != strings.Replace(toSue.String(), "Sue", "Bob", 1) || toBob.Code() != toSue.Code() {
+		t.Errorf("Expected a tell to an invisible client to look like one to an offline client, got: %q and %q", toBob.String(), toSue.String())
+	}
+	if m := fredConn.takeMessages(); len(m) != 0 {
+		t.Errorf("Expected no echo of a tell to an invisible client, got: %v", m)
+	}
+	if m := bobConn.takeMessages(); len(m) != 1 {
+		t.Errorf("Expected the invisible client to get the tell, got: %v", m)
+	}
+	fred.Join("Games")
+	fredConn.takeMessages()
+	bob.Join("Games")
+	bob.Leave()
+	if m := fredConn.takeMessages(); len(m) != 0 {
+		t.Errorf("Expected no join or leave from an invisible client, got: %v", m)
+	}
+}
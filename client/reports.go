@@ -0,0 +1,190 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/lockout"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//reportContext is how many messages from before and after a reported message are saved with the report.
+const reportContext = 5
+
+//DefaultReportMute is how long /resolve id mute mutes someone if no duration is given.
+const DefaultReportMute = 10 * time.Minute
+
+//Report saves a report of name for the moderators to review.  args may start with the number of the reported message in the client's room, written like #3, followed by the reason.  Without a number the last message name sent to the room is reported, or the room's recent messages are saved if there isn't one.
+func (cl *Client) Report(name string, args []string) *Response {
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if name == "" || len(args) == 0 {
+		return NewResponse(false, 22, "Use report user [#message number] reason.", nil)
+	}
+	if !clientdata.ValidateName(name) {
+		return NewResponse(false, 20, "Invalid name.  Name must be alphanumeric characters only.", nil)
+	}
+	if name == cl.Name() {
+		return NewResponse(false, 85, "You can't report yourself.", nil)
+	}
+	if ex, err := cl.data.ClientExists(name); !ex {
+		if err != nil {
+			log.Println(err)
+		}
+		return NewResponse(false, 42, "No client with that name exists.", nil)
+	}
+	seq := cl.room.Latest(name)
+	if strings.HasPrefix(args[0], "#") {
+		n, err := strconv.Atoi(args[0][1:])
+		if err != nil || n < 1 {
+			return NewResponse(false, 22, "Use report user [#message number] reason.", nil)
+		}
+		seq = n
+		args = args[1:]
+	}
+	reason := strings.TrimSpace(strings.Join(args, " "))
+	if reason == "" {
+		return NewResponse(false, 22, "You must give a reason for the report.", nil)
+	}
+	text := ""
+	context := make([]string, 0)
+	if seq > 0 {
+		msg, around := cl.room.Context(seq, reportContext)
+		if msg == nil || msg.Name() != name {
+			return NewResponse(false, 84, fmt.Sprintf("There is no message %v from %v in this room.", seq, name), nil)
+		}
+		text = msg.String()
+		for _, m := range around {
+			context = append(context, m.String())
+		}
+	} else {
+		history := cl.room.GetMessages()
+		if len(history) > reportContext {
+			history = history[len(history)-reportContext:]
+		}
+		context = append(context, history...)
+	}
+	r, err := cl.data.AddReport(name, cl.room.Name(), seq, text, context, reason)
+	if err != nil {
+		log.Println("Error AddReport: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	note := fmt.Sprintf("New report #%v: %v reported %v in %v: %v", r.ID, r.Reporter, r.Reported, r.Room, r.Reason)
+	for _, admin := range cl.onlineAdmins() {
+		admin.Recieve(message.NewServerMessage(note))
+	}
+	return NewResponse(true, 0, "Thank you.  Your report has been sent to the moderators.", nil)
+}
+
+//Reports shows moderators the reports waiting to be reviewed, or every report with who resolved it and how if which is all.
+func (cl *Client) Reports(which string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	list, err := cl.data.Reports(which != "all")
+	if err != nil {
+		log.Println("Error Reports: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	if len(list) == 0 {
+		return NewResponse(true, 0, "There are no reports to review.", list)
+	}
+	sresp := "Reports:"
+	for i := range list {
+		sresp = sresp + "\r\n" + list[i].String()
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//resolveUsage is the response for a resolve command that can't be understood.
+func resolveUsage() *Response {
+	return NewResponse(false, 22, "Use resolve id dismiss|warn|mute [duration]|ban [duration]|shadowban [note].", nil)
+}
+
+//Resolve closes report id with action which is dismiss, warn, mute, ban or shadowban.  Mute and ban can be followed by a duration and every action by a note that is saved with the report.  Warnings are given as the note.
+func (cl *Client) Resolve(id, action string, args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if id == "" {
+		return resolveUsage()
+	}
+	r, err := cl.data.Report(strings.TrimPrefix(id, "#"))
+	switch {
+	case err == clientdata.ErrReportNotFound:
+		return NewResponse(false, 98, "There is no report with that id.", nil)
+	case err != nil:
+		log.Println("Error Report: ", err)
+		return NewResponse(false, 50, "", nil)
+	case !r.Open():
+		return NewResponse(false, 99, fmt.Sprintf("That report was already resolved by %v.", r.ResolvedBy), nil)
+	}
+	note := strings.TrimSpace(strings.Join(args, " "))
+	switch strings.ToLower(action) {
+	case "dismiss":
+	case "warn":
+		warning := "You have been warned by the moderators"
+		if note != "" {
+			warning += ": " + note
+		} else {
+			warning += " for " + r.Reason
+		}
+		cl.warn(r.Reported, warning)
+	case "mute":
+		d := DefaultReportMute
+		if len(args) > 0 {
+			if parsed, err := parseDuration(args[0]); err == nil && parsed > 0 {
+				d = parsed
+				note = strings.TrimSpace(strings.Join(args[1:], " "))
+			}
+		}
+		if d > MaxMute {
+			d = MaxMute
+		}
+		cl.flood.mute(r.Reported, d)
+		cl.tellClient(r.Reported, message.NewServerMessage("You have been muted for "+lockout.WaitString(d)+" by the moderators."))
+		note = strings.TrimSpace("for " + lockout.WaitString(d) + " " + note)
+	case "ban":
+		if len(args) == 0 {
+			args = []string{r.Reason}
+		}
+		if resp := cl.Ban(r.Reported, args); !resp.Success() {
+			return resp
+		}
+	case "shadowban":
+		reason := note
+		if reason == "" {
+			reason = r.Reason
+		}
+		if resp := cl.Shadowban(r.Reported, reason); !resp.Success() {
+			return resp
+		}
+	default:
+		return resolveUsage()
+	}
+	r, err = cl.data.ResolveReport(r.ID, strings.ToLower(action), note)
+	switch {
+	case err == clientdata.ErrReportResolved:
+		return NewResponse(false, 99, "That report was already resolved.", nil)
+	case err != nil:
+		log.Println("Error ResolveReport: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.audit("resolve", r.Reported, strings.TrimSpace("#"+r.ID+" "+r.Action+" "+r.Note))
+	return NewResponse(true, 0, fmt.Sprintf("Report #%v resolved: %v.", r.ID, r.Action), r)
+}
+
+//warn gives name a warning from the moderators, by mail if they aren't online.
+func (cl *Client) warn(name, text string) {
+	if other := cl.rooms.GetClient(name); other != nil {
+		other.Recieve(message.NewServerMessage(text))
+		return
+	}
+	if err := cl.data.SendMail(name, text); err != nil {
+		log.Println("Error SendMail: ", err)
+	}
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+	"strings"
+	"time"
+)
+
+//Layouts accepted for an absolute time by parseWhen.
+var whenLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02T3:04pm"}
+
+//Layouts accepted for a time of day by parseWhen.
+var clockLayouts = []string{"15:04", "3:04pm", "3pm"}
+
+//parseWhen parses a duration from now like 10m or 2d, a date and time like 2016-04-06T15:04 or a time of day like 3:04pm which is the next time that time comes.  Times are in the server's time zone.
+func parseWhen(s string, now time.Time) (time.Time, error) {
+	if d, err := parseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("invalid time %q", s)
+		}
+		return now.Add(d), nil
+	}
+	for _, layout := range whenLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range clockLayouts {
+		if t, err := time.ParseInLocation(layout, strings.ToLower(s), now.Location()); err == nil {
+			at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+			if !at.After(now) {
+				at = at.AddDate(0, 0, 1)
+			}
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+//invalidTime is the response for a time parseWhen can't understand.
+func invalidTime() *Response {
+	return NewResponse(false, 49, "Invalid time.  Use a duration like 10m, 2h or 3d, a time like 3:30pm or a date like 2016-04-06T15:04.", nil)
+}
+
+//Remind schedules a reminder of text to the client at when.
+func (cl *Client) Remind(when, text string) *Response {
+	if when == "" || text == "" {
+		return NewResponse(false, 22, "You must enter when to remind you and what to remind you of.", nil)
+	}
+	at, err := parseWhen(when, time.Now())
+	if err != nil {
+		return invalidTime()
+	}
+	return cl.schedule(clientdata.ScheduleRemind, cl.Name(), text, at)
+}
+
+//ScheduleMessage schedules text to be sent to room at when.
+func (cl *Client) ScheduleMessage(room, when, text string) *Response {
+	if room == "" || when == "" || text == "" {
+		return NewResponse(false, 22, "You must enter a room, when to send the message and the message.", nil)
+	}
+	if !clientdata.ValidateName(room) {
+		return NewResponse(false, 20, "Invalid room name.  Name may only contain alphanumeric characters.", nil)
+	}
+	at, err := parseWhen(when, time.Now())
+	if err != nil {
+		return invalidTime()
+	}
+	if resp := cl.muted(); resp != nil {
+		return resp
+	}
+	if resp := cl.throttle(cl.flood.messages); resp != nil {
+		return resp
+	}
+	text, resp := cl.filterText(room, text)
+	if resp != nil {
+		return resp
+	}
+	return cl.schedule(clientdata.ScheduleRoom, room, text, at)
+}
+
+//CheckScheduled returns the text to send owner's scheduled message to rm with or an error if they are muted, sending too fast, held by slow mode or the content filter rejects the message.  It holds scheduled room messages to the same rules as messages the factory's clients send when they come due so the factory can be the scheduler's Gate.
+func (f *Factory) CheckScheduled(owner string, rm *room.Room, text string) (string, error) {
+	if f.flood.muteWait(owner) > 0 {
+		return "", errors.New("you are muted")
+	}
+	if f.flood.messages.Take(owner) > 0 {
+		return "", errors.New("you are sending messages too fast")
+	}
+	if rm.SlowMode() > 0 {
+		admin, err := f.data.Create(owner).IsAdmin()
+		if err != nil {
+			return "", err
+		}
+		if !admin && rm.SlowWait(owner) > 0 {
+			return "", fmt.Errorf("%v is in slow mode", rm.Name())
+		}
+	}
+	r := f.filter.Check(rm.Name(), text)
+	if r.Rejected {
+		return "", errors.New("it was blocked by the content filter")
+	}
+	return r.Text, nil
+}
+
+//schedule saves a scheduled item and confirms it to the client.
+func (cl *Client) schedule(kind, target, text string, at time.Time) *Response {
+	item, err := cl.data.Schedule(kind, target, text, at)
+	if err != nil {
+		log.Println("Error Schedule: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	return NewResponse(true, 0, fmt.Sprintf("Scheduled %v for %v.", item.ID, at.Format("Jan 2 3:04pm")), item)
+}
+
+//Reminders lists the client's reminders and scheduled messages.
+func (cl *Client) Reminders() *Response {
+	list, err := cl.data.ScheduledItems(cl.Name())
+	if err != nil {
+		log.Println("Error ScheduledItems: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Scheduled:"
+	for _, item := range list {
+		to := "reminder"
+		if item.Kind == clientdata.ScheduleRoom {
+			to = "to " + item.Target
+		}
+		sresp += fmt.Sprintf("\r\n%v %v %v: %v", item.ID, item.At.Format("Jan 2 3:04pm"), to, item.Text)
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//Unschedule cancels the client's scheduled item id.
+func (cl *Client) Unschedule(id string) *Response {
+	if id == "" {
+		return NewResponse(false, 22, "You must enter the id of the item to cancel.", nil)
+	}
+	err := cl.data.Unschedule(id)
+	switch {
+	case err == clientdata.ErrScheduleNotFound:
+		return NewResponse(false, 48, "You have nothing scheduled with that id.", nil)
+	case err != nil:
+		log.Println("Error Unschedule: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		return NewResponse(true, 0, fmt.Sprintf("Cancelled %v.", id), nil)
+	}
+}
+
+//deliverMail sends the client any mail saved while they were offline as tells.
+func (cl *Client) deliverMail() {
+	mail, err := cl.data.TakeMail()
+	if err != nil {
+		log.Println("Error TakeMail: ", err)
+		return
+	}
+	for _, m := range mail {
+		msg := message.NewTellMessage(m.Text, m.From, cl.Name(), true)
+		msg.Time = m.Sent
+		msg.TimeString = m.Sent.Format("3:04pm")
+		cl.Recieve(msg)
+	}
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"log"
+	"sync"
+	"time"
+)
+
+//ShadowList is the set of shadowbanned accounts.  It is kept in memory so rooms can check every message without going to the data.
+type ShadowList struct {
+	lock  sync.RWMutex
+	names map[string]bool
+}
+
+//NewShadowList returns an empty ShadowList.  Load it from the data and pass it to the rooms with RoomList.SetShadow and to the clients in Options when the server starts.
+func NewShadowList() *ShadowList {
+	return &ShadowList{names: make(map[string]bool)}
+}
+
+//Shadowbans returns the factory's list of shadowbanned accounts.
+func (f *Factory) Shadowbans() *ShadowList {
+	return f.shadow
+}
+
+//Load replaces the list with the shadowbans saved in data.
+func (s *ShadowList) Load(data clientdata.ClientData) error {
+	list, err := data.Shadowbans()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(list))
+	for i := range list {
+		names[list[i].Name] = true
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.names = names
+	return nil
+}
+
+//Shadowbanned returns true if name is shadowbanned.
+func (s *ShadowList) Shadowbanned(name string) bool {
+	if s == nil {
+		return false
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.names[name]
+}
+
+//set marks name shadowbanned or not.
+func (s *ShadowList) set(name string, shadowbanned bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if shadowbanned {
+		s.names[name] = true
+	} else {
+		delete(s.names, name)
+	}
+}
+
+//shadowbanned returns true if the client is shadowbanned.
+func (cl *Client) shadowbanned() bool {
+	return cl.shadow.Shadowbanned(cl.Name())
+}
+
+//Shadowban shadowbans the account name.  Their room messages, emotes, files, typing, topic changes and polls only go back to them, their votes aren't counted and their tells and direct messages seem to be sent but aren't delivered or saved.  They aren't told.
+func (cl *Client) Shadowban(name, reason string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if name == "" {
+		return NewResponse(false, 22, "You must enter a user to shadowban.", nil)
+	}
+	if !clientdata.ValidateName(name) {
+		return NewResponse(false, 20, "Invalid name.  Name must be alphanumeric characters only.", nil)
+	}
+	if cl.Name() == name {
+		return NewResponse(false, 34, "You can't ban yourself.", nil)
+	}
+	if ex, err := cl.data.ClientExists(name); !ex {
+		if err != nil {
+			log.Println(err)
+		}
+		return NewResponse(false, 42, "No client with that name exists.", nil)
+	}
+	if err := cl.data.Shadowban(name, reason); err != nil {
+		log.Println("Error Shadowban: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.shadow.set(name, true)
+	cl.audit("shadowban", name, reason)
+	return NewResponse(true, 0, fmt.Sprintf("%v has been shadowbanned.", name), nil)
+}
+
+//Unshadowban lifts the shadowban on name.
+func (cl *Client) Unshadowban(name string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	if name == "" {
+		return NewResponse(false, 22, "You must enter a shadowban to lift.", nil)
+	}
+	err := cl.data.Unshadowban(name)
+	switch {
+	case err == clientdata.ErrNotShadowbanned:
+		return NewResponse(false, 33, fmt.Sprintf("%v is not shadowbanned.", name), nil)
+	case err != nil:
+		log.Println("Error Unshadowban: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.shadow.set(name, false)
+	cl.audit("unshadowban", name, "")
+	return NewResponse(true, 0, fmt.Sprintf("%v is no longer shadowbanned.", name), nil)
+}
+
+//ShadowbanList provides the list of shadowbanned accounts.
+func (cl *Client) ShadowbanList() *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	list, err := cl.data.Shadowbans()
+	if err != nil {
+		log.Println("Error Shadowbans: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Shadowban List:"
+	for i := range list {
+		sresp = sresp + "\r\n" + list[i].String()
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//shadowTell answers a tell from a shadowbanned client to name as if it was sent.  sent is the tell to show the client if name is online or nil if they aren't.
+func (cl *Client) shadowTell(name string, sent *message.TellMessage) *Response {
+	if sent != nil {
+		cl.Recieve(sent)
+		return NewResponse(true, 0, "", nil)
+	}
+	if ex, _ := cl.data.ClientExists(name); ex {
+		return NewResponse(true, 0, fmt.Sprintf("%v is offline.  They can read your message with /dmhistory %v.", name, cl.Name()), nil)
+	}
+	return NewResponse(false, 42, "Could not find a client with that name.", nil)
+}
+
+//shadowDM answers a direct message from a shadowbanned client to conversation c as if it was sent.
+func (cl *Client) shadowDM(c *clientdata.Conversation, text string) *Response {
+	dm := &clientdata.DirectMessage{Conversation: c.ID, Seq: c.Count + 1, From: cl.Name(), Text: text, Sent: time.Now()}
+	cl.Recieve(message.NewDirectMessage(c.ID, c.Members, dm.Seq, text, cl.Name()))
+	return NewResponse(true, 0, "", dm)
+}
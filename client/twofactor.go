@@ -0,0 +1,95 @@
+package client
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"log"
+	"strings"
+)
+
+//TwoFactor runs the two-factor authentication subcommands enroll, confirm, disable and status.
+func (cl *Client) TwoFactor(sub string, args []string) *Response {
+	arg := ""
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	switch strings.ToLower(sub) {
+	case "enroll":
+		return cl.EnrollTwoFactor()
+	case "confirm":
+		return cl.ConfirmTwoFactor(arg)
+	case "disable":
+		return cl.DisableTwoFactor(arg)
+	case "", "status":
+		enabled, err := cl.data.TOTPEnabled()
+		if err != nil {
+			log.Println("Error TOTPEnabled: ", err)
+			return NewResponse(false, 50, "", nil)
+		}
+		if enabled {
+			return NewResponse(true, 0, "Two-factor authentication is enabled.", true)
+		}
+		return NewResponse(true, 0, "Two-factor authentication is not enabled.", false)
+	default:
+		return NewResponse(false, 70, "Invalid Command.  Use 2fa enroll, confirm, disable or status.", nil)
+	}
+}
+
+//EnrollTwoFactor starts enrollment and returns the provisioning URI and recovery codes.
+func (cl *Client) EnrollTwoFactor() *Response {
+	e, err := cl.data.EnrollTOTP()
+	switch {
+	case err == clientdata.ErrTOTPEnabled:
+		return NewResponse(false, 27, "Two-factor authentication is already enabled.", nil)
+	case err != nil:
+		log.Println("Error EnrollTOTP: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Add this account to your authenticator app then use 2fa confirm with a code from the app." +
+		"\r\n" + e.URI +
+		"\r\nSecret: " + e.Secret +
+		"\r\nRecovery codes (each can be used once if you lose your authenticator):"
+	for _, code := range e.RecoveryCodes {
+		sresp = sresp + "\r\n" + code
+	}
+	return NewResponse(true, 0, sresp, e)
+}
+
+//ConfirmTwoFactor turns on two-factor authentication once the client proves their authenticator works.
+func (cl *Client) ConfirmTwoFactor(code string) *Response {
+	if code == "" {
+		return NewResponse(false, 22, "You must enter a code from your authenticator.", nil)
+	}
+	err := cl.data.ConfirmTOTP(code)
+	switch {
+	case err == clientdata.ErrTOTPNotEnrolled:
+		return NewResponse(false, 28, "You must use 2fa enroll first.", nil)
+	case err == clientdata.ErrTOTPEnabled:
+		return NewResponse(false, 27, "Two-factor authentication is already enabled.", nil)
+	case err == clientdata.ErrInvalidCode:
+		return NewResponse(false, 26, "Invalid two-factor code.", nil)
+	case err != nil:
+		log.Println("Error ConfirmTOTP: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		return NewResponse(true, 0, "Two-factor authentication is now enabled.", nil)
+	}
+}
+
+//DisableTwoFactor turns off two-factor authentication after checking the client's password.
+func (cl *Client) DisableTwoFactor(pword string) *Response {
+	if pword == "" {
+		return NewResponse(false, 22, "You must enter your password.", nil)
+	}
+	err := cl.data.DisableTOTP(pword)
+	switch {
+	case err == clientdata.ErrWrongPassword:
+		return NewResponse(false, 21, "Password does not match.", nil)
+	case err == clientdata.ErrTOTPNotEnabled:
+		return NewResponse(false, 28, "Two-factor authentication is not enabled.", nil)
+	case err != nil:
+		log.Println("Error DisableTOTP: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		return NewResponse(true, 0, "Two-factor authentication is now disabled.", nil)
+	}
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"strings"
+	"sync"
+	"time"
+)
+
+//TypingThrottle is the least time between typing indicators sent for a client.  Typing commands that come sooner only keep the indicator from expiring.
+var TypingThrottle = 3 * time.Second
+
+//TypingTimeout is how long a typing indicator lasts without another typing command.  A stop is sent to the room when it runs out.
+var TypingTimeout = 6 * time.Second
+
+//typing is the client's typing indicator.
+type typing struct {
+	lock  sync.Mutex
+	room  *room.Room //room the indicator was sent to, nil if the client isn't typing
+	sent  time.Time
+	timer *time.Timer
+	gen   int //changes each time the timer is replaced so an old timer can't stop a newer indicator
+}
+
+//Typing tells the client's room they are typing.  state stop tells it they stopped.
+func (cl *Client) Typing(state string) *Response {
+	switch strings.ToLower(state) {
+	case "stop", "false", "off":
+		cl.stopTyping()
+		return NewResponse(true, 0, "", nil)
+	}
+	if cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	rm := cl.room
+	cl.typing.lock.Lock()
+	if cl.typing.room != nil && cl.typing.room != rm {
+		cl.typing.room.Broadcast(message.NewTypingMessage(cl.Name(), false, TypingTimeout))
+		cl.typing.room = nil
+	}
+	send := cl.typing.room == nil || time.Since(cl.typing.sent) >= TypingThrottle
+	cl.typing.room = rm
+	if send {
+		cl.typing.sent = time.Now()
+	}
+	if cl.typing.timer != nil {
+		cl.typing.timer.Stop()
+	}
+	cl.typing.gen++
+	gen := cl.typing.gen
+	cl.typing.timer = time.AfterFunc(TypingTimeout, func() { cl.expireTyping(gen) })
+	cl.typing.lock.Unlock()
+	if send {
+		rm.Broadcast(message.NewTypingMessage(cl.Name(), true, TypingTimeout))
+	}
+	return NewResponse(true, 0, "", nil)
+}
+
+//stopTyping tells the room the client was typing in that they stopped.  It does nothing if they weren't typing.
+func (cl *Client) stopTyping() {
+	cl.typing.lock.Lock()
+	cl.endTyping()
+}
+
+//expireTyping stops the indicator when the timer from generation gen runs out.
+func (cl *Client) expireTyping(gen int) {
+	cl.typing.lock.Lock()
+	if cl.typing.gen != gen {
+		cl.typing.lock.Unlock()
+		return
+	}
+	cl.endTyping()
+}
+
+//endTyping clears the indicator and unlocks the typing lock which must be held when it is called.
+func (cl *Client) endTyping() {
+	rm := cl.typing.room
+	cl.typing.room = nil
+	if cl.typing.timer != nil {
+		cl.typing.timer.Stop()
+		cl.typing.timer = nil
+	}
+	cl.typing.lock.Unlock()
+	if rm != nil {
+		rm.Broadcast(message.NewTypingMessage(cl.Name(), false, TypingTimeout))
+	}
+}
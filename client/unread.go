@@ -0,0 +1,67 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/room"
+	"log"
+)
+
+//MarkRead marks every message sent to rmName so far read.  The default room is the client's room.
+func (cl *Client) MarkRead(rmName string) *Response {
+	if rmName == "" && cl.room == nil {
+		return NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	rm := cl.room
+	if rmName != "" {
+		rm = cl.rooms.FindRoom(rmName)
+	}
+	if rm == nil {
+		return NewResponse(false, 41, "That room was not found.", nil)
+	}
+	if err := cl.markRead(rm); err != nil {
+		log.Println("Error MarkRead: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	return NewResponse(true, 0, "", nil)
+}
+
+//markRead saves the room's current position as the client's read position.
+func (cl *Client) markRead(rm *room.Room) error {
+	epoch, seq := rm.Position()
+	cl.versions.changed(cl.Name())
+	return cl.data.MarkRead(rm.Name(), epoch, seq)
+}
+
+//Unread returns the number of unread messages in each room.  Messages in the client's room are read as they arrive.
+func (cl *Client) Unread() *Response {
+	unread, err := cl.unreadCounts(cl.rooms.Who())
+	if err != nil {
+		log.Println("Error ReadPositions: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Unread:"
+	for _, name := range cl.rooms.Who() {
+		if unread[name] > 0 {
+			sresp += fmt.Sprintf("\r\n%v %v", name, unread[name])
+		}
+	}
+	return NewResponse(true, 0, sresp, unread)
+}
+
+//unreadCounts returns the number of unread messages in each of rooms.  Rooms the client never read count every message.
+func (cl *Client) unreadCounts(rooms []string) (map[string]int, error) {
+	positions, err := cl.data.ReadPositions()
+	if err != nil {
+		return nil, err
+	}
+	unread := make(map[string]int)
+	for _, name := range rooms {
+		rm := cl.rooms.FindRoom(name)
+		if rm == nil || rm == cl.room {
+			unread[name] = 0
+			continue
+		}
+		unread[name] = rm.Unread(positions[name].Epoch, positions[name].Seq)
+	}
+	return unread, nil
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"github.com/DavidAFox/Chat/connections"
+)
+
+//CheckShare returns the name to share a file under in the client's room or a response if they can't share one right now because they are muted, sending or uploading too fast, held by slow mode or the content filter rejects the name.  It is called before the file is saved.
+func (cl *Client) CheckShare(filename string) (string, connections.Response) {
+	if cl.room == nil {
+		return "", NewResponse(false, 40, "You are not in a room.", nil)
+	}
+	if resp := cl.checkSend(cl.room); resp != nil {
+		return "", resp
+	}
+	if resp := cl.throttle(cl.flood.uploads); resp != nil {
+		return "", resp
+	}
+	filename, resp := cl.filterText(cl.room.Name(), filename)
+	if resp != nil {
+		return "", resp
+	}
+	return filename, nil
+}
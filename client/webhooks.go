@@ -0,0 +1,156 @@
+package client
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/webhook"
+	"log"
+	"strings"
+)
+
+//Webhook runs the administrator webhook subcommands add, list and remove.
+func (cl *Client) Webhook(sub string, args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	switch strings.ToLower(sub) {
+	case "add":
+		if len(args) < 2 {
+			return NewResponse(false, 22, "You must enter a room and a URL.", nil)
+		}
+		return cl.AddWebhook(args[0], args[1])
+	case "", "list":
+		room := ""
+		if len(args) > 0 {
+			room = args[0]
+		}
+		return cl.Webhooks(room)
+	case "remove":
+		if len(args) == 0 {
+			return NewResponse(false, 22, "You must enter the id of the webhook to remove.", nil)
+		}
+		return cl.RemoveWebhook(args[0])
+	default:
+		return NewResponse(false, 70, "Invalid Command.  Use webhook add, list or remove.", nil)
+	}
+}
+
+//AddWebhook subscribes url to the events in room.
+func (cl *Client) AddWebhook(room, url string) *Response {
+	w, err := cl.data.AddWebhook(room, url)
+	switch {
+	case err == clientdata.ErrInvalidName:
+		return NewResponse(false, 20, "Invalid room name.  Name may only contain alphanumeric characters.", nil)
+	case err == clientdata.ErrInvalidWebhook:
+		return NewResponse(false, 47, "Invalid URL.  Webhook URLs must be http or https.", nil)
+	case err != nil:
+		log.Println("Error AddWebhook: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.audit("webhook-add", room, w.ID+" "+w.URL)
+	return NewResponse(true, 0, fmt.Sprintf("Webhook %v added for %v.  Requests are signed with this secret which will not be shown again:\r\n%v", w.ID, room, w.Secret), w)
+}
+
+//Webhooks lists the webhooks for room or every webhook if room is empty.  Secrets are not shown.
+func (cl *Client) Webhooks(room string) *Response {
+	list, err := cl.data.Webhooks(room)
+	if err != nil {
+		log.Println("Error Webhooks: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Webhooks:"
+	for i := range list {
+		list[i].Secret = ""
+		sresp += fmt.Sprintf("\r\n%v %v %v", list[i].ID, list[i].Room, list[i].URL)
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//RemoveWebhook deletes the webhook id.
+func (cl *Client) RemoveWebhook(id string) *Response {
+	err := cl.data.RemoveWebhook(id)
+	switch {
+	case err == clientdata.ErrWebhookNotFound:
+		return NewResponse(false, 46, "No webhook with that id exists.", nil)
+	case err != nil:
+		log.Println("Error RemoveWebhook: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		cl.audit("webhook-remove", id, "")
+		return NewResponse(true, 0, fmt.Sprintf("Webhook %v removed.", id), nil)
+	}
+}
+
+//IncomingWebhook runs the administrator incoming webhook subcommands add, list and remove.
+func (cl *Client) IncomingWebhook(sub string, args []string) *Response {
+	if !cl.isAdmin() {
+		return notPermitted()
+	}
+	switch strings.ToLower(sub) {
+	case "add":
+		if len(args) == 0 {
+			return NewResponse(false, 22, "You must enter a room.", nil)
+		}
+		return cl.AddIncomingWebhook(args[0], strings.Join(args[1:], " "))
+	case "", "list":
+		room := ""
+		if len(args) > 0 {
+			room = args[0]
+		}
+		return cl.IncomingWebhooks(room)
+	case "remove":
+		if len(args) == 0 {
+			return NewResponse(false, 22, "You must enter the id of the webhook to remove.", nil)
+		}
+		return cl.RemoveIncomingWebhook(args[0])
+	default:
+		return NewResponse(false, 70, "Invalid Command.  Use incoming add, list or remove.", nil)
+	}
+}
+
+//AddIncomingWebhook creates an incoming webhook that posts into room as username.
+func (cl *Client) AddIncomingWebhook(room, username string) *Response {
+	token, w, err := cl.data.AddIncomingWebhook(room, username)
+	switch {
+	case err == clientdata.ErrInvalidName:
+		return NewResponse(false, 20, "Invalid room name.  Name may only contain alphanumeric characters.", nil)
+	case err != nil:
+		log.Println("Error AddIncomingWebhook: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	cl.audit("incoming-add", room, w.ID+" "+w.Username)
+	data := struct {
+		Path string
+		clientdata.IncomingWebhook
+	}{webhook.IncomingPath + token, *w}
+	return NewResponse(true, 0, fmt.Sprintf("Incoming webhook %v added for %v.  Post to this path on the HTTP server, it will not be shown again:\r\n%v", w.ID, room, data.Path), data)
+}
+
+//IncomingWebhooks lists the incoming webhooks for room or every incoming webhook if room is empty.
+func (cl *Client) IncomingWebhooks(room string) *Response {
+	list, err := cl.data.IncomingWebhooks(room)
+	if err != nil {
+		log.Println("Error IncomingWebhooks: ", err)
+		return NewResponse(false, 50, "", nil)
+	}
+	sresp := "Incoming Webhooks:"
+	for _, w := range list {
+		sresp += fmt.Sprintf("\r\n%v %v %v", w.ID, w.Room, w.Username)
+	}
+	return NewResponse(true, 0, sresp, list)
+}
+
+//RemoveIncomingWebhook deletes the incoming webhook id.
+func (cl *Client) RemoveIncomingWebhook(id string) *Response {
+	err := cl.data.RemoveIncomingWebhook(id)
+	switch {
+	case err == clientdata.ErrWebhookNotFound:
+		return NewResponse(false, 46, "No webhook with that id exists.", nil)
+	case err != nil:
+		log.Println("Error RemoveIncomingWebhook: ", err)
+		return NewResponse(false, 50, "", nil)
+	default:
+		cl.audit("incoming-remove", id, "")
+		return NewResponse(true, 0, fmt.Sprintf("Incoming webhook %v removed.", id), nil)
+	}
+}
@@ -0,0 +1,227 @@
+package clientdata
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Commands an API key can be scoped to.
+const (
+	ScopeRead = "read"
+	ScopeSend = "send"
+)
+
+//Scopes is every command an API key can be allowed to use.
+var Scopes = []string{ScopeRead, ScopeSend}
+
+var ErrKeyNotFound = errors.New("clientdata: API key not found.")
+var ErrInvalidScope = errors.New("clientdata: Invalid API key scope.")
+var ErrNotBot = errors.New("clientdata: That account is not a bot.")
+var ErrTooManyBots = errors.New("clientdata: Too many bots.")
+
+//MaxBots is the most bots a client can own so bots can't be used to get around the limit on new accounts.  Bots the server reserves aren't counted.
+var MaxBots = 5
+
+//APIKey describes an API key.  The key itself is only returned when it is created.  Empty Rooms or Commands mean the key may be used for any room or command.
+type APIKey struct {
+	ID       string
+	Name     string
+	Rooms    []string
+	Commands []string
+	Created  time.Time
+}
+
+//Allows returns true if the key may use command in room.
+func (k *APIKey) Allows(room, command string) bool {
+	return allowed(k.Rooms, room) && allowed(k.Commands, command)
+}
+
+//allowed returns true if list is empty or contains s.
+func allowed(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+//hashKey returns the hex sha256 of an API key.  Keys are long and random so a fast hash is enough.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+//splitList splits a comma separated column into a slice.
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+//CreateAPIKey makes a new API key for the account name limited to rooms and commands.  The returned key string is the only copy, only its hash is stored.
+func (cdd *DataAccess) CreateAPIKey(name string, rooms, commands []string) (string, *APIKey, error) {
+	for _, r := range rooms {
+		if !ValidateName(r) {
+			return "", nil, ErrInvalidName
+		}
+	}
+	for _, c := range commands {
+		if !allowed(Scopes, c) {
+			return "", nil, ErrInvalidScope
+		}
+	}
+	idb, err := randomBytes(4)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomBytes(24)
+	if err != nil {
+		return "", nil, err
+	}
+	k := &APIKey{ID: hex.EncodeToString(idb), Name: name, Rooms: rooms, Commands: commands, Created: time.Now()}
+	key := name + "." + k.ID + "." + hex.EncodeToString(secret)
+	err = cdd.data.Add("apikeys", row("name", name, "id", k.ID, "hash", hashKey(key), "rooms", strings.Join(rooms, ","), "commands", strings.Join(commands, ","), "created", k.Created.Format(TimeLayout)))
+	if err != nil {
+		return "", nil, err
+	}
+	return key, k, nil
+}
+
+//APIKeys returns the API keys for the account name sorted by creation time.
+func (cdd *DataAccess) APIKeys(name string) ([]APIKey, error) {
+	rows, err := cdd.data.Get("apikeys", row("name", name))
+	if err == ErrClientNotFound {
+		return []APIKey{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]APIKey, len(rows))
+	for i := range rows {
+		list[i] = APIKey{ID: rows[i]["id"], Name: name, Rooms: splitList(rows[i]["rooms"]), Commands: splitList(rows[i]["commands"])}
+		list[i].Created, _ = time.Parse(TimeLayout, rows[i]["created"])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list, nil
+}
+
+//RevokeAPIKey deletes the API key id belonging to the account name.
+func (cdd *DataAccess) RevokeAPIKey(name, id string) error {
+	exists, err := cdd.data.Exists("apikeys", row("name", name, "id", id))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrKeyNotFound
+	}
+	return cdd.data.Delete("apikeys", row("name", name, "id", id))
+}
+
+//AuthenticateAPIKey returns the APIKey for key or nil if it isn't a valid key.
+func (cdd *DataAccess) AuthenticateAPIKey(key string) (*APIKey, error) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || !ValidateName(parts[0]) || parts[0] == "" {
+		return nil, nil
+	}
+	rows, err := cdd.data.Get("apikeys", row("name", parts[0], "id", parts[1]))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(rows[0]["hash"]), []byte(hashKey(key))) != 1 {
+		return nil, nil
+	}
+	k := &APIKey{ID: parts[1], Name: parts[0], Rooms: splitList(rows[0]["rooms"]), Commands: splitList(rows[0]["commands"])}
+	k.Created, _ = time.Parse(TimeLayout, rows[0]["created"])
+	return k, nil
+}
+
+//CreateBot creates a bot account owned by this client.  Bots have a random password so they can only be used with API keys.  The server can reserve names for its bots even when account creation is disabled.
+func (cdd *DataAccess) CreateBot(name string) error {
+	if cdd.disableNewAccounts && cdd.name != ServerRecord {
+		return ErrAccountCreationDisabled
+	}
+	if !ValidateName(name) || name == "" {
+		return ErrInvalidName
+	}
+	exists, err := cdd.ClientExists(name)
+	switch {
+	case err != nil:
+		return err
+	case exists:
+		return ErrClientExists
+	}
+	taken, err := cdd.loginTaken(name)
+	switch {
+	case err != nil:
+		return err
+	case taken:
+		return ErrClientExists
+	}
+	if cdd.name != ServerRecord {
+		owned, err := cdd.Bots()
+		if err != nil {
+			return err
+		}
+		if len(owned) >= MaxBots {
+			return ErrTooManyBots
+		}
+	}
+	pword, err := randomBytes(24)
+	if err != nil {
+		return err
+	}
+	err = cdd.data.Add("client", row("password", Encrypt(hex.EncodeToString(pword)), "name", name, "lastonline", time.Now().String()))
+	if err != nil {
+		return err
+	}
+	if err = cdd.registerLogin(name); err != nil {
+		return err
+	}
+	err = cdd.data.Add("bots", row("name", name, "owner", cdd.name))
+	if err != nil {
+		return err
+	}
+	return cdd.data.Add("ownedbots", row("name", cdd.name, "bot", name))
+}
+
+//Bots returns the names of the bots this client owns.
+func (cdd *DataAccess) Bots() ([]string, error) {
+	rows, err := cdd.data.Get("ownedbots", row("name", cdd.name), "bot")
+	if err == ErrClientNotFound {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, len(rows))
+	for i := range rows {
+		list = append(list, rows[i]["bot"])
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+//BotOwner returns the name of the client that owns the bot name.  It returns ErrNotBot if name isn't a bot.
+func (cdd *DataAccess) BotOwner(name string) (string, error) {
+	rows, err := cdd.data.Get("bots", row("name", name), "owner")
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return "", ErrNotBot
+	}
+	if err != nil {
+		return "", err
+	}
+	return rows[0]["owner"], nil
+}
@@ -0,0 +1,46 @@
+package clientdata
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrAttachmentNotFound = errors.New("clientdata: Attachment not found.")
+
+//Attachment is a file the client shared in a room.  ID is the id the file was saved under.
+type Attachment struct {
+	ID       string
+	Room     string
+	Uploader string
+	Filename string
+	MIME     string
+	Size     int64
+	Created  time.Time
+}
+
+//AddAttachment records that the client shared file id in room.
+func (cdd *DataAccess) AddAttachment(id, room, filename, mime string, size int64) (*Attachment, error) {
+	a := &Attachment{ID: id, Room: room, Uploader: cdd.name, Filename: filename, MIME: mime, Size: size, Created: time.Now()}
+	err := cdd.data.Add("attachments", row("name", ServerRecord, "id", id, "room", room, "uploader", cdd.name, "filename", filename, "mime", mime, "size", strconv.FormatInt(size, 10), "created", a.Created.Format(TimeLayout)))
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+//Attachment returns the attachment id.  It will return ErrAttachmentNotFound if there isn't one.
+func (cdd *DataAccess) Attachment(id string) (*Attachment, error) {
+	rows, err := cdd.data.Get("attachments", row("name", ServerRecord, "id", id))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := rows[0]
+	a := &Attachment{ID: r["id"], Room: r["room"], Uploader: r["uploader"], Filename: r["filename"], MIME: r["mime"]}
+	a.Size, _ = strconv.ParseInt(r["size"], 10, 64)
+	a.Created, _ = time.Parse(TimeLayout, r["created"])
+	return a, nil
+}
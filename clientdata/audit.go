@@ -0,0 +1,189 @@
+package clientdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrAuditTampered = errors.New("clientdata: The audit log has been changed.")
+
+//auditLock keeps two events from being added to the audit log with the same place in the chain.
+var auditLock sync.Mutex
+
+//AuditEvent is a record of a privileged action such as a ban.  Actor is the client that did it, Target what it was done to and Source the address of the actor's connection.  Hash is the SHA-256 of the event and the Hash of the event before it, Prev, so changing or removing an event breaks the chain from there on.
+type AuditEvent struct {
+	Seq    int
+	Time   time.Time
+	Actor  string
+	Action string
+	Target string
+	Source string
+	Detail string
+	Prev   string
+	Hash   string
+}
+
+//String formats the event for display to administrators.
+func (e AuditEvent) String() string {
+	s := fmt.Sprintf("%v %v %v %v", e.Time.Format("Jan 2 2006 3:04pm"), e.Actor, e.Action, e.Target)
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	if e.Source != "" {
+		s += " (from " + e.Source + ")"
+	}
+	return s
+}
+
+//hash returns the hash of the event's fields and Prev.
+func (e AuditEvent) hash() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s\n%q\n%q\n%q\n%q\n%q\n%s", e.Seq, e.Time.Format(TimeLayout), e.Actor, e.Action, e.Target, e.Source, e.Detail, e.Prev)))
+	return hex.EncodeToString(sum[:])
+}
+
+//AuditQuery selects events from the audit log.  Empty fields match every event and a Limit of 0 returns them all.
+type AuditQuery struct {
+	Actor  string
+	Action string
+	Target string
+	Since  time.Time
+	Limit  int
+}
+
+//Audit adds an event to the audit log for an action the client did to target from the address source.  The new head of the log is saved and written to the server log so events removed from the end can be found.
+func (cdd *DataAccess) Audit(action, target, source, detail string) (*AuditEvent, error) {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	seq, prev, found, err := cdd.auditHead()
+	if err != nil {
+		return nil, err
+	}
+	e := &AuditEvent{Seq: seq + 1, Time: time.Now(), Actor: cdd.name, Action: action, Target: target, Source: source, Detail: detail, Prev: prev}
+	e.Hash = e.hash()
+	err = cdd.data.Add("audit", row("name", ServerRecord, "seq", strconv.Itoa(e.Seq), "time", e.Time.Format(TimeLayout), "actor", e.Actor, "action", e.Action, "target", e.Target, "source", e.Source, "detail", e.Detail, "prev", e.Prev, "hash", e.Hash))
+	if err != nil {
+		return nil, err
+	}
+	if err = cdd.setAuditHead(e.Seq, e.Hash, found); err != nil {
+		return nil, err
+	}
+	log.Printf("Audit log head is event %v %v", e.Seq, e.Hash)
+	return e, nil
+}
+
+//auditHead returns the number and hash of the newest event in the audit log.  found is false if the head hasn't been saved.
+func (cdd *DataAccess) auditHead() (seq int, hash string, found bool, err error) {
+	rows, err := cdd.data.Get("audithead", row("name", ServerRecord))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return 0, "", false, nil
+	}
+	if err != nil {
+		return 0, "", false, err
+	}
+	seq, err = strconv.Atoi(rows[0]["seq"])
+	return seq, rows[0]["hash"], true, err
+}
+
+//setAuditHead saves the number and hash of the newest event in the audit log.  exists is true if the head has been saved before.
+func (cdd *DataAccess) setAuditHead(seq int, hash string, exists bool) error {
+	if exists {
+		return cdd.data.Set("audithead", row("seq", strconv.Itoa(seq), "hash", hash), row("name", ServerRecord))
+	}
+	return cdd.data.Add("audithead", row("name", ServerRecord, "seq", strconv.Itoa(seq), "hash", hash))
+}
+
+//BackfillAuditHead saves the head of an audit log written before the head was kept.  It does nothing if the head has been saved.
+func (cdd *DataAccess) BackfillAuditHead() error {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+	_, _, found, err := cdd.auditHead()
+	if err != nil || found {
+		return err
+	}
+	events, err := cdd.auditEvents(row("name", ServerRecord))
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	last := events[len(events)-1]
+	return cdd.setAuditHead(last.Seq, last.Hash, false)
+}
+
+//AuditLog returns the events in the audit log that match q with the newest first.
+func (cdd *DataAccess) AuditLog(q AuditQuery) ([]AuditEvent, error) {
+	cond := row("name", ServerRecord)
+	if q.Actor != "" {
+		cond["actor"] = q.Actor
+	}
+	if q.Action != "" {
+		cond["action"] = q.Action
+	}
+	if q.Target != "" {
+		cond["target"] = q.Target
+	}
+	events, err := cdd.auditEvents(cond)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]AuditEvent, 0, len(events))
+	for i := len(events) - 1; i >= 0 && (q.Limit <= 0 || len(list) < q.Limit); i-- {
+		if events[i].Time.Before(q.Since) {
+			break
+		}
+		list = append(list, events[i])
+	}
+	return list, nil
+}
+
+//VerifyAudit checks the audit log's hash chain and that it ends at the saved head.  It returns the number of the first event that doesn't match and ErrAuditTampered if an event was changed, removed or added out of order.
+func (cdd *DataAccess) VerifyAudit() (int, error) {
+	events, err := cdd.auditEvents(row("name", ServerRecord))
+	if err != nil {
+		return 0, err
+	}
+	prev := ""
+	for i, e := range events {
+		if e.Seq != i+1 || e.Prev != prev || e.hash() != e.Hash {
+			return i + 1, ErrAuditTampered
+		}
+		prev = e.Hash
+	}
+	seq, hash, _, err := cdd.auditHead()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case len(events) < seq:
+		return len(events) + 1, ErrAuditTampered
+	case len(events) > seq:
+		return seq + 1, ErrAuditTampered
+	case seq > 0 && events[seq-1].Hash != hash:
+		return seq, ErrAuditTampered
+	}
+	return 0, nil
+}
+
+//auditEvents returns the events in the audit log that match cond in order.
+func (cdd *DataAccess) auditEvents(cond map[string]string) ([]AuditEvent, error) {
+	rows, err := cdd.data.Get("audit", cond)
+	if err == ErrClientNotFound {
+		return []AuditEvent{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events := make([]AuditEvent, len(rows))
+	for i, r := range rows {
+		events[i] = AuditEvent{Actor: r["actor"], Action: r["action"], Target: r["target"], Source: r["source"], Detail: r["detail"], Prev: r["prev"], Hash: r["hash"]}
+		events[i].Seq, _ = strconv.Atoi(r["seq"])
+		events[i].Time, _ = time.Parse(TimeLayout, r["time"])
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events, nil
+}
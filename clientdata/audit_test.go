@@ -0,0 +1,80 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	store := filedata.NewMemData()
+	admin := clientdata.NewDataAccess("Admin", store, false)
+	other := clientdata.NewDataAccess("Mod", store, false)
+	_, _ = admin.Audit("ban", "Spam", "10.0.0.1:4000", "7d")
+	_, _ = other.Audit("slow", "Lobby", "10.0.0.2:4000", "30s")
+	last, err := admin.Audit("unban", "Spam", "10.0.0.1:4000", "")
+	if err != nil {
+		t.Fatal("Error adding audit event: ", err)
+	}
+	if last.Seq != 3 || last.Prev == "" || last.Hash == "" {
+		t.Errorf("Expected the event to be chained to the ones before it, got: %+v", last)
+	}
+	all, err := admin.AuditLog(clientdata.AuditQuery{})
+	if err != nil || len(all) != 3 || all[0].Action != "unban" || all[2].Action != "ban" {
+		t.Fatalf("Expected every event newest first, got: %v %v", all, err)
+	}
+	var tests = []struct {
+		q     clientdata.AuditQuery
+		count int
+	}{
+		{clientdata.AuditQuery{Actor: "Admin"}, 2},
+		{clientdata.AuditQuery{Target: "Lobby"}, 1},
+		{clientdata.AuditQuery{Action: "ban", Target: "Spam"}, 1},
+		{clientdata.AuditQuery{Limit: 2}, 2},
+		{clientdata.AuditQuery{Since: all[0].Time.Add(1)}, 0},
+	}
+	for _, tt := range tests {
+		if list, _ := admin.AuditLog(tt.q); len(list) != tt.count {
+			t.Errorf("AuditLog(%+v) returned %v events, want %v", tt.q, len(list), tt.count)
+		}
+	}
+	if bad, err := admin.VerifyAudit(); err != nil {
+		t.Fatalf("Expected the audit log to verify, got %v at %v", err, bad)
+	}
+	_ = store.Set("audit", map[string]string{"detail": "1d"}, map[string]string{"name": clientdata.ServerRecord, "seq": "1"})
+	if bad, err := admin.VerifyAudit(); err != clientdata.ErrAuditTampered || bad != 1 {
+		t.Errorf("Expected a changed event to break the chain at 1, got %v at %v", err, bad)
+	}
+}
+
+func TestAuditLogTruncated(t *testing.T) {
+	store := filedata.NewMemData()
+	admin := clientdata.NewDataAccess("Admin", store, false)
+	_, _ = admin.Audit("ban", "Spam", "10.0.0.1:4000", "7d")
+	_, _ = admin.Audit("unban", "Spam", "10.0.0.1:4000", "")
+	_ = store.Delete("audit", map[string]string{"name": clientdata.ServerRecord, "seq": "2"})
+	if bad, err := admin.VerifyAudit(); err != clientdata.ErrAuditTampered || bad != 2 {
+		t.Errorf("Expected a removed last event to be found at 2, got %v at %v", err, bad)
+	}
+	next, err := admin.Audit("ban", "Troll", "10.0.0.1:4000", "")
+	if err != nil || next.Seq != 3 {
+		t.Errorf("Expected the next event to follow the saved head, got: %+v %v", next, err)
+	}
+}
+
+func TestBackfillAuditHead(t *testing.T) {
+	store := filedata.NewMemData()
+	admin := clientdata.NewDataAccess("Admin", store, false)
+	first, _ := admin.Audit("ban", "Spam", "10.0.0.1:4000", "7d")
+	_ = store.Delete("audithead", map[string]string{"name": clientdata.ServerRecord})
+	if err := admin.BackfillAuditHead(); err != nil {
+		t.Fatal("Error backfilling audit head: ", err)
+	}
+	if bad, err := admin.VerifyAudit(); err != nil {
+		t.Errorf("Expected the backfilled log to verify, got %v at %v", err, bad)
+	}
+	next, err := admin.Audit("unban", "Spam", "10.0.0.1:4000", "")
+	if err != nil || next.Seq != 2 || next.Prev != first.Hash {
+		t.Errorf("Expected the next event to be chained to the first, got: %+v %v", next, err)
+	}
+}
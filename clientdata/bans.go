@@ -0,0 +1,213 @@
+package clientdata
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+//ServerRecord is the name that server wide rows such as bans are stored under.  It is not a valid client name so it can't collide with an account.
+const ServerRecord = "@server"
+
+//TimeLayout is the layout used to store times in the DataStore.
+const TimeLayout = time.RFC3339Nano
+
+const (
+	BanAccount = "account"
+	BanAddress = "address"
+)
+
+var ErrNotBanned = errors.New("clientdata: That ban was not found.")
+var ErrInvalidBan = errors.New("clientdata: Invalid ban target.")
+
+//Ban is a server wide ban on an account or an address.  Address bans may be a single IP or a CIDR range.  A zero Expires means the ban is permanent.
+type Ban struct {
+	Kind    string
+	Target  string
+	Reason  string
+	By      string
+	Created time.Time
+	Expires time.Time
+}
+
+//Permanent returns true if the ban never expires.
+func (b *Ban) Permanent() bool {
+	return b.Expires.IsZero()
+}
+
+//Expired returns true if the ban has expired at time t.
+func (b *Ban) Expired(t time.Time) bool {
+	return !b.Permanent() && !t.Before(b.Expires)
+}
+
+//String formats the ban for display to users.
+func (b *Ban) String() string {
+	until := "permanently"
+	if !b.Permanent() {
+		until = "until " + b.Expires.Format("Jan 2 2006 3:04pm")
+	}
+	s := fmt.Sprintf("%v %v banned %v by %v", b.Kind, b.Target, until, b.By)
+	if b.Reason != "" {
+		s += ": " + b.Reason
+	}
+	return s
+}
+
+//matches returns true if the ban applies to the account name or the remote address.
+func (b *Ban) matches(name, addr string) bool {
+	switch b.Kind {
+	case BanAccount:
+		return name != "" && strings.EqualFold(b.Target, name)
+	case BanAddress:
+		ip := net.ParseIP(hostOnly(addr))
+		if ip == nil {
+			return false
+		}
+		if _, network, err := net.ParseCIDR(b.Target); err == nil {
+			return network.Contains(ip)
+		}
+		target := net.ParseIP(b.Target)
+		return target != nil && target.Equal(ip)
+	}
+	return false
+}
+
+//hostOnly strips the port from a host:port address if one is present.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.Trim(addr, "[]")
+}
+
+//ValidateBanTarget returns true if target is a valid account name or address/CIDR for the kind of ban.
+func ValidateBanTarget(kind, target string) bool {
+	switch kind {
+	case BanAccount:
+		return target != "" && ValidateName(target)
+	case BanAddress:
+		if _, _, err := net.ParseCIDR(target); err == nil {
+			return true
+		}
+		return net.ParseIP(target) != nil
+	}
+	return false
+}
+
+//storedTarget returns target as it is stored in an existing ban.  Logins are case-insensitive so an account ban on "spammer" is the same ban as one on "Spammer".
+func (cdd *DataAccess) storedTarget(kind, target string) (string, error) {
+	if kind != BanAccount {
+		return target, nil
+	}
+	rows, err := cdd.data.Get("bans", row("name", ServerRecord, "kind", kind), "target")
+	if err == ErrClientNotFound {
+		return target, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	for i := range rows {
+		if strings.EqualFold(rows[i]["target"], target) {
+			return rows[i]["target"], nil
+		}
+	}
+	return target, nil
+}
+
+//Ban adds a ban on target that lasts until expires.  A zero expires makes the ban permanent.  Any existing ban on the same target is replaced.
+func (cdd *DataAccess) Ban(kind, target, reason string, expires time.Time) error {
+	if !ValidateBanTarget(kind, target) {
+		return ErrInvalidBan
+	}
+	old, err := cdd.storedTarget(kind, target)
+	if err != nil {
+		return err
+	}
+	err = cdd.data.Delete("bans", row("name", ServerRecord, "kind", kind, "target", old))
+	if err != nil && err != ErrClientNotFound {
+		return err
+	}
+	exp := ""
+	if !expires.IsZero() {
+		exp = expires.Format(TimeLayout)
+	}
+	return cdd.data.Add("bans", row("name", ServerRecord, "kind", kind, "target", target, "reason", reason, "bannedby", cdd.name, "created", time.Now().Format(TimeLayout), "expires", exp))
+}
+
+//Unban lifts the ban on target.  It will return ErrNotBanned if there is no such ban.
+func (cdd *DataAccess) Unban(kind, target string) error {
+	target, err := cdd.storedTarget(kind, target)
+	if err != nil {
+		return err
+	}
+	exists, err := cdd.data.Exists("bans", row("name", ServerRecord, "kind", kind, "target", target))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotBanned
+	}
+	return cdd.data.Delete("bans", row("name", ServerRecord, "kind", kind, "target", target))
+}
+
+//BanList returns the bans that are currently in effect sorted by kind and target.  Expired bans are removed.
+func (cdd *DataAccess) BanList() ([]Ban, error) {
+	rows, err := cdd.data.Get("bans", row("name", ServerRecord))
+	if err == ErrClientNotFound {
+		return []Ban{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := make([]Ban, 0, len(rows))
+	for i := range rows {
+		b := Ban{Kind: rows[i]["kind"], Target: rows[i]["target"], Reason: rows[i]["reason"], By: rows[i]["bannedby"]}
+		b.Created, _ = time.Parse(TimeLayout, rows[i]["created"])
+		if rows[i]["expires"] != "" {
+			b.Expires, err = time.Parse(TimeLayout, rows[i]["expires"])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if b.Expired(now) {
+			err = cdd.data.Delete("bans", row("name", ServerRecord, "kind", b.Kind, "target", b.Target))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		list = append(list, b)
+	}
+	sort.Sort(byTarget(list))
+	return list, nil
+}
+
+//CheckBan returns the ban in effect for the account name or the remote address.  It returns nil if neither is banned.  Either may be empty to skip that check.
+func (cdd *DataAccess) CheckBan(name, addr string) (*Ban, error) {
+	list, err := cdd.BanList()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].matches(name, addr) {
+			return &list[i], nil
+		}
+	}
+	return nil, nil
+}
+
+//byTarget sorts bans by kind and then target.
+type byTarget []Ban
+
+func (b byTarget) Len() int      { return len(b) }
+func (b byTarget) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byTarget) Less(i, j int) bool {
+	if b[i].Kind != b[j].Kind {
+		return b[i].Kind < b[j].Kind
+	}
+	return b[i].Target < b[j].Target
+}
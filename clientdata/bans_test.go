@@ -0,0 +1,28 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+	"time"
+)
+
+func TestAccountBanIgnoresCase(t *testing.T) {
+	admin := filedata.NewMemDataFactory().Create("Admin")
+	if err := admin.Ban(clientdata.BanAccount, "spammer", "links", time.Time{}); err != nil {
+		t.Fatal("Error banning: ", err)
+	}
+	if b, err := admin.CheckBan("Spammer", ""); err != nil || b == nil {
+		t.Errorf("Expected the ban to match Spammer, got: %v %v", b, err)
+	}
+	_ = admin.Ban(clientdata.BanAccount, "SPAMMER", "more links", time.Time{})
+	if list, _ := admin.BanList(); len(list) != 1 || list[0].Reason != "more links" {
+		t.Errorf("Expected the ban to be replaced, got: %v", list)
+	}
+	if err := admin.Unban(clientdata.BanAccount, "Spammer"); err != nil {
+		t.Error("Error lifting ban: ", err)
+	}
+	if b, _ := admin.CheckBan("spammer", ""); b != nil {
+		t.Error("Expected the ban to be lifted, got: ", b)
+	}
+}
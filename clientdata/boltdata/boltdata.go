@@ -0,0 +1,351 @@
+package boltdata
+
+//A bbolt implementation of datastore for use with clientdata.  Each table is a bucket of rows keyed by a sequence number with index buckets so lookups don't scan the whole table.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/DavidAFox/Chat/clientdata"
+	bolt "go.etcd.io/bbolt"
+	"strings"
+	"time"
+)
+
+//DEFAULTFILENAME is the name of the database file used if one is not provided.
+var DEFAULTFILENAME = "ClientData.bolt"
+
+//indexes lists the columns each table is indexed on besides name, in the order they're used in the index.  A lookup uses the index if it has a value for each of the columns.
+var indexes = map[string][][]string{
+	"blocked": {{"name", "blocked"}},
+	"friends": {{"name", "friend"}},
+}
+
+//nameIndex is the index every table has since almost every lookup is by name.
+var nameIndex = []string{"name"}
+
+//rowsBucket is the bucket in each table's bucket that holds its rows.
+var rowsBucket = []byte("rows")
+
+//NewFactory creates a new clientdata factory using a bbolt datastore.
+func NewFactory(fileName string) (*Factory, error) {
+	cdf := new(Factory)
+	database, err := NewBoltData(fileName)
+	cdf.database = database
+	return cdf, err
+}
+
+//Factory is used to make client data objects backed by a bbolt database.
+type Factory struct {
+	database clientdata.DataStore
+}
+
+//Create returns a ClientData object of the type for the factory.
+func (cdf *Factory) Create(name string) clientdata.ClientData {
+	return clientdata.NewDataAccess(name, cdf.database, false)
+}
+
+//BoltData is a type of datastore using an embedded bbolt database file.  Rows are stored as JSON.
+type BoltData struct {
+	data *bolt.DB
+}
+
+//NewBoltData opens the database in fileName, creating it if it doesn't exist, and returns a BoltData datastore.
+func NewBoltData(fileName string) (*BoltData, error) {
+	if fileName == "" {
+		fileName = DEFAULTFILENAME
+	}
+	database, err := bolt.Open(fileName, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err = database.Update(buildIndexes); err != nil {
+		database.Close()
+		return nil, err
+	}
+	return &BoltData{data: database}, nil
+}
+
+//buildIndexes creates the index buckets tables are missing, as when an index is added to indexes, and indexes the rows already in them.
+func buildIndexes(tx *bolt.Tx) error {
+	names := make([]string, 0)
+	err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		names = append(names, string(name))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tb := tx.Bucket([]byte(name))
+		rows := tb.Bucket(rowsBucket)
+		for _, columns := range tableIndexes(name) {
+			if tb.Bucket(indexName(columns)) != nil || rows == nil {
+				continue
+			}
+			idx, err := tb.CreateBucket(indexName(columns))
+			if err != nil {
+				return err
+			}
+			err = rows.ForEach(func(id, v []byte) error {
+				values := make(map[string]string)
+				if err := json.Unmarshal(v, &values); err != nil {
+					return err
+				}
+				return idx.Put(indexKey(columns, values, id), []byte{})
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Close closes the database.
+func (b *BoltData) Close() error {
+	return b.data.Close()
+}
+
+//tableIndexes returns the indexes for table with the most specific first.
+func tableIndexes(table string) [][]string {
+	list := make([][]string, 0, len(indexes[table])+1)
+	list = append(list, indexes[table]...)
+	return append(list, nameIndex)
+}
+
+//indexName returns the name of the bucket for the index on columns.
+func indexName(columns []string) []byte {
+	return []byte("index:" + strings.Join(columns, ","))
+}
+
+//indexPrefix returns the start of the index keys for rows with values in the index's columns.  It returns false if values doesn't have all of them.
+func indexPrefix(columns []string, values map[string]string) ([]byte, bool) {
+	var key bytes.Buffer
+	for _, col := range columns {
+		v, ok := values[col]
+		if !ok {
+			return nil, false
+		}
+		key.WriteString(v)
+		key.WriteByte(0)
+	}
+	return key.Bytes(), true
+}
+
+//indexKey returns the key for the row id with values in the index on columns.  Missing columns are indexed as empty.
+func indexKey(columns []string, values map[string]string, id []byte) []byte {
+	var key bytes.Buffer
+	for _, col := range columns {
+		key.WriteString(values[col])
+		key.WriteByte(0)
+	}
+	key.Write(id)
+	return key.Bytes()
+}
+
+//matchRow returns true if row has every value in cond.
+func matchRow(row, cond map[string]string) bool {
+	for k, v := range cond {
+		if d, ok := row[k]; !ok || d != v {
+			return false
+		}
+	}
+	return true
+}
+
+//row is a stored row and its id.
+type row struct {
+	id     []byte
+	values map[string]string
+}
+
+//find returns the rows of table in tb matching cond.  It uses the most specific index cond has values for.
+func find(tb *bolt.Bucket, table string, cond map[string]string) ([]row, error) {
+	rows := tb.Bucket(rowsBucket)
+	found := make([]row, 0)
+	check := func(id, v []byte) error {
+		values := make(map[string]string)
+		if err := json.Unmarshal(v, &values); err != nil {
+			return err
+		}
+		if matchRow(values, cond) {
+			found = append(found, row{id: append([]byte(nil), id...), values: values})
+		}
+		return nil
+	}
+	for _, columns := range tableIndexes(table) {
+		prefix, ok := indexPrefix(columns, cond)
+		if !ok {
+			continue
+		}
+		c := tb.Bucket(indexName(columns)).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			id := k[len(k)-8:]
+			if v := rows.Get(id); v != nil {
+				if err := check(id, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return found, nil
+	}
+	err := rows.ForEach(check)
+	return found, err
+}
+
+//table returns the bucket for table creating it and its index buckets if needed.
+func table(tx *bolt.Tx, name string) (*bolt.Bucket, error) {
+	tb, err := tx.CreateBucketIfNotExists([]byte(name))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = tb.CreateBucketIfNotExists(rowsBucket); err != nil {
+		return nil, err
+	}
+	for _, columns := range tableIndexes(name) {
+		if _, err = tb.CreateBucketIfNotExists(indexName(columns)); err != nil {
+			return nil, err
+		}
+	}
+	return tb, nil
+}
+
+//put saves values as row id in tb and adds it to the table's indexes.
+func put(tb *bolt.Bucket, name string, id []byte, values map[string]string) error {
+	v, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	if err = tb.Bucket(rowsBucket).Put(id, v); err != nil {
+		return err
+	}
+	for _, columns := range tableIndexes(name) {
+		if err = tb.Bucket(indexName(columns)).Put(indexKey(columns, values, id), []byte{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//remove deletes r from tb and the table's indexes.
+func remove(tb *bolt.Bucket, name string, r row) error {
+	if err := tb.Bucket(rowsBucket).Delete(r.id); err != nil {
+		return err
+	}
+	for _, columns := range tableIndexes(name) {
+		if err := tb.Bucket(indexName(columns)).Delete(indexKey(columns, r.values, r.id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Add adds row values to table.
+func (b *BoltData) Add(name string, values map[string]string) error {
+	return b.data.Update(func(tx *bolt.Tx) error {
+		tb, err := table(tx, name)
+		if err != nil {
+			return err
+		}
+		seq, err := tb.NextSequence()
+		if err != nil {
+			return err
+		}
+		id := make([]byte, 8)
+		binary.BigEndian.PutUint64(id, seq)
+		return put(tb, name, id, values)
+	})
+}
+
+//Delete removes rows matching values from table.
+func (b *BoltData) Delete(name string, values map[string]string) error {
+	return b.data.Update(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(name))
+		if tb == nil {
+			return nil
+		}
+		rows, err := find(tb, name, values)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err = remove(tb, name, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//Get gets the columns from the table for the rows matching values.  All the columns are returned if none are given.
+func (b *BoltData) Get(name string, values map[string]string, columns ...string) ([]map[string]string, error) {
+	m := make([]map[string]string, 0)
+	err := b.data.View(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(name))
+		if tb == nil {
+			return nil
+		}
+		rows, err := find(tb, name, values)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if len(columns) == 0 {
+				m = append(m, r.values)
+				continue
+			}
+			nmap := make(map[string]string, len(columns))
+			for _, col := range columns {
+				nmap[col] = r.values[col]
+			}
+			m = append(m, nmap)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//Set finds the rows matching cond in table and sets the column/value pairs in values.
+func (b *BoltData) Set(name string, values, cond map[string]string) error {
+	return b.data.Update(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(name))
+		if tb == nil {
+			return nil
+		}
+		rows, err := find(tb, name, cond)
+		if err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if err = remove(tb, name, r); err != nil {
+				return err
+			}
+			for k, v := range values {
+				r.values[k] = v
+			}
+			if err = put(tb, name, r.id, r.values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//Exists takes a table to check and a map representing a row to compare to and returns true if there is a match in the database.
+func (b *BoltData) Exists(name string, values map[string]string) (bool, error) {
+	found := false
+	err := b.data.View(func(tx *bolt.Tx) error {
+		tb := tx.Bucket([]byte(name))
+		if tb == nil {
+			return nil
+		}
+		rows, err := find(tb, name, values)
+		found = len(rows) > 0
+		return err
+	})
+	return found, err
+}
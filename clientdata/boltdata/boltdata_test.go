@@ -0,0 +1,96 @@
+package boltdata
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.bolt")
+	b, err := NewBoltData(file)
+	if err != nil {
+		t.Fatal("Error opening database: ", err)
+	}
+	if rows, err := b.Get("blocked", map[string]string{"name": "Fred"}); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no rows from a table that doesn't exist yet, got: %v %v", rows, err)
+	}
+	_ = b.Add("blocked", map[string]string{"name": "Fred", "blocked": "Bob"})
+	_ = b.Add("blocked", map[string]string{"name": "Fred", "blocked": "Sue", "since": "today"})
+	_ = b.Add("blocked", map[string]string{"name": "Fredrick", "blocked": "Bob"})
+	_ = b.Add("mailbox", map[string]string{"name": "Fred", "from": "Bob", "text": "hi"})
+	if rows, _ := b.Get("blocked", map[string]string{"name": "Fred"}); len(rows) != 2 {
+		t.Errorf("Expected Fred's 2 rows from the name index, got: %v", rows)
+	}
+	if rows, _ := b.Get("blocked", map[string]string{"name": "Fred", "blocked": "Sue"}, "since"); len(rows) != 1 || rows[0]["since"] != "today" || len(rows[0]) != 1 {
+		t.Errorf("Expected only the since column of Sue's row, got: %v", rows)
+	}
+	if rows, _ := b.Get("blocked", map[string]string{"blocked": "Bob"}); len(rows) != 2 {
+		t.Errorf("Expected a scan to find both rows blocking Bob, got: %v", rows)
+	}
+	if err = b.Set("blocked", map[string]string{"blocked": "Ann"}, map[string]string{"name": "Fred", "blocked": "Bob"}); err != nil {
+		t.Fatal("Error setting indexed column: ", err)
+	}
+	if found, _ := b.Exists("blocked", map[string]string{"name": "Fred", "blocked": "Bob"}); found {
+		t.Error("Expected the old index entry to be removed")
+	}
+	if found, _ := b.Exists("blocked", map[string]string{"name": "Fred", "blocked": "Ann"}); !found {
+		t.Error("Expected the row to be found by its new value")
+	}
+	if err = b.Delete("blocked", map[string]string{"name": "Fred", "blocked": "Ann"}); err != nil {
+		t.Fatal("Error deleting: ", err)
+	}
+	_ = b.Close()
+	indexes["mailbox"] = [][]string{{"name", "from"}}
+	defer delete(indexes, "mailbox")
+	b, err = NewBoltData(file)
+	if err != nil {
+		t.Fatal("Error reopening database: ", err)
+	}
+	defer b.Close()
+	if rows, _ := b.Get("blocked", map[string]string{"name": "Fred"}); len(rows) != 1 || rows[0]["blocked"] != "Sue" {
+		t.Errorf("Expected Sue's row to be kept, got: %v", rows)
+	}
+	if rows, _ := b.Get("mailbox", map[string]string{"name": "Fred", "from": "Bob"}); len(rows) != 1 || rows[0]["text"] != "hi" {
+		t.Errorf("Expected rows from before an index was added to be indexed, got: %v", rows)
+	}
+}
+
+func TestClientData(t *testing.T) {
+	s, err := NewBoltData(filepath.Join(t.TempDir(), "test.bolt"))
+	if err != nil {
+		t.Fatal("Error opening database: ", err)
+	}
+	defer s.Close()
+	fred := clientdata.NewDataAccess("Fred", s, false)
+	if err = fred.NewClient("FredsPassword"); err != nil {
+		t.Fatal("Error creating client: ", err)
+	}
+	_ = clientdata.NewDataAccess("Bob", s, false).NewClient("BobsPassword")
+	if err = clientdata.NewDataAccess("fred", s, false).NewClient("pass"); err != clientdata.ErrClientExists {
+		t.Error("Expected the name to be taken, got: ", err)
+	}
+	if ok, err := fred.Authenticate("FredsPassword"); !ok || err != nil {
+		t.Errorf("Expected Fred to authenticate, got: %v %v", ok, err)
+	}
+	c, err := fred.Conversation([]string{"Bob"})
+	if err != nil {
+		t.Fatal("Error starting conversation: ", err)
+	}
+	if _, err = fred.AddDirectMessage(c.ID, "hi"); err != nil {
+		t.Fatal("Error sending direct message: ", err)
+	}
+	bob := clientdata.NewDataAccess("Bob", s, false)
+	if history, _ := bob.DirectMessages(c.ID, 0, 0); len(history) != 1 || history[0].Text != "hi" {
+		t.Errorf("Expected Bob to see the message, got: %v", history)
+	}
+	if err = bob.Block("Fred"); err != nil {
+		t.Fatal("Error blocking: ", err)
+	}
+	if blocked, _ := bob.IsBlocked("Fred"); !blocked {
+		t.Error("Expected Fred to be blocked")
+	}
+	if _, err = fred.AddDirectMessage(c.ID, "bye"); err != clientdata.ErrConversationBlocked {
+		t.Error("Expected the conversation to be blocked, got: ", err)
+	}
+}
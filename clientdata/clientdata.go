@@ -31,9 +31,68 @@ type ClientData interface {
 	Unfriend(name string) error
 	FriendList() ([]string, error)
 	SetName(name string)
+	IsAdmin() (bool, error)
+	AddAdmin(name string) error
+	SetAdmins(names []string) error
+	Ban(kind, target, reason string, expires time.Time) error
+	Unban(kind, target string) error
+	BanList() ([]Ban, error)
+	CheckBan(name, addr string) (*Ban, error)
+	EnrollTOTP() (*Enrollment, error)
+	ConfirmTOTP(code string) error
+	TOTPEnabled() (bool, error)
+	VerifyTOTP(code string) (bool, error)
+	DisableTOTP(pword string) error
+	CreateAPIKey(name string, rooms, commands []string) (string, *APIKey, error)
+	APIKeys(name string) ([]APIKey, error)
+	RevokeAPIKey(name, id string) error
+	AuthenticateAPIKey(key string) (*APIKey, error)
+	CreateBot(name string) error
+	BotOwner(name string) (string, error)
+	Bots() ([]string, error)
+	AddWebhook(room, url string) (*Webhook, error)
+	Webhooks(room string) ([]Webhook, error)
+	RemoveWebhook(id string) error
+	AddIncomingWebhook(room, username string) (string, *IncomingWebhook, error)
+	IncomingWebhooks(room string) ([]IncomingWebhook, error)
+	RemoveIncomingWebhook(id string) error
+	AuthenticateIncomingWebhook(token string) (*IncomingWebhook, error)
+	Schedule(kind, target, text string, at time.Time) (*ScheduledItem, error)
+	ScheduledItems(owner string) ([]ScheduledItem, error)
+	Unschedule(id string) error
+	SendMail(to, text string) error
+	TakeMail() ([]Mail, error)
+	DisplayName() (string, error)
+	SetDisplayName(name string) error
+	Presence() (state, status string, err error)
+	SetPresence(state, status string) error
+	RequestFriend(name string) (bool, error)
+	FriendRequests() (incoming, outgoing []FriendRequest, err error)
+	AcceptFriend(name string) error
+	DeclineFriend(name string) error
+	MarkRead(room, epoch string, seq int) error
+	ReadPositions() (map[string]ReadPosition, error)
+	Conversation(members []string) (*Conversation, error)
+	ConversationByID(id string) (*Conversation, error)
+	Conversations() ([]Conversation, error)
+	AddDirectMessage(id, text string) (*DirectMessage, error)
+	DirectMessages(id string, before, limit int) ([]DirectMessage, error)
+	AddAttachment(id, room, filename, mime string, size int64) (*Attachment, error)
+	Attachment(id string) (*Attachment, error)
+	AddReport(name, room string, seq int, msg string, context []string, reason string) (*Report, error)
+	FlagMessage(name, room, text, reason string) (*Report, error)
+	Report(id string) (*Report, error)
+	Reports(open bool) ([]Report, error)
+	ResolveReport(id, action, note string) (*Report, error)
+	Shadowban(name, reason string) error
+	Unshadowban(name string) error
+	Shadowbans() ([]Shadowban, error)
+	Audit(action, target, source, detail string) (*AuditEvent, error)
+	AuditLog(q AuditQuery) ([]AuditEvent, error)
+	VerifyAudit() (int, error)
 }
 
-//DataStore is the interface used by DataAccess to access stored data.
+//DataStore is the interface used by DataAccess to access stored data.  Rows are kept by their name column.  Get without a name in values returns the matching rows for every name.
 type DataStore interface {
 	Add(table string, values map[string]string) error
 	Delete(table string, values map[string]string) error
@@ -76,10 +135,10 @@ type DataAccess struct {
 	disableNewAccounts bool
 }
 
-//NewDataAccess creates a new DataAccess.  Names must be alphanumeric only.
+//NewDataAccess creates a new DataAccess.  Names must be alphanumeric only or ServerRecord for server owned rows.
 func NewDataAccess(name string, data DataStore, disableNewAccounts bool) *DataAccess {
 	cdd := new(DataAccess)
-	if ValidateName(name) {
+	if ValidateName(name) || name == ServerRecord {
 		cdd.name = name
 	}
 	cdd.data = data
@@ -103,6 +162,9 @@ func (cdd *DataAccess) Authenticate(pword string) (bool, error) {
 	err = bcrypt.CompareHashAndPassword([]byte(res[0]["password"]), []byte(pword))
 	switch {
 	case err == nil:
+		if err = cdd.registerLogin(cdd.name); err != nil {
+			log.Println("Error registering login name: ", err)
+		}
 		return true, nil
 	case err == bcrypt.ErrMismatchedHashAndPassword:
 		return false, nil
@@ -142,7 +204,7 @@ func (cdd *DataAccess) LastOnline(name string) (time.Time, error) {
 	return t, err
 }
 
-//NewClient adds the client to the database with the provided password.
+//NewClient adds the client to the database with the provided password.  Names are unique ignoring case so it will return ErrClientExists if the name only differs in case from another client's or looks like someone's display name.
 func (cdd *DataAccess) NewClient(pword string) error {
 	if cdd.disableNewAccounts {
 		return ErrAccountCreationDisabled
@@ -157,9 +219,19 @@ func (cdd *DataAccess) NewClient(pword string) error {
 	if !ValidateName(cdd.name) {
 		return ErrInvalidName
 	}
+	taken, err := cdd.loginTaken(cdd.name)
+	switch {
+	case err != nil:
+		return err
+	case taken:
+		return ErrClientExists
+	}
 	hashpword := Encrypt(pword)
 	err = cdd.data.Add("client", row("password", hashpword, "name", cdd.name, "lastonline", time.Now().String()))
-	return err
+	if err != nil {
+		return err
+	}
+	return cdd.registerLogin(cdd.name)
 }
 
 //BlockList returns a list of names that the client is blocking.
@@ -245,7 +317,7 @@ func (cdd *DataAccess) FriendList() ([]string, error) {
 	return list, nil
 }
 
-//Unfriend removes name from the friend list.
+//Unfriend removes name from the friend list and the client from name's.  If they aren't friends it cancels the client's request to be name's friend.
 func (cdd *DataAccess) Unfriend(name string) error {
 	if !ValidateName(name) {
 		return ErrInvalidName
@@ -255,14 +327,75 @@ func (cdd *DataAccess) Unfriend(name string) error {
 		return err
 	}
 	if !friend {
+		if err = cdd.removeFriendRequest(cdd.name, name); err != ErrNoFriendRequest {
+			return err
+		}
 		return ErrNotFriend
 	}
-	return cdd.data.Delete("friends", row("friend", name, "name", cdd.name))
+	err = cdd.data.Delete("friends", row("friend", name, "name", cdd.name))
+	if err != nil {
+		return err
+	}
+	err = cdd.data.Delete("friends", row("friend", cdd.name, "name", name))
+	if err == ErrClientNotFound {
+		return nil
+	}
+	return err
+}
+
+//IsAdmin returns true if the client is a server administrator.
+func (cdd *DataAccess) IsAdmin() (bool, error) {
+	if cdd.name == "" {
+		return false, nil
+	}
+	return cdd.data.Exists("admins", row("name", cdd.name))
+}
+
+//AddAdmin makes name a server administrator.
+func (cdd *DataAccess) AddAdmin(name string) error {
+	if !ValidateName(name) {
+		return ErrInvalidName
+	}
+	exists, err := cdd.data.Exists("admins", row("name", name))
+	if err != nil || exists {
+		return err
+	}
+	return cdd.data.Add("admins", row("name", name))
+}
+
+//SetAdmins makes names the only server administrators.  Anyone else who was an administrator no longer is.  Invalid names are skipped and ErrInvalidName is returned after the rest are set.
+func (cdd *DataAccess) SetAdmins(names []string) error {
+	var invalid error
+	keep := make(map[string]bool)
+	for _, name := range names {
+		err := cdd.AddAdmin(name)
+		if err == ErrInvalidName {
+			invalid = err
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		keep[name] = true
+	}
+	rows, err := cdd.data.Get("admins", row(), "name")
+	if err != nil && err != ErrClientNotFound {
+		return err
+	}
+	for _, r := range rows {
+		if keep[r["name"]] {
+			continue
+		}
+		if err = cdd.data.Delete("admins", row("name", r["name"])); err != nil {
+			return err
+		}
+	}
+	return invalid
 }
 
-//SetName changes the name associated with this DataAccess object.  Name must be alphanumeric only.
+//SetName changes the name associated with this DataAccess object.  Name must be alphanumeric only or ServerRecord.
 func (cdd *DataAccess) SetName(name string) {
-	if ValidateName(name) {
+	if ValidateName(name) || name == ServerRecord {
 		cdd.name = name
 	}
 }
@@ -0,0 +1,32 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestSetAdmins(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	for _, name := range []string{"Fred", "Bob", "Sue"} {
+		_ = df.Create(name).NewClient("password")
+	}
+	_ = df.Create("").AddAdmin("Fred")
+	_ = df.Create("").AddAdmin("Bob")
+	if err := df.Create("").SetAdmins([]string{"Bob", "Sue", "not valid"}); err != clientdata.ErrInvalidName {
+		t.Errorf("Expected an invalid name to be reported, got: %v", err)
+	}
+	for name, expected := range map[string]bool{"Fred": false, "Bob": true, "Sue": true} {
+		if admin, err := df.Create(name).IsAdmin(); err != nil || admin != expected {
+			t.Errorf("IsAdmin for %v => %v %v, want %v", name, admin, err, expected)
+		}
+	}
+	if err := df.Create("").SetAdmins(nil); err != nil {
+		t.Fatal("Error setting admins: ", err)
+	}
+	for _, name := range []string{"Fred", "Bob", "Sue"} {
+		if admin, _ := df.Create(name).IsAdmin(); admin {
+			t.Errorf("Expected %v not to be an admin", name)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package clientdata
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//MaxConversationMembers is the most people a direct message conversation can have including the client that started it.
+const MaxConversationMembers = 8
+
+//DefaultHistory is how many messages DirectMessages returns when no limit is given.
+const DefaultHistory = 50
+
+var ErrConversationNotFound = errors.New("clientdata: Conversation not found.")
+var ErrConversationSize = errors.New("clientdata: A conversation needs between 2 and 8 people.")
+var ErrConversationBlocked = errors.New("clientdata: Someone in the conversation is blocking or blocked.")
+
+//conversationLock keeps messages sent to the same conversation at once from getting the same sequence number.
+var conversationLock sync.Mutex
+
+//Conversation is a direct message conversation between two or more clients.  Count is the number of messages sent to it.
+type Conversation struct {
+	ID      string
+	Members []string
+	Count   int
+	Created time.Time
+	Updated time.Time
+}
+
+//Others returns the members other than name.
+func (c *Conversation) Others(name string) []string {
+	others := make([]string, 0, len(c.Members))
+	for _, m := range c.Members {
+		if m != name {
+			others = append(others, m)
+		}
+	}
+	return others
+}
+
+//DirectMessage is a message in a conversation.  Seq is its position in the conversation starting at 1.
+type DirectMessage struct {
+	Conversation string
+	Seq          int
+	From         string
+	Text         string
+	Sent         time.Time
+}
+
+//conversationFromRow turns a conversations row into a Conversation.
+func conversationFromRow(r map[string]string) Conversation {
+	c := Conversation{ID: r["id"], Members: strings.Split(r["members"], ",")}
+	c.Count, _ = strconv.Atoi(r["count"])
+	c.Created, _ = time.Parse(TimeLayout, r["created"])
+	c.Updated, _ = time.Parse(TimeLayout, r["updated"])
+	return c
+}
+
+//blockedBy returns true if name is blocking the client.
+func (cdd *DataAccess) blockedBy(name string) (bool, error) {
+	return cdd.data.Exists("blocked", row("blocked", cdd.name, "name", name))
+}
+
+//Conversation returns the conversation between the client and members, starting it if there isn't one yet.  It will return ErrConversationBlocked if the client is blocking or blocked by any of them.
+func (cdd *DataAccess) Conversation(members []string) (*Conversation, error) {
+	set := map[string]bool{cdd.name: true}
+	for _, m := range members {
+		if !ValidateName(m) || m == "" {
+			return nil, ErrInvalidName
+		}
+		set[m] = true
+	}
+	if len(set) < 2 || len(set) > MaxConversationMembers {
+		return nil, ErrConversationSize
+	}
+	all := make([]string, 0, len(set))
+	for m := range set {
+		if m == cdd.name {
+			all = append(all, m)
+			continue
+		}
+		exists, err := cdd.ClientExists(m)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrClientNotFound
+		}
+		blocking, err := cdd.IsBlocked(m)
+		if err != nil {
+			return nil, err
+		}
+		blocked, err := cdd.blockedBy(m)
+		if err != nil {
+			return nil, err
+		}
+		if blocking || blocked {
+			return nil, ErrConversationBlocked
+		}
+		all = append(all, m)
+	}
+	sort.Strings(all)
+	key := strings.Join(all, ",")
+	conversationLock.Lock()
+	defer conversationLock.Unlock()
+	rows, err := cdd.data.Get("conversations", row("name", ServerRecord, "members", key))
+	if err != nil && err != ErrClientNotFound {
+		return nil, err
+	}
+	if len(rows) > 0 {
+		c := conversationFromRow(rows[0])
+		return &c, nil
+	}
+	idb, err := randomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	c := &Conversation{ID: hex.EncodeToString(idb), Members: all, Created: now, Updated: now}
+	err = cdd.data.Add("conversations", row("name", ServerRecord, "id", c.ID, "members", key, "count", "0", "created", now.Format(TimeLayout), "updated", now.Format(TimeLayout)))
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range all {
+		err = cdd.data.Add("conversationmembers", row("name", ServerRecord, "id", c.ID, "member", m))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+//ConversationByID returns the conversation id.  It will return ErrConversationNotFound if there isn't one or the client isn't in it.
+func (cdd *DataAccess) ConversationByID(id string) (*Conversation, error) {
+	member, err := cdd.data.Exists("conversationmembers", row("name", ServerRecord, "id", id, "member", cdd.name))
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrConversationNotFound
+	}
+	rows, err := cdd.data.Get("conversations", row("name", ServerRecord, "id", id))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := conversationFromRow(rows[0])
+	return &c, nil
+}
+
+//Conversations returns the conversations the client is in with the most recently used first.
+func (cdd *DataAccess) Conversations() ([]Conversation, error) {
+	rows, err := cdd.data.Get("conversationmembers", row("name", ServerRecord, "member", cdd.name), "id")
+	if err == ErrClientNotFound {
+		return []Conversation{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Conversation, 0, len(rows))
+	for i := range rows {
+		c, err := cdd.ConversationByID(rows[i]["id"])
+		if err == ErrConversationNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Updated.After(list[j].Updated) })
+	return list, nil
+}
+
+//AddDirectMessage saves text from the client to conversation id.  In a conversation between two people it will return ErrConversationBlocked if the other one is blocking the client.
+func (cdd *DataAccess) AddDirectMessage(id, text string) (*DirectMessage, error) {
+	conversationLock.Lock()
+	defer conversationLock.Unlock()
+	c, err := cdd.ConversationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if others := c.Others(cdd.name); len(others) == 1 {
+		blocked, err := cdd.blockedBy(others[0])
+		if err != nil {
+			return nil, err
+		}
+		if blocked {
+			return nil, ErrConversationBlocked
+		}
+	}
+	dm := &DirectMessage{Conversation: id, Seq: c.Count + 1, From: cdd.name, Text: text, Sent: time.Now()}
+	err = cdd.data.Add("directmessages", row("name", ServerRecord, "conversation", id, "seq", strconv.Itoa(dm.Seq), "from", dm.From, "text", text, "sent", dm.Sent.Format(TimeLayout)))
+	if err != nil {
+		return nil, err
+	}
+	err = cdd.data.Set("conversations", row("count", strconv.Itoa(dm.Seq), "updated", dm.Sent.Format(TimeLayout)), row("name", ServerRecord, "id", id))
+	if err != nil {
+		return nil, err
+	}
+	return dm, nil
+}
+
+//DirectMessages returns up to limit messages from conversation id oldest first.  Only messages before the sequence number before are returned unless it is 0 which returns the newest.  Messages from clients this client is blocking are left out.
+func (cdd *DataAccess) DirectMessages(id string, before, limit int) ([]DirectMessage, error) {
+	if _, err := cdd.ConversationByID(id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultHistory
+	}
+	rows, err := cdd.data.Get("directmessages", row("name", ServerRecord, "conversation", id))
+	if err == ErrClientNotFound {
+		return []DirectMessage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	blocking := make(map[string]bool)
+	list := make([]DirectMessage, 0, len(rows))
+	for i := range rows {
+		dm := DirectMessage{Conversation: id, From: rows[i]["from"], Text: rows[i]["text"]}
+		dm.Seq, _ = strconv.Atoi(rows[i]["seq"])
+		if before > 0 && dm.Seq >= before {
+			continue
+		}
+		if _, ok := blocking[dm.From]; !ok {
+			blocking[dm.From], err = cdd.IsBlocked(dm.From)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if blocking[dm.From] {
+			continue
+		}
+		dm.Sent, _ = time.Parse(TimeLayout, rows[i]["sent"])
+		list = append(list, dm)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Seq < list[j].Seq })
+	if len(list) > limit {
+		list = list[len(list)-limit:]
+	}
+	return list, nil
+}
@@ -0,0 +1,76 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"strconv"
+	"testing"
+)
+
+func newConversationData(t *testing.T, names ...string) clientdata.Factory {
+	df := filedata.NewMemDataFactory()
+	for _, name := range names {
+		if err := df.Create(name).NewClient("pass"); err != nil {
+			t.Fatal("Error creating client: ", err)
+		}
+	}
+	return df
+}
+
+func TestConversationHistory(t *testing.T) {
+	df := newConversationData(t, "Fred", "Bob", "Sue")
+	fred, bob := df.Create("Fred"), df.Create("Bob")
+	c, err := fred.Conversation([]string{"Bob"})
+	if err != nil {
+		t.Fatal("Error starting conversation: ", err)
+	}
+	if again, _ := bob.Conversation([]string{"Fred"}); again == nil || again.ID != c.ID {
+		t.Error("Expected both people to get the same conversation")
+	}
+	for i := 1; i <= 5; i++ {
+		if _, err := fred.AddDirectMessage(c.ID, strconv.Itoa(i)); err != nil {
+			t.Fatal("Error adding message: ", err)
+		}
+	}
+	page, _ := bob.DirectMessages(c.ID, 0, 2)
+	if len(page) != 2 || page[0].Text != "4" || page[1].Text != "5" {
+		t.Fatalf("Wrong newest page: %v", page)
+	}
+	page, _ = bob.DirectMessages(c.ID, page[0].Seq, 10)
+	if len(page) != 3 || page[0].Text != "1" || page[2].Text != "3" {
+		t.Errorf("Wrong older page: %v", page)
+	}
+	if _, err := df.Create("Sue").DirectMessages(c.ID, 0, 0); err != clientdata.ErrConversationNotFound {
+		t.Error("Expected someone outside the conversation not to see it, got: ", err)
+	}
+	if list, _ := bob.Conversations(); len(list) != 1 || list[0].Count != 5 {
+		t.Errorf("Wrong conversation list: %v", list)
+	}
+}
+
+func TestConversationBlocking(t *testing.T) {
+	df := newConversationData(t, "Fred", "Bob", "Sue")
+	fred, bob := df.Create("Fred"), df.Create("Bob")
+	group, err := fred.Conversation([]string{"Bob", "Sue"})
+	if err != nil {
+		t.Fatal("Error starting group conversation: ", err)
+	}
+	c, _ := fred.Conversation([]string{"Bob"})
+	_ = bob.Block("Fred")
+	if _, err := fred.AddDirectMessage(c.ID, "hi"); err != clientdata.ErrConversationBlocked {
+		t.Error("Expected a message to someone blocking you to fail, got: ", err)
+	}
+	if _, err := fred.Conversation([]string{"Bob", "Sue", "Fred"}); err != clientdata.ErrConversationBlocked {
+		t.Error("Expected starting a conversation with someone blocking you to fail, got: ", err)
+	}
+	_, _ = fred.AddDirectMessage(group.ID, "group hi")
+	if history, _ := bob.DirectMessages(group.ID, 0, 0); len(history) != 0 {
+		t.Errorf("Expected messages from blocked people to be hidden, got: %v", history)
+	}
+	if history, _ := df.Create("Sue").DirectMessages(group.ID, 0, 0); len(history) != 1 {
+		t.Errorf("Expected Sue to see the group message, got: %v", history)
+	}
+	if _, err := fred.Conversation([]string{"Nobody"}); err != clientdata.ErrClientNotFound {
+		t.Error("Expected a conversation with someone who doesn't exist to fail, got: ", err)
+	}
+}
@@ -3,15 +3,37 @@ package datafactory
 import (
 	//Add new data packages here.
 	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/boltdata"
 	"github.com/DavidAFox/Chat/clientdata/filedata"
 	"github.com/DavidAFox/Chat/clientdata/postgres"
+	"github.com/DavidAFox/Chat/clientdata/sqlite"
 )
 
-//NewFactory returns a factory to make client data objects of the type kind and using the database.  Currently supports "postgres" as a kind, using a Postgres database.
+//NewFactory returns a factory to make client data objects of the type kind and using the database.  Currently supports "postgres" as a kind, using a Postgres database, "sqlite", using a SQLite database in the file databaseName, and "bolt", using an embedded bbolt database in the file databaseName.  Any other kind uses a data file.  Clients created before login names were tracked are added to them and the head of an audit log written before it was kept is saved.
 func New(kind, databaseLogin, databasePassword, databaseName, databaseIP, databasePort string, disableNewAccounts bool) (clientdata.Factory, error) {
-	if kind == "postgres" {
+	df, err := open(kind, databaseLogin, databasePassword, databaseName, databaseIP, databasePort, disableNewAccounts)
+	if err != nil {
+		return df, err
+	}
+	data := clientdata.NewDataAccess(clientdata.ServerRecord, df.data, false)
+	if err = data.BackfillLogins(); err != nil {
+		return df, err
+	}
+	return df, data.BackfillAuditHead()
+}
+
+//open returns a factory using the kind of database.
+func open(kind, databaseLogin, databasePassword, databaseName, databaseIP, databasePort string, disableNewAccounts bool) (*DataFactory, error) {
+	switch kind {
+	case "postgres":
 		data, err := postgres.NewPostgres(databaseLogin, databasePassword, databaseName, databaseIP, databasePort)
 		return NewDataFactory(data, disableNewAccounts), err
+	case "sqlite":
+		data, err := sqlite.NewSQLite(databaseName)
+		return NewDataFactory(data, disableNewAccounts), err
+	case "bolt":
+		data, err := boltdata.NewBoltData(databaseName)
+		return NewDataFactory(data, disableNewAccounts), err
 	}
 	return NewDataFactory(filedata.NewFileData(databaseName), disableNewAccounts), nil
 }
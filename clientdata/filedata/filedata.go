@@ -97,12 +97,13 @@ func (fd *fileData) Delete(table string, values map[string]string) error {
 	}
 	fd.RLock()
 	r.Lock()
+	kept := r.Tables[table][:0]
 	for i := range r.Tables[table] {
-		if matchRow(r.Tables[table][i], values) {
-			r.Tables[table] = append(r.Tables[table][:i], r.Tables[table][i+1:]...)
-			i--
+		if !matchRow(r.Tables[table][i], values) {
+			kept = append(kept, r.Tables[table][i])
 		}
 	}
+	r.Tables[table] = kept
 	r.Unlock()
 	fd.RUnlock()
 	return fd.save()
@@ -119,6 +120,9 @@ func copyMap(original map[string]string) map[string]string {
 
 //Get returns slice of maps representing the tables that match the row represented by values.  If columns are provided it will return only those columns in the rows returned.
 func (fd *fileData) Get(table string, values map[string]string, columns ...string) ([]map[string]string, error) {
+	if _, ok := values["name"]; !ok {
+		return fd.getAll(table, values, columns...)
+	}
 	r, found := fd.Records[values["name"]]
 	res := make([]map[string]string, 0)
 	if !found {
@@ -142,6 +146,27 @@ func (fd *fileData) Get(table string, values map[string]string, columns ...strin
 	return res, nil
 }
 
+//getAll returns the rows of table matching values for every record.
+func (fd *fileData) getAll(table string, values map[string]string, columns ...string) ([]map[string]string, error) {
+	fd.RLock()
+	names := make([]string, 0, len(fd.Records))
+	for name := range fd.Records {
+		names = append(names, name)
+	}
+	fd.RUnlock()
+	res := make([]map[string]string, 0)
+	for _, name := range names {
+		cond := copyMap(values)
+		cond["name"] = name
+		rows, err := fd.Get(table, cond, columns...)
+		if err != nil && err != clientdata.ErrClientNotFound {
+			return nil, err
+		}
+		res = append(res, rows...)
+	}
+	return res, nil
+}
+
 //Set sets the values of the rows matching cond to those in values.
 func (fd *fileData) Set(table string, values, cond map[string]string) error {
 	r, found := fd.Records[cond["name"]]
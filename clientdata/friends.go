@@ -0,0 +1,118 @@
+package clientdata
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrFriendRequested = errors.New("clientdata: You have already asked them to be your friend.")
+var ErrNoFriendRequest = errors.New("clientdata: They haven't asked to be your friend.")
+
+//FriendRequest is a request from one client to be another's friend.
+type FriendRequest struct {
+	From string
+	To   string
+	Sent time.Time
+}
+
+//RequestFriend asks name to be the client's friend.  If name has already asked the client the request is accepted and it returns true.
+func (cdd *DataAccess) RequestFriend(name string) (bool, error) {
+	if !ValidateName(name) {
+		return false, ErrInvalidName
+	}
+	friend, err := cdd.IsFriend(name)
+	if err != nil {
+		return false, err
+	}
+	if friend {
+		return false, ErrFriend
+	}
+	asked, err := cdd.data.Exists("friendrequests", row("name", ServerRecord, "from", name, "to", cdd.name))
+	if err != nil {
+		return false, err
+	}
+	if asked {
+		return true, cdd.AcceptFriend(name)
+	}
+	exists, err := cdd.data.Exists("friendrequests", row("name", ServerRecord, "from", cdd.name, "to", name))
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, ErrFriendRequested
+	}
+	return false, cdd.data.Add("friendrequests", row("name", ServerRecord, "from", cdd.name, "to", name, "sent", time.Now().Format(TimeLayout)))
+}
+
+//FriendRequests returns the requests sent to the client and the requests the client has sent that haven't been answered.
+func (cdd *DataAccess) FriendRequests() (incoming, outgoing []FriendRequest, err error) {
+	incoming, err = cdd.friendRequests(row("name", ServerRecord, "to", cdd.name))
+	if err != nil {
+		return nil, nil, err
+	}
+	outgoing, err = cdd.friendRequests(row("name", ServerRecord, "from", cdd.name))
+	if err != nil {
+		return nil, nil, err
+	}
+	return incoming, outgoing, nil
+}
+
+//friendRequests returns the friend requests matching cond oldest first.
+func (cdd *DataAccess) friendRequests(cond map[string]string) ([]FriendRequest, error) {
+	rows, err := cdd.data.Get("friendrequests", cond)
+	if err == ErrClientNotFound {
+		return []FriendRequest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]FriendRequest, len(rows))
+	for i := range rows {
+		list[i] = FriendRequest{From: rows[i]["from"], To: rows[i]["to"]}
+		list[i].Sent, _ = time.Parse(TimeLayout, rows[i]["sent"])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Sent.Before(list[j].Sent) })
+	return list, nil
+}
+
+//AcceptFriend accepts name's friend request which puts each of them on the other's friend list.  It will return ErrNoFriendRequest if name hasn't asked.
+func (cdd *DataAccess) AcceptFriend(name string) error {
+	if err := cdd.removeFriendRequest(name, cdd.name); err != nil {
+		return err
+	}
+	if err := cdd.addFriend(cdd.name, name); err != nil {
+		return err
+	}
+	return cdd.addFriend(name, cdd.name)
+}
+
+//DeclineFriend deletes name's friend request.  It will return ErrNoFriendRequest if name hasn't asked.
+func (cdd *DataAccess) DeclineFriend(name string) error {
+	return cdd.removeFriendRequest(name, cdd.name)
+}
+
+//removeFriendRequest deletes the request from from to to.
+func (cdd *DataAccess) removeFriendRequest(from, to string) error {
+	if !ValidateName(from) {
+		return ErrInvalidName
+	}
+	cond := row("name", ServerRecord, "from", from, "to", to)
+	exists, err := cdd.data.Exists("friendrequests", cond)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNoFriendRequest
+	}
+	return cdd.data.Delete("friendrequests", cond)
+}
+
+//addFriend puts friend on name's friend list if they aren't already.
+func (cdd *DataAccess) addFriend(name, friend string) error {
+	exists, err := cdd.data.Exists("friends", row("friend", friend, "name", name))
+	if err != nil || exists {
+		return err
+	}
+	return cdd.data.Add("friends", row("friend", friend, "name", name))
+}
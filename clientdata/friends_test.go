@@ -0,0 +1,61 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestFriendRequests(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	fred, bob := df.Create("Fred"), df.Create("Bob")
+	if accepted, err := fred.RequestFriend("Bob"); accepted || err != nil {
+		t.Fatalf("Error requesting friend: %v %v", accepted, err)
+	}
+	if _, err := fred.RequestFriend("Bob"); err != clientdata.ErrFriendRequested {
+		t.Error("Expected a second request to fail, got: ", err)
+	}
+	if friend, _ := fred.IsFriend("Bob"); friend {
+		t.Error("Expected a request not to add a friend until it is accepted")
+	}
+	incoming, outgoing, _ := bob.FriendRequests()
+	if len(incoming) != 1 || incoming[0].From != "Fred" || len(outgoing) != 0 {
+		t.Errorf("Wrong requests for Bob: %v %v", incoming, outgoing)
+	}
+	if err := fred.AcceptFriend("Bob"); err != clientdata.ErrNoFriendRequest {
+		t.Error("Expected Fred not to be able to accept his own request, got: ", err)
+	}
+	if err := bob.AcceptFriend("Fred"); err != nil {
+		t.Fatal("Error accepting: ", err)
+	}
+	for _, pair := range [][2]clientdata.ClientData{{fred, bob}, {bob, fred}} {
+		list, _ := pair[0].FriendList()
+		if len(list) != 1 {
+			t.Errorf("Expected friendship to be mutual, got: %v", list)
+		}
+	}
+	if err := bob.Unfriend("Fred"); err != nil {
+		t.Fatal("Error unfriending: ", err)
+	}
+	if friend, _ := fred.IsFriend("Bob"); friend {
+		t.Error("Expected unfriending to remove both sides")
+	}
+}
+
+func TestMutualRequestsAccept(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	_, _ = df.Create("Fred").RequestFriend("Bob")
+	if accepted, err := df.Create("Bob").RequestFriend("Fred"); !accepted || err != nil {
+		t.Errorf("Expected asking someone who already asked to accept, got: %v %v", accepted, err)
+	}
+	if err := df.Create("Fred").DeclineFriend("Bob"); err != clientdata.ErrNoFriendRequest {
+		t.Error("Expected the request to be gone, got: ", err)
+	}
+	_, _ = df.Create("Sue").RequestFriend("Fred")
+	if err := df.Create("Sue").Unfriend("Fred"); err != nil {
+		t.Error("Expected unfriend to cancel a request, got: ", err)
+	}
+	if incoming, _, _ := df.Create("Fred").FriendRequests(); len(incoming) != 0 {
+		t.Errorf("Expected no requests, got: %v", incoming)
+	}
+}
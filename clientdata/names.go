@@ -0,0 +1,181 @@
+package clientdata
+
+import (
+	"errors"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//MaxDisplayName is the most characters a display name can have.
+const MaxDisplayName = 32
+
+//Kinds of rows in the names table.
+const (
+	nameLogin    = "login"
+	nameDisplay  = "display"
+	nameBackfill = "backfill" //marks that BackfillLogins has run
+)
+
+var ErrInvalidDisplayName = errors.New("clientdata: Invalid display name.")
+var ErrNameTaken = errors.New("clientdata: That name is taken.")
+
+//confusables maps letters and digits that look like a Latin letter to that letter.  It covers the Cyrillic and Greek homoglyphs that are used to impersonate Latin names.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', 'I': 'l', '|': 'l', '5': 's',
+	'А': 'a', 'а': 'a', 'В': 'b', 'в': 'b', 'С': 'c', 'с': 'c', 'ԁ': 'd', 'Е': 'e', 'е': 'e', 'ё': 'e',
+	'Н': 'h', 'н': 'h', 'һ': 'h', 'І': 'l', 'і': 'i', 'Ј': 'j', 'ј': 'j', 'К': 'k', 'к': 'k', 'М': 'm', 'м': 'm',
+	'О': 'o', 'о': 'o', 'Р': 'p', 'р': 'p', 'ԛ': 'q', 'Ѕ': 's', 'ѕ': 's', 'Т': 't', 'т': 't', 'У': 'y', 'у': 'y',
+	'Ү': 'y', 'ү': 'y', 'ԝ': 'w', 'Х': 'x', 'х': 'x',
+	'Α': 'a', 'α': 'a', 'Β': 'b', 'β': 'b', 'Ε': 'e', 'ε': 'e', 'Ζ': 'z', 'Η': 'h', 'Ι': 'l', 'ι': 'i', 'Κ': 'k', 'κ': 'k',
+	'Μ': 'm', 'Ν': 'n', 'ν': 'v', 'Ο': 'o', 'ο': 'o', 'Ρ': 'p', 'ρ': 'p', 'Τ': 't', 'τ': 't', 'Υ': 'y', 'υ': 'u',
+	'Χ': 'x', 'χ': 'x', 'ω': 'w',
+}
+
+//confusableScripts are the scripts whose letters are confusable with each other.  A display name may only use one of them.
+var confusableScripts = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek}
+
+//NormalizeName trims the spaces from name and puts it in Unicode normalization form C.
+func NormalizeName(name string) string {
+	return norm.NFC.String(strings.TrimSpace(name))
+}
+
+//ValidateDisplayName returns true if name is a valid display name.  Display names may have Unicode letters, marks and digits with single spaces and the punctuation .-_' between them.  They must be in normalization form C, start with a letter or digit and not mix Latin, Cyrillic and Greek letters.
+func ValidateDisplayName(name string) bool {
+	if name == "" || utf8.RuneCountInString(name) > MaxDisplayName || !norm.NFC.IsNormalString(name) {
+		return false
+	}
+	script := -1
+	var last rune
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+			for s := range confusableScripts {
+				if unicode.Is(confusableScripts[s], r) {
+					if script != -1 && script != s {
+						return false
+					}
+					script = s
+				}
+			}
+		case unicode.IsDigit(r):
+		case i == 0:
+			return false
+		case unicode.IsMark(r):
+		case r == ' ' || strings.ContainsRune(".-_'", r):
+			if last == ' ' {
+				return false
+			}
+		default:
+			return false
+		}
+		last = r
+	}
+	return last != ' '
+}
+
+//Skeleton returns the form of name used to find names that look alike.  Accents, spaces and punctuation are removed, letters are lower cased and homoglyphs are replaced with the Latin letter they look like.
+func Skeleton(name string) string {
+	var skel strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		r = unicode.ToLower(r)
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			skel.WriteRune(r)
+		}
+	}
+	return skel.String()
+}
+
+//registerLogin adds name to the names table so other logins and display names can be checked against it.
+func (cdd *DataAccess) registerLogin(name string) error {
+	exists, err := cdd.data.Exists("names", row("name", ServerRecord, "kind", nameLogin, "owner", name))
+	if err != nil || exists {
+		return err
+	}
+	return cdd.data.Add("names", row("name", ServerRecord, "kind", nameLogin, "owner", name, "value", name, "lower", strings.ToLower(name), "skeleton", Skeleton(name)))
+}
+
+//BackfillLogins adds clients created before the names table existed to it so their logins are checked when clients register or set display names.  It only runs once for a DataStore.
+func (cdd *DataAccess) BackfillLogins() error {
+	done, err := cdd.data.Exists("names", row("name", ServerRecord, "kind", nameBackfill))
+	if err != nil || done {
+		return err
+	}
+	rows, err := cdd.data.Get("client", row(), "name")
+	if err != nil && err != ErrClientNotFound {
+		return err
+	}
+	for i := range rows {
+		if err = cdd.registerLogin(rows[i]["name"]); err != nil {
+			return err
+		}
+	}
+	return cdd.data.Add("names", row("name", ServerRecord, "kind", nameBackfill))
+}
+
+//loginTaken returns true if name is already used by another login ignoring case or looks like someone's display name.
+func (cdd *DataAccess) loginTaken(name string) (bool, error) {
+	exists, err := cdd.data.Exists("names", row("name", ServerRecord, "kind", nameLogin, "lower", strings.ToLower(name)))
+	if err != nil || exists {
+		return exists, err
+	}
+	return cdd.data.Exists("names", row("name", ServerRecord, "kind", nameDisplay, "skeleton", Skeleton(name)))
+}
+
+//DisplayName returns the client's display name or an empty string if they don't have one.
+func (cdd *DataAccess) DisplayName() (string, error) {
+	rows, err := cdd.data.Get("names", row("name", ServerRecord, "kind", nameDisplay, "owner", cdd.name), "value")
+	switch {
+	case err == ErrClientNotFound:
+		return "", nil
+	case err != nil:
+		return "", err
+	case len(rows) == 0:
+		return "", nil
+	}
+	return rows[0]["value"], nil
+}
+
+//SetDisplayName changes the client's display name.  An empty name clears it.  name is normalized first and it will return ErrInvalidDisplayName if it isn't valid or ErrNameTaken if it looks like another client's login or display name.
+func (cdd *DataAccess) SetDisplayName(name string) error {
+	name = NormalizeName(name)
+	if name != "" {
+		if !ValidateDisplayName(name) {
+			return ErrInvalidDisplayName
+		}
+		skel := Skeleton(name)
+		rows, err := cdd.data.Get("names", row("name", ServerRecord, "skeleton", skel), "owner")
+		if err != nil && err != ErrClientNotFound {
+			return err
+		}
+		for i := range rows {
+			if rows[i]["owner"] != cdd.name {
+				return ErrNameTaken
+			}
+		}
+		if ValidateName(name) && name != cdd.name {
+			exists, err := cdd.ClientExists(name)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return ErrNameTaken
+			}
+		}
+	}
+	err := cdd.data.Delete("names", row("name", ServerRecord, "kind", nameDisplay, "owner", cdd.name))
+	if err != nil && err != ErrClientNotFound {
+		return err
+	}
+	if name == "" {
+		return nil
+	}
+	return cdd.data.Add("names", row("name", ServerRecord, "kind", nameDisplay, "owner", cdd.name, "value", name, "lower", strings.ToLower(name), "skeleton", Skeleton(name)))
+}
@@ -0,0 +1,55 @@
+package clientdata
+
+import (
+	"testing"
+)
+
+func TestValidateDisplayName(t *testing.T) {
+	var tests = []struct {
+		name  string
+		valid bool
+	}{
+		{"José", true},
+		{"Zoë O'Brien", true},
+		{"Дмитрий", true},
+		{"山田 太郎", true},
+		{"José", false}, //not NFC
+		{"", false},
+		{" Fred", false},
+		{"Fred ", false},
+		{"Fr  ed", false},
+		{"-Fred", false},
+		{"Fred!", false},
+		{"Frеd", false}, //Cyrillic е
+		{"ThisNameIsMuchTooLongToBeADisplayName", false},
+	}
+	for _, tt := range tests {
+		if ValidateDisplayName(tt.name) != tt.valid {
+			t.Errorf("ValidateDisplayName(%q) => %v, want %v", tt.name, !tt.valid, tt.valid)
+		}
+	}
+	if !ValidateDisplayName(NormalizeName(" José ")) {
+		t.Error("Expected NormalizeName to make a valid display name")
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	var tests = []struct {
+		a, b string
+	}{
+		{"Fred", "fred"},
+		{"José", "Jose"},
+		{"Раypal", "paypal"}, //Cyrillic Р and а
+		{"Ivan", "lvan"},
+		{"B0b", "Bob"},
+		{"Mary Ann", "MaryAnn"},
+	}
+	for _, tt := range tests {
+		if Skeleton(tt.a) != Skeleton(tt.b) {
+			t.Errorf("Skeleton(%q) = %q, Skeleton(%q) = %q, expected them to match", tt.a, Skeleton(tt.a), tt.b, Skeleton(tt.b))
+		}
+	}
+	if Skeleton("Fred") == Skeleton("Bob") {
+		t.Error("Expected different names to have different skeletons")
+	}
+}
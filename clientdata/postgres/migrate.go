@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//migrationFiles holds the scripts to upgrade the schema to each version and back.  They are named like 0001_initial.up.sql and 0001_initial.down.sql.  New features add their tables by adding the next number.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//Latest is passed to Migrate to bring the schema up to the newest version.
+const Latest = -1
+
+//ErrUnknownVersion is returned by Migrate for a version there is no migration for.
+var ErrUnknownVersion = errors.New("postgres: unknown schema version")
+
+//ErrSchemaTooNew is returned when the database has migrations this server doesn't know about, as when a newer server has upgraded it.
+var ErrSchemaTooNew = errors.New("postgres: database schema is newer than this server")
+
+//schemaTable records the migrations that have been applied.  The schema's version is the highest one.
+const schemaTable = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY,
+	name    TEXT NOT NULL,
+	applied TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+//Migration is a version of the schema with the SQL to upgrade to it from the version before and to downgrade back.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+//Migrations returns the embedded migrations in order.  Versions start at 1 and every version has an up and a down script.
+func Migrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[int]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+		stem := strings.TrimSuffix(base, ".sql")
+		up := strings.HasSuffix(stem, ".up")
+		if !up && !strings.HasSuffix(stem, ".down") {
+			return nil, fmt.Errorf("postgres: migration %v is not an up or down script", base)
+		}
+		stem = strings.TrimSuffix(strings.TrimSuffix(stem, ".up"), ".down")
+		parts := strings.SplitN(stem, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version < 1 || len(parts) != 2 {
+			return nil, fmt.Errorf("postgres: migration %v is not named like 0001_name.up.sql", base)
+		}
+		script, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := found[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[1]}
+			found[version] = m
+		} else if m.Name != parts[1] {
+			return nil, fmt.Errorf("postgres: migration %v is named both %v and %v", version, m.Name, parts[1])
+		}
+		if up {
+			m.Up = string(script)
+		} else {
+			m.Down = string(script)
+		}
+	}
+	list := make([]Migration, 0, len(found))
+	for _, m := range found {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, m := range list {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("postgres: migration %v is missing", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("postgres: migration %v_%v needs both an up and a down script", m.Version, m.Name)
+		}
+	}
+	return list, nil
+}
+
+//Version returns the version of the schema in the database.  It is 0 if no migrations have been applied.
+func (p *Postgres) Version() (int, error) {
+	if _, err := p.data.Exec(schemaTable); err != nil {
+		return 0, err
+	}
+	return schemaVersion(p.data)
+}
+
+//schemaVersion reads the schema's version with db, which can be a transaction.
+func schemaVersion(db interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}) (int, error) {
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+//Migrate upgrades or downgrades the schema to version, or to the newest one if version is Latest.  Each migration runs in its own transaction with schema_version locked so servers starting at the same time don't both apply it.
+func (p *Postgres) Migrate(version int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if version == Latest {
+		version = len(migrations)
+	}
+	if version < 0 || version > len(migrations) {
+		return ErrUnknownVersion
+	}
+	if _, err = p.data.Exec(schemaTable); err != nil {
+		return err
+	}
+	for {
+		done, err := p.step(migrations, version)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+//step applies or undoes one migration to move the schema toward version.  It returns true once the schema is at version.
+func (p *Postgres) step(migrations []Migration, version int) (bool, error) {
+	tx, err := p.data.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("LOCK TABLE schema_version IN EXCLUSIVE MODE"); err != nil {
+		return false, err
+	}
+	current, err := schemaVersion(tx)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case current > len(migrations):
+		return false, ErrSchemaTooNew
+	case current == version:
+		return true, nil
+	case current < version:
+		m := migrations[current]
+		if _, err = tx.Exec(m.Up); err != nil {
+			return false, fmt.Errorf("postgres: migration %v_%v: %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec("INSERT INTO schema_version (version, name) VALUES ($1, $2)", m.Version, m.Name)
+	default:
+		m := migrations[current-1]
+		if _, err = tx.Exec(m.Down); err != nil {
+			return false, fmt.Errorf("postgres: undoing migration %v_%v: %v", m.Version, m.Name, err)
+		}
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = $1", m.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+	return false, tx.Commit()
+}
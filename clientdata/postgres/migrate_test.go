@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var (
+	createTable = regexp.MustCompile(`CREATE TABLE IF NOT EXISTS (\w+)`)
+	dropTable   = regexp.MustCompile(`DROP TABLE IF EXISTS (\w+)`)
+	usedTable   = regexp.MustCompile(`data\.(?:Add|Delete|Get|Set|Exists)\("(\w+)"`)
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal("Error loading migrations: ", err)
+	}
+	if len(migrations) == 0 || migrations[0].Name != "initial" {
+		t.Fatalf("Expected the first migration to be initial, got: %v", migrations)
+	}
+	for _, m := range migrations {
+		created := createTable.FindAllStringSubmatch(m.Up, -1)
+		dropped := make(map[string]bool)
+		for _, d := range dropTable.FindAllStringSubmatch(m.Down, -1) {
+			dropped[d[1]] = true
+		}
+		for _, c := range created {
+			if !dropped[c[1]] {
+				t.Errorf("Expected %v_%v's down script to drop %v", m.Version, m.Name, c[1])
+			}
+		}
+	}
+}
+
+//TestMigrationsCoverClientData checks every table clientdata uses is created by a migration.
+func TestMigrationsCoverClientData(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal("Error loading migrations: ", err)
+	}
+	tables := make(map[string]bool)
+	for _, m := range migrations {
+		for _, c := range createTable.FindAllStringSubmatch(m.Up, -1) {
+			tables[c[1]] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join("..", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, used := range usedTable.FindAllStringSubmatch(string(src), -1) {
+			if !tables[used[1]] {
+				t.Errorf("Expected a migration to create the %v table used in %v", used[1], filepath.Base(file))
+			}
+		}
+	}
+}
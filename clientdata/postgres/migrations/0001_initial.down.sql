@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS friends;
+DROP TABLE IF EXISTS blocked;
+DROP TABLE IF EXISTS client;
@@ -0,0 +1,20 @@
+-- The tables the server has always used.  IF NOT EXISTS lets databases that were set up by hand adopt the migrations.
+
+CREATE TABLE IF NOT EXISTS client (
+	name       TEXT NOT NULL DEFAULT '',
+	password   TEXT NOT NULL DEFAULT '',
+	lastonline TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS client_name ON client (name);
+
+CREATE TABLE IF NOT EXISTS blocked (
+	name    TEXT NOT NULL DEFAULT '',
+	blocked TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS blocked_name ON blocked (name, blocked);
+
+CREATE TABLE IF NOT EXISTS friends (
+	name   TEXT NOT NULL DEFAULT '',
+	friend TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS friends_name ON friends (name, friend);
@@ -0,0 +1,17 @@
+-- Administrators and account and IP bans.
+
+CREATE TABLE IF NOT EXISTS admins (
+	name TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS admins_name ON admins (name);
+
+CREATE TABLE IF NOT EXISTS bans (
+	name     TEXT NOT NULL DEFAULT '',
+	kind     TEXT NOT NULL DEFAULT '',
+	target   TEXT NOT NULL DEFAULT '',
+	reason   TEXT NOT NULL DEFAULT '',
+	bannedby TEXT NOT NULL DEFAULT '',
+	created  TEXT NOT NULL DEFAULT '',
+	expires  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS bans_target ON bans (name, kind, target);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recoverycodes;
+DROP TABLE IF EXISTS totp;
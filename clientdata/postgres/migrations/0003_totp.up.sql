@@ -0,0 +1,15 @@
+-- Two-factor authentication secrets and recovery codes.
+
+CREATE TABLE IF NOT EXISTS totp (
+	name        TEXT NOT NULL DEFAULT '',
+	secret      TEXT NOT NULL DEFAULT '',
+	enabled     TEXT NOT NULL DEFAULT '',
+	lastcounter TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS totp_name ON totp (name);
+
+CREATE TABLE IF NOT EXISTS recoverycodes (
+	name TEXT NOT NULL DEFAULT '',
+	code TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS recoverycodes_name ON recoverycodes (name, code);
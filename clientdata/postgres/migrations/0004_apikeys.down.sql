@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ownedbots;
+DROP TABLE IF EXISTS bots;
+DROP TABLE IF EXISTS apikeys;
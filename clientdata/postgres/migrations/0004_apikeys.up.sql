@@ -0,0 +1,23 @@
+-- API keys and bot accounts.
+
+CREATE TABLE IF NOT EXISTS apikeys (
+	name     TEXT NOT NULL DEFAULT '',
+	id       TEXT NOT NULL DEFAULT '',
+	hash     TEXT NOT NULL DEFAULT '',
+	rooms    TEXT NOT NULL DEFAULT '',
+	commands TEXT NOT NULL DEFAULT '',
+	created  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS apikeys_name ON apikeys (name, id);
+
+CREATE TABLE IF NOT EXISTS bots (
+	name  TEXT NOT NULL DEFAULT '',
+	owner TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS bots_name ON bots (name);
+
+CREATE TABLE IF NOT EXISTS ownedbots (
+	name TEXT NOT NULL DEFAULT '',
+	bot  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS ownedbots_name ON ownedbots (name);
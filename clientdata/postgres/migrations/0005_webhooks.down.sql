@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS incomingwebhooks;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,23 @@
+-- Outgoing and incoming webhooks.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	name      TEXT NOT NULL DEFAULT '',
+	id        TEXT NOT NULL DEFAULT '',
+	room      TEXT NOT NULL DEFAULT '',
+	url       TEXT NOT NULL DEFAULT '',
+	secret    TEXT NOT NULL DEFAULT '',
+	createdby TEXT NOT NULL DEFAULT '',
+	created   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS webhooks_id ON webhooks (name, id);
+
+CREATE TABLE IF NOT EXISTS incomingwebhooks (
+	name      TEXT NOT NULL DEFAULT '',
+	id        TEXT NOT NULL DEFAULT '',
+	hash      TEXT NOT NULL DEFAULT '',
+	room      TEXT NOT NULL DEFAULT '',
+	username  TEXT NOT NULL DEFAULT '',
+	createdby TEXT NOT NULL DEFAULT '',
+	created   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS incomingwebhooks_id ON incomingwebhooks (name, id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mailbox;
+DROP TABLE IF EXISTS scheduled;
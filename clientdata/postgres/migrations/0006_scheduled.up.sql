@@ -0,0 +1,21 @@
+-- Reminders, scheduled messages and mail for offline users.
+
+CREATE TABLE IF NOT EXISTS scheduled (
+	name    TEXT NOT NULL DEFAULT '',
+	id      TEXT NOT NULL DEFAULT '',
+	kind    TEXT NOT NULL DEFAULT '',
+	owner   TEXT NOT NULL DEFAULT '',
+	target  TEXT NOT NULL DEFAULT '',
+	text    TEXT NOT NULL DEFAULT '',
+	at      TEXT NOT NULL DEFAULT '',
+	created TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS scheduled_id ON scheduled (name, id);
+
+CREATE TABLE IF NOT EXISTS mailbox (
+	name   TEXT NOT NULL DEFAULT '',
+	sender TEXT NOT NULL DEFAULT '',
+	text   TEXT NOT NULL DEFAULT '',
+	sent   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS mailbox_name ON mailbox (name);
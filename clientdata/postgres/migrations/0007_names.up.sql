@@ -0,0 +1,13 @@
+-- Login and display names.  lower and skeleton are used to find names that only differ by case or look alike.
+
+CREATE TABLE IF NOT EXISTS names (
+	name     TEXT NOT NULL DEFAULT '',
+	kind     TEXT NOT NULL DEFAULT '',
+	owner    TEXT NOT NULL DEFAULT '',
+	value    TEXT NOT NULL DEFAULT '',
+	lower    TEXT NOT NULL DEFAULT '',
+	skeleton TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS names_owner ON names (owner);
+CREATE INDEX IF NOT EXISTS names_lower ON names (lower);
+CREATE INDEX IF NOT EXISTS names_skeleton ON names (skeleton);
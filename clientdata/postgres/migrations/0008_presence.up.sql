@@ -0,0 +1,8 @@
+-- Presence states and status text.
+
+CREATE TABLE IF NOT EXISTS presence (
+	name   TEXT NOT NULL DEFAULT '',
+	state  TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS presence_name ON presence (name);
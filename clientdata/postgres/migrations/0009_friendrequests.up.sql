@@ -0,0 +1,10 @@
+-- Friend requests.  from and to are quoted since they're keywords.
+
+CREATE TABLE IF NOT EXISTS friendrequests (
+	name   TEXT NOT NULL DEFAULT '',
+	"from" TEXT NOT NULL DEFAULT '',
+	"to"   TEXT NOT NULL DEFAULT '',
+	sent   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS friendrequests_to ON friendrequests ("to");
+CREATE INDEX IF NOT EXISTS friendrequests_from ON friendrequests ("from");
@@ -0,0 +1,9 @@
+-- Read positions in each room.
+
+CREATE TABLE IF NOT EXISTS lastread (
+	name  TEXT NOT NULL DEFAULT '',
+	room  TEXT NOT NULL DEFAULT '',
+	epoch TEXT NOT NULL DEFAULT '',
+	seq   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS lastread_name ON lastread (name, room);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS directmessages;
+DROP TABLE IF EXISTS conversationmembers;
+DROP TABLE IF EXISTS conversations;
@@ -0,0 +1,29 @@
+-- Direct message conversations, their members and their history.
+
+CREATE TABLE IF NOT EXISTS conversations (
+	name    TEXT NOT NULL DEFAULT '',
+	id      TEXT NOT NULL DEFAULT '',
+	members TEXT NOT NULL DEFAULT '',
+	count   TEXT NOT NULL DEFAULT '',
+	created TEXT NOT NULL DEFAULT '',
+	updated TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS conversations_id ON conversations (id);
+CREATE INDEX IF NOT EXISTS conversations_members ON conversations (members);
+
+CREATE TABLE IF NOT EXISTS conversationmembers (
+	name   TEXT NOT NULL DEFAULT '',
+	id     TEXT NOT NULL DEFAULT '',
+	member TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS conversationmembers_member ON conversationmembers (member);
+
+CREATE TABLE IF NOT EXISTS directmessages (
+	name         TEXT NOT NULL DEFAULT '',
+	conversation TEXT NOT NULL DEFAULT '',
+	seq          TEXT NOT NULL DEFAULT '',
+	"from"       TEXT NOT NULL DEFAULT '',
+	text         TEXT NOT NULL DEFAULT '',
+	sent         TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS directmessages_conversation ON directmessages (conversation);
@@ -0,0 +1,13 @@
+-- Uploaded files.
+
+CREATE TABLE IF NOT EXISTS attachments (
+	name     TEXT NOT NULL DEFAULT '',
+	id       TEXT NOT NULL DEFAULT '',
+	room     TEXT NOT NULL DEFAULT '',
+	uploader TEXT NOT NULL DEFAULT '',
+	filename TEXT NOT NULL DEFAULT '',
+	mime     TEXT NOT NULL DEFAULT '',
+	size     TEXT NOT NULL DEFAULT '',
+	created  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS attachments_id ON attachments (id);
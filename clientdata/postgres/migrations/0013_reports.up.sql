@@ -0,0 +1,19 @@
+-- Abuse reports.
+
+CREATE TABLE IF NOT EXISTS reports (
+	name       TEXT NOT NULL DEFAULT '',
+	id         TEXT NOT NULL DEFAULT '',
+	reporter   TEXT NOT NULL DEFAULT '',
+	reported   TEXT NOT NULL DEFAULT '',
+	room       TEXT NOT NULL DEFAULT '',
+	seq        TEXT NOT NULL DEFAULT '',
+	message    TEXT NOT NULL DEFAULT '',
+	context    TEXT NOT NULL DEFAULT '',
+	reason     TEXT NOT NULL DEFAULT '',
+	created    TEXT NOT NULL DEFAULT '',
+	action     TEXT NOT NULL DEFAULT '',
+	resolvedby TEXT NOT NULL DEFAULT '',
+	resolved   TEXT NOT NULL DEFAULT '',
+	note       TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS reports_id ON reports (id);
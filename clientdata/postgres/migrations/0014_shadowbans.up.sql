@@ -0,0 +1,10 @@
+-- Shadowbans.
+
+CREATE TABLE IF NOT EXISTS shadowbans (
+	name     TEXT NOT NULL DEFAULT '',
+	target   TEXT NOT NULL DEFAULT '',
+	reason   TEXT NOT NULL DEFAULT '',
+	bannedby TEXT NOT NULL DEFAULT '',
+	created  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS shadowbans_target ON shadowbans (target);
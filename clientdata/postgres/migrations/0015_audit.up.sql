@@ -0,0 +1,15 @@
+-- The audit log of administrator actions.
+
+CREATE TABLE IF NOT EXISTS audit (
+	name   TEXT NOT NULL DEFAULT '',
+	seq    TEXT NOT NULL DEFAULT '',
+	time   TEXT NOT NULL DEFAULT '',
+	actor  TEXT NOT NULL DEFAULT '',
+	action TEXT NOT NULL DEFAULT '',
+	target TEXT NOT NULL DEFAULT '',
+	source TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT '',
+	prev   TEXT NOT NULL DEFAULT '',
+	hash   TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS audit_seq ON audit (seq);
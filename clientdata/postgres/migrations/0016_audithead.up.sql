@@ -0,0 +1,7 @@
+-- The newest event in the audit log so events removed from its end can be found.
+
+CREATE TABLE IF NOT EXISTS audithead (
+	name TEXT NOT NULL DEFAULT '',
+	seq  TEXT NOT NULL DEFAULT '',
+	hash TEXT NOT NULL DEFAULT ''
+);
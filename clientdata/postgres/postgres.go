@@ -6,7 +6,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/DavidAFox/Chat/clientdata"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"log"
 	"strconv"
 )
@@ -34,8 +34,17 @@ type Postgres struct {
 	data *sql.DB
 }
 
-//NewPostgres sets up the connection to the database and returns a Postgres datastore.
+//NewPostgres sets up the connection to the database, brings its schema up to date and returns a Postgres datastore.
 func NewPostgres(databaseLogin, databasePassword, databaseName, databaseIP, databasePort string) (*Postgres, error) {
+	p, err := Connect(databaseLogin, databasePassword, databaseName, databaseIP, databasePort)
+	if err != nil {
+		return p, err
+	}
+	return p, p.Migrate(Latest)
+}
+
+//Connect sets up the connection to the database without checking its schema.  It is used to run migrations by hand.
+func Connect(databaseLogin, databasePassword, databaseName, databaseIP, databasePort string) (*Postgres, error) {
 	p := new(Postgres)
 	if databaseIP == "" {
 		databaseIP = "localhost"
@@ -48,12 +57,22 @@ func NewPostgres(databaseLogin, databasePassword, databaseName, databaseIP, data
 	return p, err
 }
 
+//Close closes the connection to the database.
+func (p *Postgres) Close() error {
+	return p.data.Close()
+}
+
+//quote returns name quoted for use as an identifier so columns like from and to can be used.
+func quote(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
 //Add adds row values to table.
 func (p *Postgres) Add(table string, values map[string]string) error {
-	qstring := "INSERT INTO " + table + " ("
+	qstring := "INSERT INTO " + quote(table) + " ("
 	args := make([]interface{}, 0, len(values))
 	for i := range values {
-		qstring += i + ", "
+		qstring += quote(i) + ", "
 		args = append(args, values[i])
 	}
 	qstring = qstring[:len(qstring)-2] + ") VALUES ("
@@ -69,14 +88,14 @@ func (p *Postgres) Add(table string, values map[string]string) error {
 
 //Delete removes rows matching values from table.
 func (p *Postgres) Delete(table string, values map[string]string) error {
-	qstring := "DELETE FROM " + table + " WHERE "
+	qstring := "DELETE FROM " + quote(table) + " WHERE "
 	args := make([]interface{}, 0, len(values)*2)
 	x := 1
 	for i := range values {
 		if x != 1 {
 			qstring += " AND "
 		}
-		qstring += i + " = $" + strconv.Itoa(x)
+		qstring += quote(i) + " = $" + strconv.Itoa(x)
 		args = append(args, values[i])
 		x++
 	}
@@ -93,18 +112,21 @@ func (p *Postgres) Get(table string, values map[string]string, columns ...string
 			if i != 0 {
 				qstring += ", "
 			}
-			qstring += columns[i]
+			qstring += quote(columns[i])
 		}
 	} else {
 		qstring += "*"
 	}
-	qstring += " FROM " + table + " WHERE "
+	qstring += " FROM " + quote(table)
+	if len(values) > 0 {
+		qstring += " WHERE "
+	}
 	x := 1
 	for i := range values {
 		if x != 1 {
 			qstring += " AND "
 		}
-		qstring += i + " = $" + strconv.Itoa(x)
+		qstring += quote(i) + " = $" + strconv.Itoa(x)
 		args = append(args, values[i])
 		x++
 	}
@@ -138,14 +160,14 @@ func (p *Postgres) Get(table string, values map[string]string, columns ...string
 
 //Set finds a row matching cond in table and sets the column/value pairs in values.
 func (p *Postgres) Set(table string, values, cond map[string]string) error {
-	qstring := "UPDATE " + table + " SET "
+	qstring := "UPDATE " + quote(table) + " SET "
 	args := make([]interface{}, 0, len(values))
 	x := 1
 	for i := range values {
 		if x != 1 {
-			qstring += " AND "
+			qstring += ", "
 		}
-		qstring += i + " = $" + strconv.Itoa(x)
+		qstring += quote(i) + " = $" + strconv.Itoa(x)
 		args = append(args, values[i])
 		x++
 	}
@@ -155,7 +177,7 @@ func (p *Postgres) Set(table string, values, cond map[string]string) error {
 		if x != 1+len(values) {
 			qstring += " AND "
 		}
-		qstring += i + " = $" + strconv.Itoa(x)
+		qstring += quote(i) + " = $" + strconv.Itoa(x)
 		args2 = append(args2, cond[i])
 		x++
 	}
@@ -166,14 +188,14 @@ func (p *Postgres) Set(table string, values, cond map[string]string) error {
 
 //Exists takes a table to check and a map representing a row to compare to and returns true if there is a match in the database.
 func (p *Postgres) Exists(table string, values map[string]string) (bool, error) {
-	qstring := "SELECT count(*) FROM " + table + " WHERE "
+	qstring := "SELECT count(*) FROM " + quote(table) + " WHERE "
 	args := make([]interface{}, 0, len(values))
 	x := 1
 	for i := range values {
 		if x != 1 {
 			qstring += " AND "
 		}
-		qstring += i + " = $" + strconv.Itoa(x)
+		qstring += quote(i) + " = $" + strconv.Itoa(x)
 		args = append(args, values[i])
 		x++
 	}
@@ -0,0 +1,27 @@
+package clientdata
+
+//Presence returns the presence state and status text the client last set.  Both are empty if they never set one.
+func (cdd *DataAccess) Presence() (state, status string, err error) {
+	rows, err := cdd.data.Get("presence", row("name", cdd.name), "state", "status")
+	switch {
+	case err == ErrClientNotFound:
+		return "", "", nil
+	case err != nil:
+		return "", "", err
+	case len(rows) == 0:
+		return "", "", nil
+	}
+	return rows[0]["state"], rows[0]["status"], nil
+}
+
+//SetPresence saves the client's presence state and status text.
+func (cdd *DataAccess) SetPresence(state, status string) error {
+	exists, err := cdd.data.Exists("presence", row("name", cdd.name))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return cdd.data.Set("presence", row("state", state, "status", status), row("name", cdd.name))
+	}
+	return cdd.data.Add("presence", row("name", cdd.name, "state", state, "status", status))
+}
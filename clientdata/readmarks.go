@@ -0,0 +1,44 @@
+package clientdata
+
+import (
+	"strconv"
+)
+
+//ReadPosition is where a client last read up to in a room.  Epoch and Seq come from the room.
+type ReadPosition struct {
+	Epoch string
+	Seq   int
+}
+
+//MarkRead saves the client's read position in room.
+func (cdd *DataAccess) MarkRead(room, epoch string, seq int) error {
+	cond := row("name", cdd.name, "room", room)
+	exists, err := cdd.data.Exists("lastread", cond)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return cdd.data.Set("lastread", row("epoch", epoch, "seq", strconv.Itoa(seq)), cond)
+	}
+	return cdd.data.Add("lastread", row("name", cdd.name, "room", room, "epoch", epoch, "seq", strconv.Itoa(seq)))
+}
+
+//ReadPositions returns the client's read positions keyed by room.
+func (cdd *DataAccess) ReadPositions() (map[string]ReadPosition, error) {
+	positions := make(map[string]ReadPosition)
+	rows, err := cdd.data.Get("lastread", row("name", cdd.name))
+	if err == ErrClientNotFound {
+		return positions, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		seq, err := strconv.Atoi(rows[i]["seq"])
+		if err != nil {
+			return nil, err
+		}
+		positions[rows[i]["room"]] = ReadPosition{Epoch: rows[i]["epoch"], Seq: seq}
+	}
+	return positions, nil
+}
@@ -0,0 +1,30 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestReadPositions(t *testing.T) {
+	fred := filedata.NewMemDataFactory().Create("Fred")
+	if positions, err := fred.ReadPositions(); err != nil || len(positions) != 0 {
+		t.Fatalf("Expected no read positions, got: %v %v", positions, err)
+	}
+	_ = fred.MarkRead("Lobby", "a", 3)
+	_ = fred.MarkRead("Games", "b", 1)
+	_ = fred.MarkRead("Lobby", "a", 7)
+	positions, err := fred.ReadPositions()
+	if err != nil {
+		t.Fatal("Error getting read positions: ", err)
+	}
+	expected := map[string]clientdata.ReadPosition{"Lobby": {"a", 7}, "Games": {"b", 1}}
+	if len(positions) != len(expected) {
+		t.Fatalf("Wrong read positions expected: %v, got: %v", expected, positions)
+	}
+	for room, p := range expected {
+		if positions[room] != p {
+			t.Errorf("Wrong read position for %v expected: %v, got: %v", room, p, positions[room])
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestLoginNamesIgnoreCase(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	if err := df.Create("Fred").NewClient("pass"); err != nil {
+		t.Fatal("Error creating client: ", err)
+	}
+	if err := df.Create("fred").NewClient("pass"); err != clientdata.ErrClientExists {
+		t.Error("Expected a name that only differs in case to be taken, got: ", err)
+	}
+}
+
+func TestBackfillLogins(t *testing.T) {
+	store := filedata.NewMemData()
+	_ = store.Add("client", map[string]string{"name": "Fred", "password": "x", "lastonline": ""})
+	admin := clientdata.NewDataAccess(clientdata.ServerRecord, store, false)
+	if err := admin.BackfillLogins(); err != nil {
+		t.Fatal("Error backfilling: ", err)
+	}
+	if err := clientdata.NewDataAccess("fred", store, false).NewClient("pass"); err != clientdata.ErrClientExists {
+		t.Error("Expected an account from before names were tracked to be taken, got: ", err)
+	}
+	if err := clientdata.NewDataAccess("Sue", store, false).CreateBot("FRED"); err != clientdata.ErrClientExists {
+		t.Error("Expected a bot name that only differs in case to be taken, got: ", err)
+	}
+	if err := admin.BackfillLogins(); err != nil {
+		t.Error("Error backfilling again: ", err)
+	}
+}
+
+func TestDisplayNames(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	_ = df.Create("Fred").NewClient("pass")
+	_ = df.Create("jose").NewClient("pass")
+	jose := df.Create("jose")
+	if err := jose.SetDisplayName("José"); err != nil {
+		t.Fatal("Error setting display name: ", err)
+	}
+	if name, _ := jose.DisplayName(); name != "José" {
+		t.Errorf("Expected the display name to be normalized, got: %q", name)
+	}
+	var tests = []struct {
+		client string
+		name   string
+		err    error
+	}{
+		{"Fred", "JOSÉ", clientdata.ErrNameTaken},
+		{"Fred", "Jоse", clientdata.ErrInvalidDisplayName}, //mixes Cyrillic о
+		{"Fred", "Јоѕе", clientdata.ErrNameTaken},          //all Cyrillic
+		{"jose", "Fred", clientdata.ErrNameTaken},
+		{"Fred", "Frederick", nil},
+		{"Fred", "Fred", nil},
+	}
+	for _, tt := range tests {
+		if err := df.Create(tt.client).SetDisplayName(tt.name); err != tt.err {
+			t.Errorf("%v SetDisplayName(%q) => %v, want %v", tt.client, tt.name, err, tt.err)
+		}
+	}
+	if err := df.Create("Jose").NewClient("pass"); err != clientdata.ErrClientExists {
+		t.Error("Expected a login that looks like a display name to be taken, got: ", err)
+	}
+	_ = jose.SetDisplayName("")
+	if name, _ := jose.DisplayName(); name != "" {
+		t.Errorf("Expected the display name to be cleared, got: %q", name)
+	}
+}
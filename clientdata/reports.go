@@ -0,0 +1,167 @@
+package clientdata
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrReportNotFound = errors.New("clientdata: Report not found.")
+var ErrReportResolved = errors.New("clientdata: Report has already been resolved.")
+
+//Report is a client's report of another client for moderators to review.  Seq is the number of the reported message in Room, 0 if no message was given, and Context is the room's messages around it when the report was made.  Action, ResolvedBy, Resolved and Note are set when a moderator resolves it.
+type Report struct {
+	ID         string
+	Reporter   string
+	Reported   string
+	Room       string
+	Seq        int
+	Message    string
+	Context    []string
+	Reason     string
+	Created    time.Time
+	Action     string
+	ResolvedBy string
+	Resolved   time.Time
+	Note       string
+}
+
+//Open returns true if the report hasn't been resolved.
+func (r Report) Open() bool {
+	return r.ResolvedBy == ""
+}
+
+//String formats the report for display to moderators.  The reported message and its context are on the lines after the summary.
+func (r Report) String() string {
+	s := fmt.Sprintf("#%v %v reported %v in %v on %v: %v", r.ID, r.Reporter, r.Reported, r.Room, r.Created.Format("Jan 2 3:04pm"), r.Reason)
+	if !r.Open() {
+		s += fmt.Sprintf(" (%v by %v on %v", r.Action, r.ResolvedBy, r.Resolved.Format("Jan 2 3:04pm"))
+		if r.Note != "" {
+			s += ": " + r.Note
+		}
+		s += ")"
+	}
+	for _, line := range r.Context {
+		marker := "   "
+		if line == r.Message {
+			marker = " > "
+		}
+		s += "\r\n" + marker + line
+	}
+	return s
+}
+
+//AddReport saves a report of name by the client.  msg is the reported message and context the messages around it.
+func (cdd *DataAccess) AddReport(name, room string, seq int, msg string, context []string, reason string) (*Report, error) {
+	idb, err := randomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{ID: hex.EncodeToString(idb), Reporter: cdd.name, Reported: name, Room: room, Seq: seq, Message: msg, Context: context, Reason: reason, Created: time.Now()}
+	err = cdd.data.Add("reports", row("name", ServerRecord, "id", r.ID, "reporter", r.Reporter, "reported", r.Reported, "room", r.Room, "seq", strconv.Itoa(seq), "message", msg, "context", contextString(context), "reason", reason, "created", r.Created.Format(TimeLayout), "action", "", "resolvedby", "", "resolved", "", "note", ""))
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//FilterReporter is the reporter of messages flagged by the content filter.  It can't be a client's name.
+const FilterReporter = "@filter"
+
+//FlagMessage saves a report of name by the content filter for text they sent to room so moderators can review it.  room is empty for private messages.
+func (cdd *DataAccess) FlagMessage(name, room, text, reason string) (*Report, error) {
+	filter := &DataAccess{name: FilterReporter, data: cdd.data}
+	return filter.AddReport(name, room, 0, text, []string{text}, reason)
+}
+
+//Report returns the report id.  It will return ErrReportNotFound if there isn't one.
+func (cdd *DataAccess) Report(id string) (*Report, error) {
+	rows, err := cdd.data.Get("reports", row("name", ServerRecord, "id", id))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return nil, ErrReportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := readReport(rows[0])
+	return &r, nil
+}
+
+//Reports returns the reports sorted oldest first.  If open is true only the reports that haven't been resolved are returned.
+func (cdd *DataAccess) Reports(open bool) ([]Report, error) {
+	cond := row("name", ServerRecord)
+	if open {
+		cond["resolvedby"] = ""
+	}
+	rows, err := cdd.data.Get("reports", cond)
+	if err == ErrClientNotFound {
+		return []Report{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Report, len(rows))
+	for i := range rows {
+		list[i] = readReport(rows[i])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list, nil
+}
+
+//ResolveReport closes the report id with action, recording the client as the moderator who resolved it.  It will return ErrReportNotFound if there is no such report and ErrReportResolved if it was already resolved.
+func (cdd *DataAccess) ResolveReport(id, action, note string) (*Report, error) {
+	r, err := cdd.Report(id)
+	if err != nil {
+		return nil, err
+	}
+	if !r.Open() {
+		return nil, ErrReportResolved
+	}
+	r.Action, r.ResolvedBy, r.Resolved, r.Note = action, cdd.name, time.Now(), note
+	err = cdd.data.Set("reports", row("action", action, "resolvedby", cdd.name, "resolved", r.Resolved.Format(TimeLayout), "note", note), row("name", ServerRecord, "id", id))
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+//contextString returns context as a JSON array for storing.
+func contextString(context []string) string {
+	if len(context) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(context)
+	if err != nil {
+		log.Println("Error encoding report context: ", err)
+		return ""
+	}
+	return string(b)
+}
+
+//readContext reads context stored by contextString.  Reports saved before the context was stored as JSON have it on separate lines.
+func readContext(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var context []string
+	if strings.HasPrefix(s, "[") && json.Unmarshal([]byte(s), &context) == nil {
+		return context
+	}
+	return strings.Split(s, "\n")
+}
+
+//readReport converts a stored row to a Report.
+func readReport(r map[string]string) Report {
+	rep := Report{ID: r["id"], Reporter: r["reporter"], Reported: r["reported"], Room: r["room"], Message: r["message"], Reason: r["reason"], Action: r["action"], ResolvedBy: r["resolvedby"], Note: r["note"]}
+	rep.Seq, _ = strconv.Atoi(r["seq"])
+	rep.Context = readContext(r["context"])
+	rep.Created, _ = time.Parse(TimeLayout, r["created"])
+	rep.Resolved, _ = time.Parse(TimeLayout, r["resolved"])
+	return rep
+}
@@ -0,0 +1,87 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestReports(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	fred := df.Create("Fred")
+	context := []string{"1:00pm [Bob]: hi", "1:01pm [Spam]: buy now", "1:02pm [Bob]: no"}
+	first, err := fred.AddReport("Spam", "Lobby", 2, context[1], context, "spam")
+	if err != nil {
+		t.Fatal("Error adding report: ", err)
+	}
+	if _, err := fred.AddReport("Troll", "Games", 0, "", nil, "rude"); err != nil {
+		t.Fatal("Error adding report: ", err)
+	}
+	r, err := df.Create("Admin").Report(first.ID)
+	if err != nil {
+		t.Fatal("Error getting report: ", err)
+	}
+	if r.Reporter != "Fred" || r.Reported != "Spam" || r.Seq != 2 || r.Message != context[1] || len(r.Context) != 3 || r.Context[2] != context[2] || !r.Open() {
+		t.Errorf("Wrong report: %+v", r)
+	}
+	if open, _ := fred.Reports(true); len(open) != 2 || open[0].ID != first.ID {
+		t.Fatalf("Expected 2 open reports oldest first, got: %v", open)
+	}
+	admin := df.Create("Admin")
+	resolved, err := admin.ResolveReport(first.ID, "mute", "10m")
+	if err != nil {
+		t.Fatal("Error resolving report: ", err)
+	}
+	if resolved.ResolvedBy != "Admin" || resolved.Action != "mute" || resolved.Open() {
+		t.Errorf("Wrong resolved report: %+v", resolved)
+	}
+	if _, err := admin.ResolveReport(first.ID, "dismiss", ""); err != clientdata.ErrReportResolved {
+		t.Error("Expected ErrReportResolved resolving twice, got: ", err)
+	}
+	if _, err := admin.ResolveReport("missing", "dismiss", ""); err != clientdata.ErrReportNotFound {
+		t.Error("Expected ErrReportNotFound, got: ", err)
+	}
+	if open, _ := admin.Reports(true); len(open) != 1 || open[0].Reported != "Troll" {
+		t.Errorf("Expected only the unresolved report to be open, got: %v", open)
+	}
+	if all, _ := admin.Reports(false); len(all) != 2 || all[0].ResolvedBy != "Admin" {
+		t.Errorf("Expected every report with the resolution kept, got: %v", all)
+	}
+}
+
+func TestReportContextLines(t *testing.T) {
+	data := filedata.NewMemData()
+	fred := clientdata.NewDataAccess("Fred", data, false)
+	context := []string{"1:00pm [Bob]: hi", "1:01pm [Spam]: buy now\nreally cheap", "[Bob]: no"}
+	r, err := fred.AddReport("Spam", "Lobby", 2, context[1], context, "spam")
+	if err != nil {
+		t.Fatal("Error adding report: ", err)
+	}
+	if r, err = fred.Report(r.ID); err != nil {
+		t.Fatal("Error getting report: ", err)
+	}
+	if len(r.Context) != 3 || r.Context[1] != context[1] || r.Context[2] != context[2] {
+		t.Errorf("Expected a multi-line message to stay one line of context, got: %q", r.Context)
+	}
+	_ = data.Add("reports", map[string]string{"name": clientdata.ServerRecord, "id": "old", "reporter": "Fred", "reported": "Spam", "room": "Lobby", "seq": "0", "context": "1:00pm [Bob]: hi\n1:01pm [Spam]: buy now", "resolvedby": ""})
+	if r, err = fred.Report("old"); err != nil {
+		t.Fatal("Error getting report: ", err)
+	}
+	if len(r.Context) != 2 || r.Context[1] != "1:01pm [Spam]: buy now" {
+		t.Errorf("Expected context saved on separate lines to still be read, got: %q", r.Context)
+	}
+}
+
+func TestFlagMessage(t *testing.T) {
+	df := filedata.NewMemDataFactory()
+	if _, err := df.Create(clientdata.ServerRecord).FlagMessage("Spam", "Lobby", "buy now", "link"); err != nil {
+		t.Fatal("Error flagging message: ", err)
+	}
+	open, err := df.Create("Admin").Reports(true)
+	if err != nil {
+		t.Fatal("Error getting reports: ", err)
+	}
+	if len(open) != 1 || open[0].Reporter != clientdata.FilterReporter || open[0].Reported != "Spam" || open[0].Message != "buy now" || open[0].Reason != "link" {
+		t.Errorf("Expected the flagged message in the moderator queue, got: %v", open)
+	}
+}
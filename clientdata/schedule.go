@@ -0,0 +1,113 @@
+package clientdata
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+//Kinds of scheduled items.
+const (
+	ScheduleRemind = "remind" //a reminder sent to the owner as a tell
+	ScheduleRoom   = "room"   //a message sent to a room as the owner
+)
+
+var ErrScheduleNotFound = errors.New("clientdata: Scheduled item not found.")
+
+//ScheduledItem is a reminder or message waiting to be sent.  Target is the room for room messages and the owner for reminders.
+type ScheduledItem struct {
+	ID      string
+	Kind    string
+	Owner   string
+	Target  string
+	Text    string
+	At      time.Time
+	Created time.Time
+}
+
+//Mail is a message saved for a client that was offline.
+type Mail struct {
+	From string
+	Text string
+	Sent time.Time
+}
+
+//Schedule saves an item of kind to be sent to target at time at.
+func (cdd *DataAccess) Schedule(kind, target, text string, at time.Time) (*ScheduledItem, error) {
+	idb, err := randomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	item := &ScheduledItem{ID: hex.EncodeToString(idb), Kind: kind, Owner: cdd.name, Target: target, Text: text, At: at, Created: time.Now()}
+	err = cdd.data.Add("scheduled", row("name", ServerRecord, "id", item.ID, "kind", kind, "owner", cdd.name, "target", target, "text", text, "at", at.Format(TimeLayout), "created", item.Created.Format(TimeLayout)))
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+//ScheduledItems returns the items owned by owner sorted by when they will be sent.  If owner is empty it returns every item.
+func (cdd *DataAccess) ScheduledItems(owner string) ([]ScheduledItem, error) {
+	cond := row("name", ServerRecord)
+	if owner != "" {
+		cond["owner"] = owner
+	}
+	rows, err := cdd.data.Get("scheduled", cond)
+	if err == ErrClientNotFound {
+		return []ScheduledItem{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ScheduledItem, len(rows))
+	for i := range rows {
+		list[i] = ScheduledItem{ID: rows[i]["id"], Kind: rows[i]["kind"], Owner: rows[i]["owner"], Target: rows[i]["target"], Text: rows[i]["text"]}
+		list[i].At, err = time.Parse(TimeLayout, rows[i]["at"])
+		if err != nil {
+			return nil, err
+		}
+		list[i].Created, _ = time.Parse(TimeLayout, rows[i]["created"])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].At.Before(list[j].At) })
+	return list, nil
+}
+
+//Unschedule deletes the item id owned by this client.  It will return ErrScheduleNotFound if the client has no such item.
+func (cdd *DataAccess) Unschedule(id string) error {
+	exists, err := cdd.data.Exists("scheduled", row("name", ServerRecord, "id", id, "owner", cdd.name))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrScheduleNotFound
+	}
+	return cdd.data.Delete("scheduled", row("name", ServerRecord, "id", id, "owner", cdd.name))
+}
+
+//SendMail saves text from this client to be delivered to the client to when they next log in.
+func (cdd *DataAccess) SendMail(to, text string) error {
+	return cdd.data.Add("mailbox", row("name", to, "sender", cdd.name, "text", text, "sent", time.Now().Format(TimeLayout)))
+}
+
+//TakeMail returns the client's saved mail oldest first and removes it from the mailbox.
+func (cdd *DataAccess) TakeMail() ([]Mail, error) {
+	rows, err := cdd.data.Get("mailbox", row("name", cdd.name))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return []Mail{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Mail, len(rows))
+	for i := range rows {
+		list[i] = Mail{From: rows[i]["sender"], Text: rows[i]["text"]}
+		list[i].Sent, _ = time.Parse(TimeLayout, rows[i]["sent"])
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Sent.Before(list[j].Sent) })
+	err = cdd.data.Delete("mailbox", row("name", cdd.name))
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
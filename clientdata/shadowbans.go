@@ -0,0 +1,69 @@
+package clientdata
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrNotShadowbanned = errors.New("clientdata: They are not shadowbanned.")
+
+//Shadowban is a flag on an account that hides what it sends from everyone else without telling the owner.
+type Shadowban struct {
+	Name    string
+	Reason  string
+	By      string
+	Created time.Time
+}
+
+//String formats the shadowban for display to administrators.
+func (s Shadowban) String() string {
+	str := fmt.Sprintf("%v shadowbanned by %v on %v", s.Name, s.By, s.Created.Format("Jan 2 2006 3:04pm"))
+	if s.Reason != "" {
+		str += ": " + s.Reason
+	}
+	return str
+}
+
+//Shadowban shadowbans the account name.  Any existing shadowban on name is replaced.
+func (cdd *DataAccess) Shadowban(name, reason string) error {
+	if name == "" || !ValidateName(name) {
+		return ErrInvalidName
+	}
+	err := cdd.data.Delete("shadowbans", row("name", ServerRecord, "target", name))
+	if err != nil && err != ErrClientNotFound {
+		return err
+	}
+	return cdd.data.Add("shadowbans", row("name", ServerRecord, "target", name, "reason", reason, "bannedby", cdd.name, "created", time.Now().Format(TimeLayout)))
+}
+
+//Unshadowban lifts the shadowban on name.  It will return ErrNotShadowbanned if name isn't shadowbanned.
+func (cdd *DataAccess) Unshadowban(name string) error {
+	exists, err := cdd.data.Exists("shadowbans", row("name", ServerRecord, "target", name))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotShadowbanned
+	}
+	return cdd.data.Delete("shadowbans", row("name", ServerRecord, "target", name))
+}
+
+//Shadowbans returns the shadowbanned accounts sorted by name.
+func (cdd *DataAccess) Shadowbans() ([]Shadowban, error) {
+	rows, err := cdd.data.Get("shadowbans", row("name", ServerRecord))
+	if err == ErrClientNotFound {
+		return []Shadowban{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Shadowban, len(rows))
+	for i := range rows {
+		list[i] = Shadowban{Name: rows[i]["target"], Reason: rows[i]["reason"], By: rows[i]["bannedby"]}
+		list[i].Created, _ = time.Parse(TimeLayout, rows[i]["created"])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
@@ -0,0 +1,33 @@
+package clientdata_test
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"testing"
+)
+
+func TestShadowbans(t *testing.T) {
+	admin := filedata.NewMemDataFactory().Create("Admin")
+	if list, err := admin.Shadowbans(); err != nil || len(list) != 0 {
+		t.Fatalf("Expected no shadowbans, got: %v %v", list, err)
+	}
+	_ = admin.Shadowban("Spam", "links")
+	_ = admin.Shadowban("Bot", "")
+	_ = admin.Shadowban("Spam", "more links")
+	list, err := admin.Shadowbans()
+	if err != nil {
+		t.Fatal("Error getting shadowbans: ", err)
+	}
+	if len(list) != 2 || list[0].Name != "Bot" || list[1].Name != "Spam" || list[1].Reason != "more links" || list[1].By != "Admin" {
+		t.Errorf("Wrong shadowbans: %v", list)
+	}
+	if err := admin.Shadowban("not valid", ""); err != clientdata.ErrInvalidName {
+		t.Error("Expected ErrInvalidName, got: ", err)
+	}
+	if err := admin.Unshadowban("Spam"); err != nil {
+		t.Error("Error lifting shadowban: ", err)
+	}
+	if err := admin.Unshadowban("Spam"); err != clientdata.ErrNotShadowbanned {
+		t.Error("Expected ErrNotShadowbanned, got: ", err)
+	}
+}
@@ -0,0 +1,276 @@
+package sqlite
+
+//A SQLite implementation of datastore for use with clientdata.  Tables and columns are created the first time they are written to so new features don't need a schema change.
+
+import (
+	"database/sql"
+	"github.com/DavidAFox/Chat/clientdata"
+	_ "github.com/mattn/go-sqlite3"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//DEFAULTFILENAME is the name of the database file used if one is not provided.
+var DEFAULTFILENAME = "ClientData.db"
+
+//NewFactory creates a new clientdata factory using a SQLite datastore.
+func NewFactory(fileName string) (*Factory, error) {
+	cdf := new(Factory)
+	database, err := NewSQLite(fileName)
+	cdf.database = database
+	return cdf, err
+}
+
+//Factory is used to make client data objects backed by a SQLite database.
+type Factory struct {
+	database clientdata.DataStore
+}
+
+//Create returns a ClientData object of the type for the factory.
+func (cdf *Factory) Create(name string) clientdata.ClientData {
+	return clientdata.NewDataAccess(name, cdf.database, false)
+}
+
+//SQLite is a type of datastore using a SQLite database file.  Every column is text.
+type SQLite struct {
+	data    *sql.DB
+	lock    sync.Mutex
+	columns map[string]map[string]bool //columns of each table that exists, guarded by lock
+}
+
+//NewSQLite opens the database in fileName, creating it if it doesn't exist, and returns a SQLite datastore.
+func NewSQLite(fileName string) (*SQLite, error) {
+	if fileName == "" {
+		fileName = DEFAULTFILENAME
+	}
+	database, err := sql.Open("sqlite3", "file:"+fileName+"?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL")
+	if err != nil {
+		return nil, err
+	}
+	database.SetMaxOpenConns(1)
+	s := &SQLite{data: database, columns: make(map[string]map[string]bool)}
+	if err = s.loadSchema(); err != nil {
+		database.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+//Close closes the database.
+func (s *SQLite) Close() error {
+	return s.data.Close()
+}
+
+//quote returns name quoted for use as an identifier.
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+//loadSchema reads the tables and columns that already exist.
+func (s *SQLite) loadSchema() error {
+	rows, err := s.data.Query("SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return err
+	}
+	tables := make([]string, 0)
+	for rows.Next() {
+		var table string
+		if err = rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		cols, err := s.data.Query("SELECT name FROM pragma_table_info(?)", table)
+		if err != nil {
+			return err
+		}
+		s.columns[table] = make(map[string]bool)
+		for cols.Next() {
+			var col string
+			if err = cols.Scan(&col); err != nil {
+				cols.Close()
+				return err
+			}
+			s.columns[table][col] = true
+		}
+		cols.Close()
+		if err = cols.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//ensure creates table and adds any of the columns in values it doesn't have yet.
+func (s *SQLite) ensure(table string, values map[string]string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cols, ok := s.columns[table]
+	if !ok {
+		_, err := s.data.Exec("CREATE TABLE IF NOT EXISTS " + quote(table) + " (name TEXT NOT NULL DEFAULT '')")
+		if err != nil {
+			return err
+		}
+		_, err = s.data.Exec("CREATE INDEX IF NOT EXISTS " + quote(table+"_name") + " ON " + quote(table) + " (name)")
+		if err != nil {
+			return err
+		}
+		cols = map[string]bool{"name": true}
+		s.columns[table] = cols
+	}
+	for col := range values {
+		if cols[col] {
+			continue
+		}
+		_, err := s.data.Exec("ALTER TABLE " + quote(table) + " ADD COLUMN " + quote(col) + " TEXT NOT NULL DEFAULT ''")
+		if err != nil {
+			return err
+		}
+		cols[col] = true
+	}
+	return nil
+}
+
+//has returns true if table exists and has every column in values.  A row can't match a column that doesn't exist.
+func (s *SQLite) has(table string, values map[string]string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cols, ok := s.columns[table]
+	if !ok {
+		return false
+	}
+	for col := range values {
+		if !cols[col] {
+			return false
+		}
+	}
+	return true
+}
+
+//where returns a WHERE clause matching values and its arguments.  Columns are sorted so the same conditions make the same statement.
+func where(values map[string]string) (string, []interface{}) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	conds := make([]string, len(keys))
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		conds[i] = quote(k) + " = ?"
+		args[i] = values[k]
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+//Add adds row values to table.
+func (s *SQLite) Add(table string, values map[string]string) error {
+	if err := s.ensure(table, values); err != nil {
+		return err
+	}
+	cols := make([]string, 0, len(values))
+	marks := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values))
+	for col, v := range values {
+		cols = append(cols, quote(col))
+		marks = append(marks, "?")
+		args = append(args, v)
+	}
+	_, err := s.data.Exec("INSERT INTO "+quote(table)+" ("+strings.Join(cols, ", ")+") VALUES ("+strings.Join(marks, ", ")+")", args...)
+	return err
+}
+
+//Delete removes rows matching values from table.
+func (s *SQLite) Delete(table string, values map[string]string) error {
+	if !s.has(table, values) {
+		return nil
+	}
+	w, args := where(values)
+	_, err := s.data.Exec("DELETE FROM "+quote(table)+w, args...)
+	return err
+}
+
+//Get gets the columns from the table for the rows matching values.  All the columns are returned if none are given.
+func (s *SQLite) Get(table string, values map[string]string, columns ...string) ([]map[string]string, error) {
+	m := make([]map[string]string, 0)
+	if !s.has(table, values) {
+		return m, nil
+	}
+	sel := "*"
+	if len(columns) > 0 {
+		quoted := make([]string, len(columns))
+		for i := range columns {
+			quoted[i] = quote(columns[i])
+		}
+		sel = strings.Join(quoted, ", ")
+	}
+	w, args := where(values)
+	rows, err := s.data.Query("SELECT "+sel+" FROM "+quote(table)+w, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	col, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		st := make([]sql.NullString, len(col))
+		res := make([]interface{}, len(col))
+		for i := range res {
+			res[i] = &st[i]
+		}
+		if err = rows.Scan(res...); err != nil {
+			return nil, err
+		}
+		r := make(map[string]string, len(col))
+		for i := range col {
+			r[col[i]] = st[i].String
+		}
+		m = append(m, r)
+	}
+	return m, rows.Err()
+}
+
+//Set finds the rows matching cond in table and sets the column/value pairs in values.
+func (s *SQLite) Set(table string, values, cond map[string]string) error {
+	if !s.has(table, cond) {
+		return nil
+	}
+	if err := s.ensure(table, values); err != nil {
+		return err
+	}
+	sets := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values)+len(cond))
+	for col, v := range values {
+		sets = append(sets, quote(col)+" = ?")
+		args = append(args, v)
+	}
+	w, wargs := where(cond)
+	_, err := s.data.Exec("UPDATE "+quote(table)+" SET "+strings.Join(sets, ", ")+w, append(args, wargs...)...)
+	return err
+}
+
+//Exists takes a table to check and a map representing a row to compare to and returns true if there is a match in the database.
+func (s *SQLite) Exists(table string, values map[string]string) (bool, error) {
+	if !s.has(table, values) {
+		return false, nil
+	}
+	w, args := where(values)
+	var found int
+	err := s.data.QueryRow("SELECT EXISTS (SELECT 1 FROM "+quote(table)+w+")", args...).Scan(&found)
+	if err != nil {
+		return false, err
+	}
+	return found == 1, nil
+}
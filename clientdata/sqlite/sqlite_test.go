@@ -0,0 +1,90 @@
+package sqlite
+
+import (
+	"github.com/DavidAFox/Chat/clientdata"
+	"path/filepath"
+	"testing"
+)
+
+func TestDataStore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.db")
+	s, err := NewSQLite(file)
+	if err != nil {
+		t.Fatal("Error opening database: ", err)
+	}
+	if rows, err := s.Get("blocked", map[string]string{"name": "Fred"}); err != nil || len(rows) != 0 {
+		t.Errorf("Expected no rows from a table that doesn't exist yet, got: %v %v", rows, err)
+	}
+	if found, err := s.Exists("blocked", map[string]string{"name": "Fred"}); err != nil || found {
+		t.Errorf("Expected nothing to exist in a table that doesn't exist yet, got: %v %v", found, err)
+	}
+	_ = s.Add("blocked", map[string]string{"name": "Fred", "blocked": "Bob"})
+	_ = s.Add("blocked", map[string]string{"name": "Fred", "blocked": "Sue", "since": "today"})
+	rows, err := s.Get("blocked", map[string]string{"name": "Fred"})
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got: %v %v", rows, err)
+	}
+	if rows, _ = s.Get("blocked", map[string]string{"name": "Fred", "blocked": "Sue"}, "since"); len(rows) != 1 || rows[0]["since"] != "today" || len(rows[0]) != 1 {
+		t.Errorf("Expected only the since column of Sue's row, got: %v", rows)
+	}
+	if rows, _ = s.Get("blocked", map[string]string{"name": "Fred", "missing": "x"}); len(rows) != 0 {
+		t.Errorf("Expected no rows to match a column that doesn't exist, got: %v", rows)
+	}
+	if err = s.Set("blocked", map[string]string{"note": "spam"}, map[string]string{"name": "Fred", "blocked": "Bob"}); err != nil {
+		t.Fatal("Error setting new column: ", err)
+	}
+	if found, _ := s.Exists("blocked", map[string]string{"blocked": "Bob", "note": "spam"}); !found {
+		t.Error("Expected the updated row to exist")
+	}
+	if err = s.Delete("blocked", map[string]string{"name": "Fred", "blocked": "Bob"}); err != nil {
+		t.Fatal("Error deleting: ", err)
+	}
+	_ = s.Close()
+	s, err = NewSQLite(file)
+	if err != nil {
+		t.Fatal("Error reopening database: ", err)
+	}
+	defer s.Close()
+	if rows, _ = s.Get("blocked", map[string]string{"name": "Fred"}); len(rows) != 1 || rows[0]["blocked"] != "Sue" || rows[0]["note"] != "" {
+		t.Errorf("Expected Sue's row to be kept, got: %v", rows)
+	}
+}
+
+func TestClientData(t *testing.T) {
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal("Error opening database: ", err)
+	}
+	defer s.Close()
+	fred := clientdata.NewDataAccess("Fred", s, false)
+	if err = fred.NewClient("FredsPassword"); err != nil {
+		t.Fatal("Error creating client: ", err)
+	}
+	_ = clientdata.NewDataAccess("Bob", s, false).NewClient("BobsPassword")
+	if err = clientdata.NewDataAccess("fred", s, false).NewClient("pass"); err != clientdata.ErrClientExists {
+		t.Error("Expected the name to be taken, got: ", err)
+	}
+	if ok, err := fred.Authenticate("FredsPassword"); !ok || err != nil {
+		t.Errorf("Expected Fred to authenticate, got: %v %v", ok, err)
+	}
+	c, err := fred.Conversation([]string{"Bob"})
+	if err != nil {
+		t.Fatal("Error starting conversation: ", err)
+	}
+	if _, err = fred.AddDirectMessage(c.ID, "hi"); err != nil {
+		t.Fatal("Error sending direct message: ", err)
+	}
+	bob := clientdata.NewDataAccess("Bob", s, false)
+	if history, _ := bob.DirectMessages(c.ID, 0, 0); len(history) != 1 || history[0].Text != "hi" {
+		t.Errorf("Expected Bob to see the message, got: %v", history)
+	}
+	if err = bob.Block("Fred"); err != nil {
+		t.Fatal("Error blocking: ", err)
+	}
+	if blocked, _ := bob.IsBlocked("Fred"); !blocked {
+		t.Error("Expected Fred to be blocked")
+	}
+	if _, err = fred.AddDirectMessage(c.ID, "bye"); err != clientdata.ErrConversationBlocked {
+		t.Error("Expected the conversation to be blocked, got: ", err)
+	}
+}
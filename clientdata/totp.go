@@ -0,0 +1,242 @@
+package clientdata
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//TOTPIssuer is the issuer shown in authenticator apps for provisioning URIs.
+var TOTPIssuer = "Chat"
+
+const (
+	totpPeriod        = 30
+	totpDigits        = 6
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var ErrTOTPEnabled = errors.New("clientdata: Two-factor authentication is already enabled.")
+var ErrTOTPNotEnabled = errors.New("clientdata: Two-factor authentication is not enabled.")
+var ErrTOTPNotEnrolled = errors.New("clientdata: Two-factor authentication enrollment has not been started.")
+var ErrInvalidCode = errors.New("clientdata: Invalid two-factor code.")
+var ErrWrongPassword = errors.New("clientdata: Password does not match.")
+
+//Enrollment is returned when a client starts enrolling in two-factor authentication.
+type Enrollment struct {
+	URI           string
+	Secret        string
+	RecoveryCodes []string
+}
+
+//totpCode returns the RFC 6238 code for secret at counter.
+func totpCode(secret []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0xf
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+//GenerateTOTP returns the code for the base32 secret at time t.
+func GenerateTOTP(secret string, t time.Time) (string, error) {
+	key, err := base32NoPad.DecodeString(strings.TrimRight(strings.ToUpper(secret), "="))
+	if err != nil {
+		return "", err
+	}
+	return totpCode(key, totpCounter(t), totpDigits), nil
+}
+
+//totpCounter returns the time step for t.
+func totpCounter(t time.Time) uint64 {
+	return uint64(t.Unix()) / totpPeriod
+}
+
+var base32NoPad = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+//randomBytes returns n random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
+
+//hashCode returns the hex sha256 of a recovery code.  Recovery codes are random so they don't need a slow hash.
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+//normalizeCode removes spaces and dashes and lowercases a code entered by a user.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+//EnrollTOTP starts two-factor enrollment for the client.  It returns the provisioning URI and recovery codes.  Two-factor authentication isn't required until ConfirmTOTP is called with a valid code.
+func (cdd *DataAccess) EnrollTOTP() (*Enrollment, error) {
+	enabled, err := cdd.TOTPEnabled()
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTOTPEnabled
+	}
+	secret, err := randomBytes(20)
+	if err != nil {
+		return nil, err
+	}
+	e := new(Enrollment)
+	e.Secret = base32NoPad.EncodeToString(secret)
+	label := url.PathEscape(TOTPIssuer + ":" + cdd.name)
+	q := url.Values{"secret": {e.Secret}, "issuer": {TOTPIssuer}, "algorithm": {"SHA1"}, "digits": {strconv.Itoa(totpDigits)}, "period": {strconv.Itoa(totpPeriod)}}
+	e.URI = "otpauth://totp/" + label + "?" + q.Encode()
+	err = cdd.clearTOTP()
+	if err != nil {
+		return nil, err
+	}
+	err = cdd.data.Add("totp", row("name", cdd.name, "secret", e.Secret, "enabled", "false", "lastcounter", "0"))
+	if err != nil {
+		return nil, err
+	}
+	e.RecoveryCodes = make([]string, recoveryCodeCount)
+	for i := range e.RecoveryCodes {
+		b, err := randomBytes(5)
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32NoPad.EncodeToString(b))
+		e.RecoveryCodes[i] = code[:4] + "-" + code[4:]
+		err = cdd.data.Add("recoverycodes", row("name", cdd.name, "code", hashCode(code)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+//ConfirmTOTP turns on two-factor authentication if code is valid for the pending enrollment.
+func (cdd *DataAccess) ConfirmTOTP(code string) error {
+	res, err := cdd.getTOTP()
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return ErrTOTPNotEnrolled
+	}
+	if res["enabled"] == "true" {
+		return ErrTOTPEnabled
+	}
+	ok, err := cdd.checkTOTP(res, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidCode
+	}
+	return cdd.data.Set("totp", row("enabled", "true"), row("name", cdd.name))
+}
+
+//TOTPEnabled returns true if the client must enter a two-factor code to log in.
+func (cdd *DataAccess) TOTPEnabled() (bool, error) {
+	if cdd.name == "" {
+		return false, nil
+	}
+	return cdd.data.Exists("totp", row("name", cdd.name, "enabled", "true"))
+}
+
+//VerifyTOTP returns true if code is a current code from the client's authenticator or one of their unused recovery codes.  A recovery code is used up when it is verified.
+func (cdd *DataAccess) VerifyTOTP(code string) (bool, error) {
+	res, err := cdd.getTOTP()
+	if err != nil || res == nil || res["enabled"] != "true" {
+		return false, err
+	}
+	code = normalizeCode(code)
+	if len(code) == totpDigits {
+		return cdd.checkTOTP(res, code)
+	}
+	used, err := cdd.data.Exists("recoverycodes", row("name", cdd.name, "code", hashCode(code)))
+	if err != nil || !used {
+		return false, err
+	}
+	return true, cdd.data.Delete("recoverycodes", row("name", cdd.name, "code", hashCode(code)))
+}
+
+//DisableTOTP turns off two-factor authentication after confirming the client's password.
+func (cdd *DataAccess) DisableTOTP(pword string) error {
+	ok, err := cdd.Authenticate(pword)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrWrongPassword
+	}
+	enabled, err := cdd.TOTPEnabled()
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrTOTPNotEnabled
+	}
+	return cdd.clearTOTP()
+}
+
+//getTOTP returns the client's totp row or nil if there isn't one.
+func (cdd *DataAccess) getTOTP() (map[string]string, error) {
+	res, err := cdd.data.Get("totp", row("name", cdd.name))
+	switch {
+	case err == ErrClientNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case len(res) == 0:
+		return nil, nil
+	}
+	return res[0], nil
+}
+
+//checkTOTP returns true if code matches the secret in res within the allowed clock skew.  Codes can only be used once so the matching time step is saved.
+func (cdd *DataAccess) checkTOTP(res map[string]string, code string) (bool, error) {
+	secret, err := base32NoPad.DecodeString(res["secret"])
+	if err != nil {
+		return false, err
+	}
+	last, _ := strconv.ParseUint(res["lastcounter"], 10, 64)
+	now := totpCounter(time.Now())
+	for c := now - totpSkew; c <= now+totpSkew; c++ {
+		if c <= last {
+			continue
+		}
+		if hmac.Equal([]byte(totpCode(secret, c, totpDigits)), []byte(normalizeCode(code))) {
+			return true, cdd.data.Set("totp", row("lastcounter", strconv.FormatUint(c, 10)), row("name", cdd.name))
+		}
+	}
+	return false, nil
+}
+
+//clearTOTP removes the client's two-factor secret and recovery codes.
+func (cdd *DataAccess) clearTOTP() error {
+	for _, table := range []string{"totp", "recoverycodes"} {
+		err := cdd.data.Delete(table, row("name", cdd.name))
+		if err != nil && err != ErrClientNotFound {
+			return err
+		}
+	}
+	return nil
+}
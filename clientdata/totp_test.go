@@ -0,0 +1,41 @@
+package clientdata
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+//totpTests are the SHA1 test vectors from RFC 6238 appendix B.
+var totpTests = []struct {
+	unix int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestTOTPCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	for _, tt := range totpTests {
+		code := totpCode(secret, totpCounter(time.Unix(tt.unix, 0)), 8)
+		if code != tt.code {
+			t.Errorf("totpCode at %v => %v, want %v", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestGenerateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	code, err := GenerateTOTP(secret, time.Unix(59, 0))
+	if err != nil {
+		t.Fatal("Error in GenerateTOTP: ", err)
+	}
+	if code != "287082" {
+		t.Errorf("GenerateTOTP => %v, want %v", code, "287082")
+	}
+}
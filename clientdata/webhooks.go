@@ -0,0 +1,182 @@
+package clientdata
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrWebhookNotFound = errors.New("clientdata: Webhook not found.")
+var ErrInvalidWebhook = errors.New("clientdata: Invalid webhook URL.")
+
+//Webhook is an outgoing webhook subscription.  Events in Room are posted to URL and signed with Secret.
+type Webhook struct {
+	ID      string
+	Room    string
+	URL     string
+	Secret  string
+	By      string
+	Created time.Time
+}
+
+//ValidateWebhookURL returns true if u is an absolute http or https URL.
+func ValidateWebhookURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+//AddWebhook subscribes u to the events in room.  The returned Webhook includes the generated signing secret.
+func (cdd *DataAccess) AddWebhook(room, u string) (*Webhook, error) {
+	if room == "" || !ValidateName(room) {
+		return nil, ErrInvalidName
+	}
+	if !ValidateWebhookURL(u) {
+		return nil, ErrInvalidWebhook
+	}
+	idb, err := randomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomBytes(24)
+	if err != nil {
+		return nil, err
+	}
+	w := &Webhook{ID: hex.EncodeToString(idb), Room: room, URL: u, Secret: hex.EncodeToString(secret), By: cdd.name, Created: time.Now()}
+	err = cdd.data.Add("webhooks", row("name", ServerRecord, "id", w.ID, "room", w.Room, "url", w.URL, "secret", w.Secret, "createdby", w.By, "created", w.Created.Format(TimeLayout)))
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+//Webhooks returns the webhooks subscribed to room sorted by creation time.  If room is empty it returns every webhook.
+func (cdd *DataAccess) Webhooks(room string) ([]Webhook, error) {
+	cond := row("name", ServerRecord)
+	if room != "" {
+		cond["room"] = room
+	}
+	rows, err := cdd.data.Get("webhooks", cond)
+	if err == ErrClientNotFound {
+		return []Webhook{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Webhook, len(rows))
+	for i := range rows {
+		list[i] = Webhook{ID: rows[i]["id"], Room: rows[i]["room"], URL: rows[i]["url"], Secret: rows[i]["secret"], By: rows[i]["createdby"]}
+		list[i].Created, _ = time.Parse(TimeLayout, rows[i]["created"])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list, nil
+}
+
+//RemoveWebhook deletes the webhook id.  It will return ErrWebhookNotFound if there is no such webhook.
+func (cdd *DataAccess) RemoveWebhook(id string) error {
+	exists, err := cdd.data.Exists("webhooks", row("name", ServerRecord, "id", id))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWebhookNotFound
+	}
+	return cdd.data.Delete("webhooks", row("name", ServerRecord, "id", id))
+}
+
+//IncomingWebhook is a secret URL that posts messages into Room.  The token is only returned when it is created.
+type IncomingWebhook struct {
+	ID       string
+	Room     string
+	Username string
+	By       string
+	Created  time.Time
+}
+
+//AddIncomingWebhook creates an incoming webhook that posts into room as username.  It returns the token for the webhook's URL which is the only copy, only its hash is stored.
+func (cdd *DataAccess) AddIncomingWebhook(room, username string) (string, *IncomingWebhook, error) {
+	if room == "" || !ValidateName(room) {
+		return "", nil, ErrInvalidName
+	}
+	idb, err := randomBytes(4)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomBytes(24)
+	if err != nil {
+		return "", nil, err
+	}
+	w := &IncomingWebhook{ID: hex.EncodeToString(idb), Room: room, Username: username, By: cdd.name, Created: time.Now()}
+	token := w.ID + "." + hex.EncodeToString(secret)
+	err = cdd.data.Add("incomingwebhooks", row("name", ServerRecord, "id", w.ID, "hash", hashKey(token), "room", w.Room, "username", w.Username, "createdby", w.By, "created", w.Created.Format(TimeLayout)))
+	if err != nil {
+		return "", nil, err
+	}
+	return token, w, nil
+}
+
+//IncomingWebhooks returns the incoming webhooks for room sorted by creation time.  If room is empty it returns every incoming webhook.
+func (cdd *DataAccess) IncomingWebhooks(room string) ([]IncomingWebhook, error) {
+	cond := row("name", ServerRecord)
+	if room != "" {
+		cond["room"] = room
+	}
+	rows, err := cdd.data.Get("incomingwebhooks", cond)
+	if err == ErrClientNotFound {
+		return []IncomingWebhook{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list := make([]IncomingWebhook, len(rows))
+	for i := range rows {
+		list[i] = incomingWebhook(rows[i])
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.Before(list[j].Created) })
+	return list, nil
+}
+
+//RemoveIncomingWebhook deletes the incoming webhook id.  It will return ErrWebhookNotFound if there is no such webhook.
+func (cdd *DataAccess) RemoveIncomingWebhook(id string) error {
+	exists, err := cdd.data.Exists("incomingwebhooks", row("name", ServerRecord, "id", id))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrWebhookNotFound
+	}
+	return cdd.data.Delete("incomingwebhooks", row("name", ServerRecord, "id", id))
+}
+
+//AuthenticateIncomingWebhook returns the incoming webhook for token or nil if it isn't a valid token.
+func (cdd *DataAccess) AuthenticateIncomingWebhook(token string) (*IncomingWebhook, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, nil
+	}
+	rows, err := cdd.data.Get("incomingwebhooks", row("name", ServerRecord, "id", parts[0]))
+	if err == ErrClientNotFound || (err == nil && len(rows) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(rows[0]["hash"]), []byte(hashKey(token))) != 1 {
+		return nil, nil
+	}
+	w := incomingWebhook(rows[0])
+	return &w, nil
+}
+
+//incomingWebhook converts a row from the incomingwebhooks table.
+func incomingWebhook(r map[string]string) IncomingWebhook {
+	w := IncomingWebhook{ID: r["id"], Room: r["room"], Username: r["username"], By: r["createdby"]}
+	w.Created, _ = time.Parse(TimeLayout, r["created"])
+	return w
+}
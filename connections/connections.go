@@ -10,6 +10,16 @@ type Client interface {
 	SetConnection(Connection)
 }
 
+//Versioned is implemented by clients that can tell connections the version of a command's response so they can answer conditional requests without running it.  ETag returns an empty string if the response isn't versioned.
+type Versioned interface {
+	ETag(command []string) string
+}
+
+//Sharer is implemented by clients that hold files shared in their room to the same rules as messages.  CheckShare is called before the file is saved and returns the name to share it under or a response saying why it can't be shared.
+type Sharer interface {
+	CheckShare(filename string) (string, Response)
+}
+
 type Response interface {
 	Success() bool
 	Code() int
@@ -25,3 +35,8 @@ type Connection interface {
 	SendMessage(m message.Message)
 	Close()
 }
+
+//Addressed is implemented by connections that know the address of the user's end.  It is recorded with privileged actions in the audit log.
+type Addressed interface {
+	RemoteAddr() string
+}
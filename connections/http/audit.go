@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/DavidAFox/Chat/clientdata"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//AuditLog is the body of a response from Audit.  Intact is false if the log's hash chain is broken and Broken is the number of the first event that doesn't match.
+type AuditLog struct {
+	Events []clientdata.AuditEvent
+	Intact bool
+	Broken int
+}
+
+//Audit sends administrators the audit log's events newest first.  The actor, action, target, since and limit query parameters narrow them down.  since is an RFC 3339 time.
+func (h *RoomHandler) Audit(c *Connection, w http.ResponseWriter, rq *http.Request) {
+	data := h.datafactory.Create(c.name)
+	admin, err := data.IsAdmin()
+	if err != nil {
+		ServerError(w, err)
+		return
+	}
+	if !admin {
+		w.Header().Set("success", "false")
+		w.Header().Set("code", "71")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	query := rq.URL.Query()
+	q := clientdata.AuditQuery{Actor: query.Get("actor"), Action: query.Get("action"), Target: query.Get("target")}
+	if since := query.Get("since"); since != "" {
+		if q.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil || q.Limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	events, err := data.AuditLog(q)
+	if err != nil {
+		ServerError(w, err)
+		return
+	}
+	body := &AuditLog{Events: events, Intact: true}
+	body.Broken, err = data.VerifyAudit()
+	if err == clientdata.ErrAuditTampered {
+		body.Intact = false
+	} else if err != nil {
+		ServerError(w, err)
+		return
+	}
+	w.Header().Set("success", "true")
+	w.Header().Set("code", "0")
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Println("Error encoding in audit: ", err)
+	}
+}
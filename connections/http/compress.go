@@ -0,0 +1,127 @@
+package http
+
+import (
+	"compress/gzip"
+	"github.com/andybalholm/brotli"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//compressible are the content types worth compressing.  Images and archives are already compressed.
+var compressible = []string{"text/", "application/json", "application/javascript", "application/xml", "image/svg+xml"}
+
+//Compress wraps h so responses are compressed with brotli or gzip when the request's Accept-Encoding allows it.  WebSocket upgrades, HEAD requests and content that is already compressed are passed through unchanged.
+func Compress(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		encoding := acceptedEncoding(rq.Header.Get("Accept-Encoding"))
+		if encoding == "" || rq.Method == "HEAD" || strings.ToLower(rq.Header.Get("Upgrade")) == "websocket" {
+			h.ServeHTTP(w, rq)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		h.ServeHTTP(cw, rq)
+	})
+}
+
+//acceptedEncoding returns the encoding to use for a request with the Accept-Encoding header accept.  It prefers brotli then gzip and returns "" if neither is accepted.
+func acceptedEncoding(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				var err error
+				if q, err = strconv.ParseFloat(param[2:], 64); err != nil {
+					q = 0
+				}
+			}
+		}
+		if name == "*" {
+			name = "br"
+		}
+		if (name != "br" && name != "gzip") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+//compressWriter compresses what is written to it.  The headers are held until the first write so the content type is known before deciding whether to compress.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+	started  bool
+	enc      io.WriteCloser
+}
+
+//WriteHeader records the status to send with the headers on the first write.
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+//Write compresses b if the response is being compressed.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.started {
+		cw.start(b)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+//start decides whether to compress and sends the headers.  b is the first data written and is used to detect the content type when the handler didn't set one.
+func (cw *compressWriter) start(b []byte) {
+	cw.started = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	h := cw.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if h.Get("Content-Type") == "" && len(b) > 0 {
+		h.Set("Content-Type", http.DetectContentType(b))
+	}
+	if len(b) > 0 && cw.status != http.StatusNoContent && cw.status != http.StatusNotModified && h.Get("Content-Encoding") == "" && isCompressible(h.Get("Content-Type")) {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+		if cw.encoding == "br" {
+			cw.enc = brotli.NewWriterLevel(cw.ResponseWriter, 5)
+		} else {
+			cw.enc, _ = gzip.NewWriterLevel(cw.ResponseWriter, gzip.DefaultCompression)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+//Close sends the headers if nothing was written and finishes the compressed stream.
+func (cw *compressWriter) Close() error {
+	if !cw.started {
+		cw.start(nil)
+	}
+	if cw.enc != nil {
+		return cw.enc.Close()
+	}
+	return nil
+}
+
+//isCompressible returns true if contentType is worth compressing.
+func isCompressible(contentType string) bool {
+	for _, prefix := range compressible {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
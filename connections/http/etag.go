@@ -0,0 +1,26 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+//NotModified sets the ETag header to etag and returns true after writing a 304 Not Modified response if the request's If-None-Match header matches it.  etag is given without quotes.  Nothing is done for an empty etag.
+func NotModified(w http.ResponseWriter, rq *http.Request, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = `"` + etag + `"`
+	w.Header().Set("ETag", etag)
+	if rq.Method != "GET" && rq.Method != "HEAD" {
+		return false
+	}
+	for _, tag := range strings.Split(rq.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/connections"
 	"github.com/DavidAFox/Chat/connections/websocket"
+	"github.com/DavidAFox/Chat/files"
+	"github.com/DavidAFox/Chat/lockout"
 	"github.com/DavidAFox/Chat/message"
 	"github.com/DavidAFox/Chat/room"
 	gorilla "github.com/gorilla/websocket"
@@ -29,6 +31,8 @@ type Connection struct {
 	timeOut  *time.Timer
 	token    string
 	cMap     *ClientMap
+	name     string
+	addr     string
 }
 
 //New creates a new Connection and associated client.
@@ -39,6 +43,7 @@ func (h *RoomHandler) New(m *ClientMap, name string, roomlist *room.RoomList, ch
 	d := 5 * time.Minute
 	c.timeOut = time.AfterFunc(d, c.Close)
 	c.cMap = m
+	c.name = name
 	c.client = h.clientFactory.New(name, c)
 	return c
 }
@@ -51,14 +56,27 @@ type RoomHandler struct {
 	datafactory   clientdata.Factory
 	clientFactory connections.ClientFactory
 	origin        string
+	lockout       *lockout.Tracker
+	files         files.Store
+	maxUpload     int64
+	uploadTypes   map[string]bool
+	baseURL       string
+	signer        *files.Signer
 }
 
+//Options configures a RoomHandler.  Files can be nil to turn off uploads.  BaseURL is put in front of download links and should be the address clients use to reach the server.  Signer signs download links and upload tickets and should be shared by every RoomHandler serving the same files.  A new one is made if it is nil.
 type Options struct {
 	RoomList      *room.RoomList
 	ChatLog       io.Writer
 	DataFactory   clientdata.Factory
 	ClientFactory connections.ClientFactory
 	Origin        string
+	Lockout       *lockout.Tracker
+	Files         files.Store
+	MaxUploadSize int64
+	UploadTypes   []string
+	BaseURL       string
+	Signer        *files.Signer
 }
 
 //NewRoomHandler initializes and returns a new roomHandler.
@@ -76,11 +94,37 @@ func NewRoomHandler(options Options) *RoomHandler {
 	r.clients = NewClientMap()
 	r.datafactory = options.DataFactory
 	r.clientFactory = options.ClientFactory
+	if options.Lockout != nil {
+		r.lockout = options.Lockout
+	} else {
+		r.lockout = lockout.New(lockout.Options{})
+	}
 	if options.Origin != "" {
 		r.origin = options.Origin
 	} else {
 		r.origin = "*"
 	}
+	r.files = options.Files
+	r.maxUpload = options.MaxUploadSize
+	if r.maxUpload <= 0 {
+		r.maxUpload = DefaultMaxUpload
+	}
+	types := options.UploadTypes
+	if len(types) == 0 {
+		types = DefaultUploadTypes
+	}
+	r.uploadTypes = make(map[string]bool)
+	for _, t := range types {
+		r.uploadTypes[t] = true
+	}
+	r.baseURL = strings.TrimSuffix(options.BaseURL, "/")
+	r.signer = options.Signer
+	if r.signer == nil {
+		var err error
+		if r.signer, err = files.NewSigner(nil); err != nil {
+			panic(err)
+		}
+	}
 	return r
 }
 
@@ -116,6 +160,7 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 		w.Header().Add("Access-Control-Allow-Methods", "OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Authorization")
 		w.Header().Add("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Add("Access-Control-Allow-Headers", "If-None-Match")
 		w.Header().Set("Access-Control-Max-Age", "1728000")
 		w.Header().Set("Content-Type", "application/json")
 		return
@@ -123,6 +168,7 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", h.origin)
 	w.Header().Set("Access-Control-Expose-Headers", "Success")
 	w.Header().Add("Access-Control-Expose-Headers", "Code")
+	w.Header().Add("Access-Control-Expose-Headers", "ETag")
 	if strings.ToLower(rq.Header.Get("Upgrade")) == "websocket" {
 		upgrader := &gorilla.Upgrader{HandshakeTimeout: (time.Minute * 2), ReadBufferSize: 1024, WriteBufferSize: 1024, CheckOrigin: func(r *http.Request) bool { return r.Header.Get("Origin") == h.origin }}
 		socket, err := upgrader.Upgrade(w, rq, nil)
@@ -130,7 +176,11 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 			log.Println(err)
 			return
 		}
-		go websocket.Start(socket, &websocket.Options{RoomList: h.rooms, ClientFactory: h.clientFactory, DataFactory: h.datafactory, ChatLog: h.chl})
+		options := &websocket.Options{RoomList: h.rooms, ClientFactory: h.clientFactory, DataFactory: h.datafactory, ChatLog: h.chl, RemoteAddr: rq.RemoteAddr, Lockout: h.lockout}
+		if h.files != nil {
+			options.Uploads = h.signer
+		}
+		go websocket.Start(socket, options)
 		return
 	}
 	if len(path) < 2 {
@@ -141,6 +191,10 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 		h.Login(w, rq)
 	case "register":
 		h.Register(w, rq)
+	case "files":
+		h.Download(w, rq, path)
+	case "upload":
+		h.Upload(w, rq)
 	default:
 		if !h.CheckToken(rq) {
 			w.Header().Set("WWW-Authenticate", "token")
@@ -152,6 +206,14 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 			c.GetMessages(w, rq)
 			return
 		}
+		if path[1] == "update" {
+			c.Update(w, rq)
+			return
+		}
+		if path[1] == "audit" && rq.Method == "GET" {
+			h.Audit(c, w, rq)
+			return
+		}
 		com := make([]string, 1, 1)
 		com[0] = path[1]
 		args := make([]string, 0, 0)
@@ -161,10 +223,30 @@ func (h *RoomHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
 			log.Println(err)
 		}
 		com = append(com, args...)
+		if v, ok := c.client.(connections.Versioned); ok {
+			etag := v.ETag(com)
+			if etag != "" && rq.Header.Get("Data") == "simple" {
+				etag += "-simple"
+			}
+			w.Header().Set("Vary", "Authorization, Data")
+			if NotModified(w, rq, etag) {
+				return
+			}
+		}
 		resp := c.client.Execute(com) //do the stuff
 		w.Header().Set("success", strconv.FormatBool(resp.Success()))
 		w.Header().Set("code", strconv.Itoa(resp.Code()))
 		switch resp.Code() {
+		case 83:
+			if wait, ok := resp.Data().(int); ok {
+				w.Header().Set("Retry-After", strconv.Itoa(wait))
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			err := json.NewEncoder(w).Encode(resp.String())
+			if err != nil {
+				log.Println(err)
+			}
+			return
 		case 70:
 			w.WriteHeader(http.StatusNotFound)
 			return
@@ -235,6 +317,20 @@ func (h *RoomHandler) Register(w http.ResponseWriter, rq *http.Request) {
 		return
 	}
 	data := h.datafactory.Create(l[0])
+	if h.banned(w, data, "", rq) {
+		return
+	}
+	if wait := h.lockout.Register(rq.RemoteAddr); wait > 0 {
+		w.Header().Set("success", "false")
+		w.Header().Set("code", "83")
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		w.WriteHeader(http.StatusTooManyRequests)
+		err = json.NewEncoder(w).Encode("Too many new accounts.  Try again in " + lockout.WaitString(wait) + ".")
+		if err != nil {
+			log.Println("Error encoding in Register: ", err)
+		}
+		return
+	}
 	err = data.NewClient(l[1])
 	switch {
 	case err == clientdata.ErrClientExists:
@@ -285,6 +381,19 @@ func (h *RoomHandler) Login(w http.ResponseWriter, rq *http.Request) {
 		return
 	}
 	data := h.datafactory.Create(l[0])
+	if h.banned(w, data, "", rq) {
+		return
+	}
+	if wait := h.lockout.Check(l[0], rq.RemoteAddr); wait > 0 {
+		w.Header().Set("success", "false")
+		w.Header().Set("code", "24")
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		err = json.NewEncoder(w).Encode("Too many failed login attempts.  Try again in " + lockout.WaitString(wait) + ".")
+		if err != nil {
+			log.Println("Error encoding in login: ", err)
+		}
+		return
+	}
 	success, err = data.Authenticate(l[1])
 	if err != nil {
 		ServerError(w, err)
@@ -292,6 +401,13 @@ func (h *RoomHandler) Login(w http.ResponseWriter, rq *http.Request) {
 	}
 	enc := json.NewEncoder(w)
 	if success {
+		if !h.secondFactor(w, data, l, rq) {
+			return
+		}
+		h.lockout.Success(l[0], rq.RemoteAddr)
+		if h.banned(w, data, l[0], rq) {
+			return
+		}
 		if h.rooms.GetClient(l[0]) != nil {
 			w.Header().Set("success", "false")
 			w.Header().Set("code", "21")
@@ -303,12 +419,14 @@ func (h *RoomHandler) Login(w http.ResponseWriter, rq *http.Request) {
 		}
 		w.Header().Set("success", "true")
 		c := h.New(h.clients, l[0], h.rooms, h.chl, data)
+		c.addr = rq.RemoteAddr
 		c.cMap.Add(c)
 		err = enc.Encode(c.token)
 		if err != nil {
 			log.Println("Error encoding client token in login: ", err)
 		}
 	} else {
+		h.lockout.Failure(l[0], rq.RemoteAddr)
 		w.Header().Set("success", "false")
 		w.Header().Set("code", "21")
 		err = enc.Encode("User name and password don't match.")
@@ -319,6 +437,64 @@ func (h *RoomHandler) Login(w http.ResponseWriter, rq *http.Request) {
 
 }
 
+//secondFactor returns true if the account doesn't use two-factor authentication or the code in l[2] is valid.  Otherwise it writes the failure response.
+func (h *RoomHandler) secondFactor(w http.ResponseWriter, data clientdata.ClientData, l []string, rq *http.Request) bool {
+	enabled, err := data.TOTPEnabled()
+	if err != nil {
+		ServerError(w, err)
+		return false
+	}
+	if !enabled {
+		return true
+	}
+	enc := json.NewEncoder(w)
+	if len(l) < 3 || l[2] == "" {
+		w.Header().Set("success", "false")
+		w.Header().Set("code", "25")
+		err = enc.Encode("Two-factor code required.")
+		if err != nil {
+			log.Println("Error encoding in login: ", err)
+		}
+		return false
+	}
+	ok, err := data.VerifyTOTP(l[2])
+	if err != nil {
+		ServerError(w, err)
+		return false
+	}
+	if !ok {
+		h.lockout.Failure(l[0], rq.RemoteAddr)
+		w.Header().Set("success", "false")
+		w.Header().Set("code", "26")
+		err = enc.Encode("Invalid two-factor code.")
+		if err != nil {
+			log.Println("Error encoding in login: ", err)
+		}
+		return false
+	}
+	return true
+}
+
+//banned writes a banned response and returns true if the account name or the request's remote address is banned.  name may be empty to only check the address.
+func (h *RoomHandler) banned(w http.ResponseWriter, data clientdata.ClientData, name string, rq *http.Request) bool {
+	ban, err := data.CheckBan(name, rq.RemoteAddr)
+	if err != nil {
+		log.Println("Error checking ban: ", err)
+		return false
+	}
+	if ban == nil {
+		return false
+	}
+	w.Header().Set("success", "false")
+	w.Header().Set("code", "23")
+	enc := json.NewEncoder(w)
+	err = enc.Encode("You are banned from this server.")
+	if err != nil {
+		log.Println("Error encoding in banned: ", err)
+	}
+	return true
+}
+
 //ResetTimeOut resets the clients timeout timer.
 func (cl *Connection) ResetTimeOut() {
 	_ = cl.timeOut.Reset(5 * time.Minute)
@@ -346,19 +522,31 @@ func (cl *Connection) Close() {
 	_ = cl.timeOut.Stop()
 }
 
-//GetMessage gets all the messages for a client since the last time they were checked and then removes them from their message list.
-func (cl *Connection) GetMessages(w http.ResponseWriter, rq *http.Request) {
+//RemoteAddr returns the address the client logged in from.
+func (cl *Connection) RemoteAddr() string {
+	return cl.addr
+}
+
+//takeMessages removes and returns the messages waiting for the client.  Ephemeral messages that have expired are dropped.
+func (cl *Connection) takeMessages() []message.Message {
+	now := time.Now()
 	cl.messages.Lock()
-	m := make([]message.Message, cl.messages.Len(), cl.messages.Len())
-	for i, x := cl.messages.Front(), 0; i != nil; i, x = i.Next(), x+1 {
-		m[x] = i.Value.(message.Message)
-	}
+	m := make([]message.Message, 0, cl.messages.Len())
 	for i, x := cl.messages.Front(), cl.messages.Front(); i != nil; {
 		x = i
 		i = i.Next()
+		if e, ok := x.Value.(message.Ephemeral); !ok || !e.Expired(now) {
+			m = append(m, x.Value.(message.Message))
+		}
 		cl.messages.Remove(x)
 	}
 	cl.messages.Unlock()
+	return m
+}
+
+//GetMessage gets all the messages for a client since the last time they were checked and then removes them from their message list.
+func (cl *Connection) GetMessages(w http.ResponseWriter, rq *http.Request) {
+	m := cl.takeMessages()
 	w.Header().Set("success", "true")
 	enc := json.NewEncoder(w)
 	err := enc.Encode(m)
@@ -380,18 +568,7 @@ func (cl *Connection) Update(w http.ResponseWriter, rq *http.Request) {
 	for _, i := range requests {
 		switch i {
 		case "messages":
-			cl.messages.Lock()
-			m := make([]message.Message, cl.messages.Len(), cl.messages.Len())
-			for i, x := cl.messages.Front(), 0; i != nil; i, x = i.Next(), x+1 {
-				m[x] = i.Value.(message.Message)
-			}
-			for i, x := cl.messages.Front(), cl.messages.Front(); i != nil; {
-				x = i
-				i = i.Next()
-				cl.messages.Remove(x)
-			}
-			cl.messages.Unlock()
-			resp["messages"] = m
+			resp["messages"] = cl.takeMessages()
 		case "friendlist":
 			r := cl.client.Execute([]string{"friendlist"})
 			if r.Success() {
@@ -408,6 +585,14 @@ func (cl *Connection) Update(w http.ResponseWriter, rq *http.Request) {
 				failed = true
 				resp["who"] = "failed"
 			}
+		case "unread":
+			r := cl.client.Execute([]string{"unread"})
+			if r.Success() {
+				resp["unread"] = r.Data()
+			} else {
+				failed = true
+				resp["unread"] = "failed"
+			}
 		default:
 			resp[i] = "failed"
 			failed = true
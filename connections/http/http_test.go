@@ -2,14 +2,24 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"github.com/DavidAFox/Chat/client"
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/files"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
 	"github.com/DavidAFox/Chat/room"
+	"github.com/andybalholm/brotli"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestServeHTTPHandlesCORSOptionsRequest(t *testing.T) {
@@ -108,13 +118,108 @@ func TestLoginfailure(t *testing.T) {
 	checkHeadersPresent(w.Header(), expectedHeaders, t)
 }
 
+func TestLoginBannedAccount(t *testing.T) {
+	req, err := http.NewRequest("POST", "www.example.com/login", strings.NewReader("[\"Fred\", \"FredsPassword\"]"))
+	if err != nil {
+		t.Fatal("Error creating request in TestLoginBannedAccount: ", err)
+	}
+	w := httptest.NewRecorder()
+	wsh := newTestRoomHandler(t)
+	err = wsh.datafactory.Create("").Ban(clientdata.BanAccount, "Fred", "spam", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal("Error banning in TestLoginBannedAccount: ", err)
+	}
+	wsh.ServeHTTP(w, req)
+	expectedHeaders := []TestHeader{
+		{"Success", "false"},
+		{"Code", "23"},
+	}
+	checkHeadersPresent(w.Header(), expectedHeaders, t)
+}
+
+func TestRegisterBannedAddress(t *testing.T) {
+	req, err := http.NewRequest("POST", "www.example.com/register", strings.NewReader("[\"Bob\", \"BobsPassword\"]"))
+	if err != nil {
+		t.Fatal("Error creating request in TestRegisterBannedAddress: ", err)
+	}
+	req.RemoteAddr = "10.1.2.3:5000"
+	w := httptest.NewRecorder()
+	wsh := newTestRoomHandler(t)
+	err = wsh.datafactory.Create("").Ban(clientdata.BanAddress, "10.1.0.0/16", "", time.Time{})
+	if err != nil {
+		t.Fatal("Error banning in TestRegisterBannedAddress: ", err)
+	}
+	wsh.ServeHTTP(w, req)
+	expectedHeaders := []TestHeader{
+		{"Success", "false"},
+		{"Code", "23"},
+	}
+	checkHeadersPresent(w.Header(), expectedHeaders, t)
+}
+
+func TestLoginLockedOut(t *testing.T) {
+	wsh := newTestRoomHandler(t)
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("POST", "www.example.com/login", strings.NewReader("[\"Fred\", \"WrongPassword\"]"))
+		if err != nil {
+			t.Fatal("Error creating request in TestLoginLockedOut: ", err)
+		}
+		req.RemoteAddr = "10.1.2.3:5000"
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		if i == 1 {
+			expectedHeaders := []TestHeader{
+				{"Success", "false"},
+				{"Code", "24"},
+				{"Retry-After", "1"},
+			}
+			checkHeadersPresent(w.Header(), expectedHeaders, t)
+		}
+	}
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	wsh := newTestRoomHandler(t)
+	cd := wsh.datafactory.Create("Fred")
+	e, err := cd.EnrollTOTP()
+	if err != nil {
+		t.Fatal("Error enrolling in TestLoginTwoFactor: ", err)
+	}
+	code, err := clientdata.GenerateTOTP(e.Secret, time.Now())
+	if err != nil {
+		t.Fatal("Error generating code in TestLoginTwoFactor: ", err)
+	}
+	err = cd.ConfirmTOTP(code)
+	if err != nil {
+		t.Fatal("Error confirming in TestLoginTwoFactor: ", err)
+	}
+	logins := []struct {
+		body    string
+		headers []TestHeader
+	}{
+		{"[\"Fred\", \"FredsPassword\"]", []TestHeader{{"Success", "false"}, {"Code", "25"}}},
+		{"[\"Fred\", \"FredsPassword\", \"zzzz-zzzz\"]", []TestHeader{{"Success", "false"}, {"Code", "26"}}},
+		{"[\"Fred\", \"FredsPassword\", \"" + e.RecoveryCodes[0] + "\"]", []TestHeader{{"Success", "true"}}},
+	}
+	for _, tt := range logins {
+		req, err := http.NewRequest("POST", "www.example.com/login", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal("Error creating request in TestLoginTwoFactor: ", err)
+		}
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		checkHeadersPresent(w.Header(), tt.headers, t)
+		wsh.lockout.Success("Fred", "")
+	}
+}
+
 func newTestRoomHandler(t *testing.T) *RoomHandler {
 	factory, err := newTestMemDataFactory()
 	if err != nil {
 		t.Fatal("Error creating handler: ", err)
 	}
 	roomlist := room.NewRoomList(100)
-	wsh := NewRoomHandler(Options{Origin: "test origin", ChatLog: new(bytes.Buffer), RoomList: roomlist, DataFactory: factory, ClientFactory: client.NewFactory(roomlist, new(bytes.Buffer), factory)})
+	wsh := NewRoomHandler(Options{Origin: "test origin", ChatLog: new(bytes.Buffer), RoomList: roomlist, DataFactory: factory, ClientFactory: client.NewFactory(roomlist, new(bytes.Buffer), factory, client.Options{})})
 	return wsh
 }
 
@@ -124,3 +229,329 @@ func newTestMemDataFactory() (clientdata.Factory, error) {
 	err := cd.NewClient("FredsPassword")
 	return df, err
 }
+
+func TestTakeMessagesDropsExpiredTyping(t *testing.T) {
+	c := &Connection{messages: message.NewMessageList()}
+	c.SendMessage(message.NewTypingMessage("Bob", true, -time.Second))
+	c.SendMessage(message.NewSendMessage("hi", "Bob"))
+	c.SendMessage(message.NewTypingMessage("Sue", true, time.Minute))
+	m := c.takeMessages()
+	if len(m) != 2 {
+		t.Fatalf("Expected the expired typing message to be dropped, got: %v", m)
+	}
+	if typing, ok := m[1].(*message.TypingMessage); !ok || typing.Sender != "Sue" {
+		t.Errorf("Wrong message: %v", m[1])
+	}
+	if len(c.takeMessages()) != 0 {
+		t.Error("Expected takeMessages to empty the list")
+	}
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	factory, err := newTestMemDataFactory()
+	if err != nil {
+		t.Fatal("Error creating data: ", err)
+	}
+	store, err := files.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal("Error creating store: ", err)
+	}
+	roomlist := room.NewRoomList(100)
+	wsh := NewRoomHandler(Options{RoomList: roomlist, DataFactory: factory, ClientFactory: client.NewFactory(roomlist, new(bytes.Buffer), factory, client.Options{Limits: client.Limits{Uploads: ratelimit.Rate{PerMinute: -1}}}), Files: store, MaxUploadSize: 1024, BaseURL: "http://example.com/"})
+	fred := wsh.New(wsh.clients, "Fred", roomlist, nil, factory.Create("Fred"))
+	wsh.clients.Add(fred)
+	upload := func(filename, contents string) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		fw, _ := mw.CreateFormFile("file", filename)
+		_, _ = fw.Write([]byte(contents))
+		_ = mw.Close()
+		req := httptest.NewRequest("POST", "/upload", body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set("Authorization", fred.token)
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		return w
+	}
+	checkHeadersPresent(upload("evil.exe", "MZ\x90\x00\x03").Header(), []TestHeader{{"Success", "false"}, {"Code", "81"}}, t)
+	checkHeadersPresent(upload("big.txt", strings.Repeat("a", 2048)).Header(), []TestHeader{{"Success", "false"}, {"Code", "80"}}, t)
+	w := upload("notes.txt", "hello")
+	checkHeadersPresent(w.Header(), []TestHeader{{"Success", "true"}}, t)
+	var a *message.AttachmentMessage
+	for _, m := range fred.takeMessages() {
+		if msg, ok := m.(*message.AttachmentMessage); ok {
+			a = msg
+		}
+	}
+	if a == nil || a.Filename != "notes.txt" || a.MIME != "text/plain" || a.Size != 5 {
+		t.Fatalf("Expected an attachment message, got: %v", a)
+	}
+	if expected := "http://example.com/files/" + a.ID + "/notes.txt?sig="; !strings.HasPrefix(a.URL, expected) {
+		t.Errorf("Wrong URL expected: %v..., got: %v", expected, a.URL)
+	}
+	download := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, httptest.NewRequest("GET", "/files/"+a.ID+"/notes.txt?token="+token, nil))
+		return w
+	}
+	if w = download(fred.token); w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("Expected the file, got: %v %q", w.Code, w.Body.String())
+	}
+	if w = download(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a download without a token to be unauthorized, got: %v", w.Code)
+	}
+	fred.client.Execute([]string{"join", "Games"})
+	if w = download(fred.token); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a download from outside the room to be forbidden, got: %v", w.Code)
+	}
+	roomlist.FindRoom("Games").SetSlowMode(time.Hour)
+	checkHeadersPresent(upload("first.txt", "hello").Header(), []TestHeader{{"Success", "true"}}, t)
+	if w = upload("second.txt", "hello"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an upload in slow mode to wait, got: %v", w.Code)
+	}
+}
+
+//otherConnection is a connection that isn't HTTP, like a telnet or websocket connection.
+type otherConnection struct {
+	messages []message.Message
+}
+
+func (c *otherConnection) SendMessage(m message.Message) {
+	c.messages = append(c.messages, m)
+}
+
+func (c *otherConnection) Close() {
+}
+
+func TestUploadAndDownloadWithoutHTTP(t *testing.T) {
+	factory, err := newTestMemDataFactory()
+	if err != nil {
+		t.Fatal("Error creating data: ", err)
+	}
+	_ = factory.Create("Bob").NewClient("BobsPassword")
+	store, err := files.NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal("Error creating store: ", err)
+	}
+	roomlist := room.NewRoomList(100)
+	clients := client.NewFactory(roomlist, new(bytes.Buffer), factory, client.Options{})
+	wsh := NewRoomHandler(Options{RoomList: roomlist, DataFactory: factory, ClientFactory: clients, Files: store, BaseURL: "http://example.com/"})
+	conn := new(otherConnection)
+	client.New("Bob", clients, conn)
+	upload := func(ticket string) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		fw, _ := mw.CreateFormFile("file", "notes.txt")
+		_, _ = fw.Write([]byte("hello"))
+		_ = mw.Close()
+		req := httptest.NewRequest("POST", "/upload?ticket="+ticket, body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		return w
+	}
+	if w := upload("Bob.123.abc"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an upload with a forged ticket to be unauthorized, got: %v", w.Code)
+	}
+	checkHeadersPresent(upload(wsh.signer.Ticket("Bob", time.Minute)).Header(), []TestHeader{{"Success", "true"}}, t)
+	var a *message.AttachmentMessage
+	for _, m := range conn.messages {
+		if msg, ok := m.(*message.AttachmentMessage); ok {
+			a = msg
+		}
+	}
+	if a == nil || a.Sender != "Bob" {
+		t.Fatalf("Expected an attachment message from Bob, got: %v", a)
+	}
+	download := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+		return w
+	}
+	link := strings.TrimPrefix(a.URL, "http://example.com")
+	if w := download(link); w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("Expected the file from the signed link, got: %v %q", w.Code, w.Body.String())
+	}
+	tampered := link[:len(link)-1] + "0"
+	if strings.HasSuffix(link, "0") {
+		tampered = link[:len(link)-1] + "1"
+	}
+	if w := download(tampered); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a tampered link to be unauthorized, got: %v", w.Code)
+	}
+	if w := download("/files/" + a.ID + "/notes.txt?sig=" + wsh.signer.Download(a.ID, -time.Minute)); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an expired link to be unauthorized, got: %v", w.Code)
+	}
+}
+
+func TestListETag(t *testing.T) {
+	wsh := newTestRoomHandler(t)
+	fred := wsh.New(wsh.clients, "Fred", wsh.rooms, nil, wsh.datafactory.Create("Fred"))
+	wsh.clients.Add(fred)
+	list := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/list", nil)
+		req.Header.Set("Authorization", fred.token)
+		req.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		return w
+	}
+	w := list("")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a list with an ETag, got: %v %q", w.Code, etag)
+	}
+	if w = list(etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("Expected 304 for a matching ETag, got: %v %q", w.Code, w.Body.String())
+	}
+	fred.client.Execute([]string{"join", "Games"})
+	if w = list(etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("Expected a new list after joining a room, got: %v %q", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("[\"Lobby\"]", 100)
+	h := Compress(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+	var tests = []struct {
+		accept   string
+		encoding string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/list", nil)
+		req.Header.Set("Accept-Encoding", tt.accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if encoding := w.Header().Get("Content-Encoding"); encoding != tt.encoding {
+			t.Errorf("Accept-Encoding %q expected %q, got %q", tt.accept, tt.encoding, encoding)
+			continue
+		}
+		var r io.Reader = w.Body
+		switch tt.encoding {
+		case "gzip":
+			r, _ = gzip.NewReader(w.Body)
+		case "br":
+			r = brotli.NewReader(w.Body)
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil || string(got) != body {
+			t.Errorf("Accept-Encoding %q wrong body: %v", tt.accept, err)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	wsh := newTestRoomHandler(t)
+	_ = wsh.datafactory.Create("").AddAdmin("Fred")
+	_ = wsh.datafactory.Create("Bob").NewClient("BobsPassword")
+	_ = wsh.datafactory.Create("Sue").NewClient("SuesPassword")
+	fred := wsh.New(wsh.clients, "Fred", wsh.rooms, nil, wsh.datafactory.Create("Fred"))
+	fred.addr = "10.0.0.1:5000"
+	wsh.clients.Add(fred)
+	bob := wsh.New(wsh.clients, "Bob", wsh.rooms, nil, wsh.datafactory.Create("Bob"))
+	wsh.clients.Add(bob)
+	fred.client.Execute([]string{"slow", "10"})
+	fred.client.Execute([]string{"ban", "Sue", "1d", "spam"})
+	get := func(c *Connection, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/audit"+query, nil)
+		req.Header.Set("Authorization", c.token)
+		w := httptest.NewRecorder()
+		wsh.ServeHTTP(w, req)
+		return w
+	}
+	w := get(fred, "?action=ban")
+	checkHeadersPresent(w.Header(), []TestHeader{{"Success", "true"}}, t)
+	body := new(AuditLog)
+	if err := json.NewDecoder(w.Body).Decode(body); err != nil {
+		t.Fatal("Error decoding audit log: ", err)
+	}
+	if !body.Intact || len(body.Events) != 1 {
+		t.Fatalf("Expected one ban in an intact log, got: %+v", body)
+	}
+	if e := body.Events[0]; e.Actor != "Fred" || e.Target != "Sue" || e.Source != "10.0.0.1:5000" || e.Detail != "1d spam" || e.Seq != 2 {
+		t.Errorf("Wrong audit event: %+v", e)
+	}
+	if w = get(bob, ""); w.Code != http.StatusForbidden {
+		t.Errorf("Expected the audit log to be forbidden to non administrators, got: %v", w.Code)
+	}
+}
+
+func TestShadowban(t *testing.T) {
+	factory, err := newTestMemDataFactory()
+	if err != nil {
+		t.Fatal("Error creating data: ", err)
+	}
+	_ = factory.Create("").AddAdmin("Fred")
+	_ = factory.Create("Bob").NewClient("BobsPassword")
+	roomlist := room.NewRoomList(100)
+	clients := client.NewFactory(roomlist, new(bytes.Buffer), factory, client.Options{})
+	roomlist.SetShadow(clients.Shadowbans())
+	wsh := NewRoomHandler(Options{RoomList: roomlist, DataFactory: factory, ClientFactory: clients})
+	fred := wsh.New(wsh.clients, "Fred", roomlist, nil, factory.Create("Fred"))
+	wsh.clients.Add(fred)
+	bob := wsh.New(wsh.clients, "Bob", roomlist, nil, factory.Create("Bob"))
+	wsh.clients.Add(bob)
+	if r := fred.client.Execute([]string{"shadowban", "Bob", "spam"}); !r.Success() {
+		t.Fatalf("Error shadowbanning: %v", r)
+	}
+	fred.takeMessages()
+	bob.takeMessages()
+	lobby := roomlist.FindRoom("Lobby")
+	epoch, seq := lobby.Position()
+	var commands = [][]string{
+		{"send", "buy my stuff"},
+		{"tell", "Fred", "buy my stuff"},
+		{"dm", "Fred", "buy my stuff"},
+		{"topic", "buy my stuff"},
+		{"poll", "\"Buy my stuff?\"", "yes", "no"},
+	}
+	for _, command := range commands {
+		if r := bob.client.Execute(command); !r.Success() {
+			t.Errorf("Expected %v to seem to succeed, got: %v %v", command, r.Code(), r.String())
+		}
+		if m := bob.takeMessages(); len(m) == 0 {
+			t.Errorf("Expected Bob to see his own %v", command[0])
+		}
+		if m := fred.takeMessages(); len(m) != 0 {
+			t.Errorf("Expected %v from a shadowbanned client not to reach Fred, got: %v", command[0], m)
+		}
+	}
+	if lobby.Topic() != "" {
+		t.Errorf("Expected the topic to be unchanged, got: %q", lobby.Topic())
+	}
+	if n := lobby.Unread(epoch, seq); n != 0 {
+		t.Errorf("Expected nothing kept in the room, got %v messages", n)
+	}
+	conversations, err := factory.Create("Fred").Conversations()
+	if err != nil {
+		t.Fatal("Error getting conversations: ", err)
+	}
+	for _, c := range conversations {
+		if c.Count != 0 {
+			t.Errorf("Expected no messages saved for Fred, got: %+v", c)
+		}
+	}
+	if lobby.Poll() != nil {
+		t.Fatalf("Expected the shadowbanned poll not to be opened")
+	}
+	fred.client.Execute([]string{"poll", "\"Lunch?\"", "Pizza", "Tacos"})
+	fred.takeMessages()
+	bob.takeMessages()
+	if r := bob.client.Execute([]string{"vote", "1"}); !r.Success() {
+		t.Errorf("Expected a shadowbanned vote to seem to succeed, got: %v %v", r.Code(), r.String())
+	}
+	if m := fred.takeMessages(); len(m) != 0 {
+		t.Errorf("Expected a shadowbanned vote not to reach Fred, got: %v", m)
+	}
+	if p := lobby.Poll().Message(); p.Options[0].Votes != 0 {
+		t.Errorf("Expected a shadowbanned vote not to be counted, got: %+v", p.Options)
+	}
+}
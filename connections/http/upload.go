@@ -0,0 +1,233 @@
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/connections"
+	"github.com/DavidAFox/Chat/files"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//DefaultMaxUpload is the largest file in bytes that can be uploaded if Options doesn't set one.
+const DefaultMaxUpload = 10 << 20
+
+//DownloadLinkLifetime is how long the download links posted to rooms work for.  After that only HTTP clients in the file's room can download it.
+const DownloadLinkLifetime = 7 * 24 * time.Hour
+
+//DefaultUploadTypes are the MIME types that can be uploaded if Options doesn't set them.
+var DefaultUploadTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain", "application/zip"}
+
+//uploadError writes a failed response with code and text.
+func uploadError(w http.ResponseWriter, status, code int, text string) {
+	w.Header().Set("success", "false")
+	w.Header().Set("code", strconv.Itoa(code))
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(text)
+	if err != nil {
+		log.Println("Error encoding in upload: ", err)
+	}
+}
+
+//refuseUpload writes the response from a client that can't share a file right now.  Clients that are sending too fast are told when to try again.
+func refuseUpload(w http.ResponseWriter, resp connections.Response) {
+	status := http.StatusOK
+	if wait, ok := resp.Data().(int); ok && resp.Code() == 83 {
+		w.Header().Set("Retry-After", strconv.Itoa(wait))
+		status = http.StatusTooManyRequests
+	}
+	uploadError(w, status, resp.Code(), resp.String())
+}
+
+//uploader returns the name of the client uploading a file and the client if it checks shares.  HTTP clients send their token and clients connected another way send an upload ticket in the ticket query parameter.  ok is false if neither is valid.
+func (h *RoomHandler) uploader(rq *http.Request) (name string, sharer connections.Sharer, ok bool) {
+	if c := h.GetConnection(rq); c != nil {
+		sharer, _ = c.client.(connections.Sharer)
+		return c.name, sharer, true
+	}
+	name = h.signer.CheckTicket(rq.URL.Query().Get("ticket"))
+	if name == "" {
+		return "", nil, false
+	}
+	cl := h.rooms.GetClient(name)
+	if cl == nil {
+		return "", nil, false
+	}
+	sharer, _ = cl.(connections.Sharer)
+	return name, sharer, true
+}
+
+//Upload saves the file in the "file" field of a multipart POST and shares it in the client's room.  The file's type is detected from its contents and must be one of the allowed upload types.  Clients that check shares can refuse it before it is saved, as when they are muted.
+func (h *RoomHandler) Upload(w http.ResponseWriter, rq *http.Request) {
+	name, sharer, ok := h.uploader(rq)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "token")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if rq.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		uploadError(w, http.StatusMethodNotAllowed, 60, "Unsupported Method: Use POST to upload.")
+		return
+	}
+	if h.files == nil {
+		uploadError(w, http.StatusNotFound, 70, "File uploads are disabled.")
+		return
+	}
+	rm := h.rooms.FindRoom(h.rooms.FindClientRoom(name))
+	if rm == nil {
+		uploadError(w, http.StatusOK, 40, "You are not in a room.")
+		return
+	}
+	rq.Body = http.MaxBytesReader(w, rq.Body, h.maxUpload+1<<20)
+	mr, err := rq.MultipartReader()
+	if err != nil {
+		uploadError(w, http.StatusBadRequest, 70, "Expected a multipart form with a file.")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			uploadError(w, http.StatusBadRequest, 70, "Expected a multipart form with a file.")
+			return
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			filename := path.Base(strings.Replace(part.FileName(), "\\", "/", -1))
+			if sharer != nil {
+				var resp connections.Response
+				if filename, resp = sharer.CheckShare(filename); resp != nil {
+					refuseUpload(w, resp)
+					return
+				}
+			}
+			h.saveUpload(name, rm, filename, part, w)
+			return
+		}
+	}
+}
+
+//saveUpload stores the file read from r and posts it to rm.
+func (h *RoomHandler) saveUpload(name string, rm *room.Room, filename string, r io.Reader, w http.ResponseWriter) {
+	br := bufio.NewReaderSize(r, 512)
+	head, _ := br.Peek(512)
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !h.uploadTypes[mimeType] {
+		uploadError(w, http.StatusUnsupportedMediaType, 81, "That type of file can't be uploaded.")
+		return
+	}
+	id, size, err := h.files.Save(br, h.maxUpload)
+	if err == files.ErrTooLarge {
+		uploadError(w, http.StatusRequestEntityTooLarge, 80, fmt.Sprintf("Files can't be larger than %v MB.", h.maxUpload>>20))
+		return
+	}
+	if err != nil {
+		ServerError(w, err)
+		return
+	}
+	data := h.datafactory.Create(name)
+	if _, err = data.AddAttachment(id, rm.Name(), filename, mimeType, size); err != nil {
+		_ = h.files.Delete(id)
+		ServerError(w, err)
+		return
+	}
+	msg := message.NewAttachmentMessage(id, filename, mimeType, size, h.fileURL(id, filename), name)
+	if msg.Display, err = data.DisplayName(); err != nil {
+		log.Println("Error DisplayName: ", err)
+	}
+	if h.chl != nil {
+		if _, err = io.WriteString(h.chl, msg.String()+"\n"); err != nil {
+			log.Println(err)
+		}
+	}
+	rm.Send(msg)
+	w.Header().Set("success", "true")
+	w.Header().Set("code", "0")
+	err = json.NewEncoder(w).Encode(&Response{Data: msg, String: msg.String()})
+	if err != nil {
+		log.Println("Error encoding in upload: ", err)
+	}
+}
+
+//fileURL returns the download URL of file id.  It carries a signature so it works for DownloadLinkLifetime without an HTTP session.
+func (h *RoomHandler) fileURL(id, filename string) string {
+	return h.baseURL + "/files/" + id + "/" + url.PathEscape(filename) + "?sig=" + h.signer.Download(id, DownloadLinkLifetime)
+}
+
+//Download sends the file in parts[2] to anyone with a signed link to it from fileURL or to an HTTP client that is in the room it was shared in.  The token can be given in the Authorization header or the token query parameter so links work in a browser.
+func (h *RoomHandler) Download(w http.ResponseWriter, rq *http.Request, parts []string) {
+	if rq.Method != "GET" && rq.Method != "HEAD" {
+		w.Header().Set("Allow", "GET")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	signed := len(parts) > 2 && h.signer.CheckDownload(parts[2], rq.URL.Query().Get("sig"))
+	var c *Connection
+	if !signed {
+		token := rq.Header.Get("Authorization")
+		if token == "" {
+			token = rq.URL.Query().Get("token")
+		}
+		c = h.clients.Get(token)
+		if token == "" || c == nil {
+			w.Header().Set("WWW-Authenticate", "token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		c.ResetTimeOut()
+	}
+	if h.files == nil || len(parts) < 3 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	a, err := h.datafactory.Create("").Attachment(parts[2])
+	if err == clientdata.ErrAttachmentNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		ServerError(w, err)
+		return
+	}
+	if !signed {
+		rm := h.rooms.FindRoom(a.Room)
+		if rm == nil || !rm.Present(c.name) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+	f, err := h.files.Open(a.ID)
+	if err == files.ErrNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		ServerError(w, err)
+		return
+	}
+	defer f.Close()
+	disposition := "attachment"
+	if strings.HasPrefix(a.MIME, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", a.MIME)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if rq.Method == "HEAD" {
+		return
+	}
+	if _, err = io.Copy(w, f); err != nil {
+		log.Println("Error sending file: ", err)
+	}
+}
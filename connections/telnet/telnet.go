@@ -7,6 +7,7 @@ Package telnet provides a connection implementation for use with the client pack
 import (
 	"github.com/DavidAFox/Chat/client"
 	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/lockout"
 	"github.com/DavidAFox/Chat/message"
 	"github.com/DavidAFox/Chat/room"
 	"io"
@@ -23,15 +24,18 @@ type Connection struct {
 }
 
 //New creates a new connection and associated client.
-func New(name string, roomlist *room.RoomList, chatlog io.Writer, data clientdata.ClientData, conn net.Conn) *Connection {
+func New(name string, clients *client.Factory, conn net.Conn) *Connection {
 	c := new(Connection)
 	c.conn = conn
-	c.client = client.New(name, roomlist, chatlog, data, c)
+	c.client = client.New(name, clients, c)
 	return c
 }
 
-//SendMessage is used by the client package to forward messages to the connection so they can be send to the user.  This version appends a \r\n and sends the message out the conn.
+//SendMessage is used by the client package to forward messages to the connection so they can be send to the user.  This version appends a \r\n and sends the message out the conn.  Ephemeral messages like typing indicators are dropped.
 func (c *Connection) SendMessage(m message.Message) {
+	if _, ok := m.(message.Ephemeral); ok {
+		return
+	}
 	_, err := io.WriteString(c.conn, m.String()+"\r\n")
 	if err != nil {
 		log.Println(err)
@@ -39,6 +43,11 @@ func (c *Connection) SendMessage(m message.Message) {
 	}
 }
 
+//RemoteAddr returns the address of the user's end of the connection.
+func (c *Connection) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
 //Close closes out the telnet connection.
 func (c *Connection) Close() {
 	c.conn.Close()
@@ -72,8 +81,8 @@ func readString(conn net.Conn) (string, error) {
 	return strings.TrimSuffix(ip, "\r"), err
 }
 
-//TelnetRegister is used to create new accounts using a telnet connection.
-func TelnetRegister(conn net.Conn, cd clientdata.ClientData) {
+//TelnetRegister is used to create new accounts using a telnet connection.  New accounts from one address are limited by guard which may be nil.
+func TelnetRegister(conn net.Conn, cd clientdata.ClientData, guard *lockout.Tracker) {
 	for {
 		name := getInput(conn, "Enter Name.")
 		if clientdata.ValidateName(name) {
@@ -88,6 +97,13 @@ func TelnetRegister(conn net.Conn, cd clientdata.ClientData) {
 					pword1 = getInput(conn, "Passwords don't match. Enter Password.")
 					pword2 = getInput(conn, "Please enter Password again.")
 				}
+				if wait := guard.Register(conn.RemoteAddr().String()); wait > 0 {
+					_, err = io.WriteString(conn, "Too many new accounts.  Try again in "+lockout.WaitString(wait)+".\n\r")
+					if err != nil {
+						log.Println("Error Writing in TelnetRegister", err)
+					}
+					return
+				}
 				cd.SetName(name)
 				err := cd.NewClient(pword1)
 				if err == clientdata.ErrAccountCreationDisabled {
@@ -134,28 +150,85 @@ func getInput(conn net.Conn, text string) string {
 	return response
 }
 
-//TelnetLogin is used to initiate clients.
-func TelnetLogin(conn net.Conn, rooms *room.RoomList, chl io.Writer, cd clientdata.ClientData) {
+//banned returns true and closes the connection if the account name or the connection's address is banned.
+func banned(conn net.Conn, cd clientdata.ClientData, name string) bool {
+	ban, err := cd.CheckBan(name, conn.RemoteAddr().String())
+	if err != nil {
+		log.Println("Error checking ban: ", err)
+		return false
+	}
+	if ban == nil {
+		return false
+	}
+	_, err = io.WriteString(conn, "You are banned from this server.\r\n")
+	if err != nil {
+		log.Println("Error Writing: ", err)
+	}
+	conn.Close()
+	return true
+}
+
+//secondFactor asks for a two-factor code if the account uses one and returns true if it is valid or not needed.
+func secondFactor(conn net.Conn, cd clientdata.ClientData, guard *lockout.Tracker, name string) bool {
+	enabled, err := cd.TOTPEnabled()
+	if err != nil {
+		log.Println("Error checking two-factor: ", err)
+		return false
+	}
+	if !enabled {
+		return true
+	}
+	code := getInput(conn, "Enter two-factor code.")
+	ok, err := cd.VerifyTOTP(code)
+	if err != nil {
+		log.Println("Error verifying two-factor: ", err)
+		return false
+	}
+	if !ok {
+		guard.Failure(name, conn.RemoteAddr().String())
+		_, err = io.WriteString(conn, "Invalid two-factor code.\n\r")
+		if err != nil {
+			log.Println("Error Writing: ", err)
+		}
+	}
+	return ok
+}
+
+//TelnetLogin is used to initiate clients made by clients.  Failed logins are recorded in guard which may be nil.
+func TelnetLogin(conn net.Conn, rooms *room.RoomList, clients *client.Factory, cd clientdata.ClientData, guard *lockout.Tracker) {
 	logged := false
 	var name string
 	var err error
 	for !logged {
 		name = getInput(conn, "Enter Name or /new to create a new account.")
 		if name == "/new" {
-			TelnetRegister(conn, cd)
+			TelnetRegister(conn, cd, guard)
 		} else if clientdata.ValidateName(name) {
 			cd.SetName(name)
 			pword := getInput(conn, "Enter Password.")
+			if wait := guard.Check(name, conn.RemoteAddr().String()); wait > 0 {
+				io.WriteString(conn, "Too many failed login attempts.  Try again in "+lockout.WaitString(wait)+".\n\r")
+				continue
+			}
 			logged, err = cd.Authenticate(pword)
 			if err != nil {
 				log.Println("Error Autheticating: ", err)
 			} else {
 				if logged == false {
+					guard.Failure(name, conn.RemoteAddr().String())
 					io.WriteString(conn, "User name and Password do not match.\n\r")
-				} else if rooms.GetClient(name) != nil {
-					io.WriteString(conn, "That user is already logged in.\n\r")
+				} else if !secondFactor(conn, cd, guard, name) {
 					logged = false
-				} 
+				} else {
+					guard.Success(name, conn.RemoteAddr().String())
+					if banned(conn, cd, name) {
+						return
+					}
+					if rooms.GetClient(name) != nil {
+						io.WriteString(conn, "That user is already logged in.\n\r")
+						logged = false
+					}
+				}
 			}
 		} else {
 			_, err = io.WriteString(conn, "Invalid name.  Name must be alphanumeric characters only.")
@@ -164,7 +237,7 @@ func TelnetLogin(conn net.Conn, rooms *room.RoomList, chl io.Writer, cd clientda
 			}
 		}
 	}
-	c := New(name, rooms, chl, cd, conn)
+	c := New(name, clients, conn)
 	_, err = io.WriteString(conn, "Welcome\r\n")
 	if err != nil {
 		log.Println("Error Wrting: ", err)
@@ -6,11 +6,14 @@ import (
 	"errors"
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/connections"
+	"github.com/DavidAFox/Chat/files"
+	"github.com/DavidAFox/Chat/lockout"
 	"github.com/DavidAFox/Chat/message"
 	"github.com/DavidAFox/Chat/room"
 	"io"
 	"log"
 	"sync"
+	"time"
 )
 
 const TEXT_MESSAGE = 1
@@ -18,6 +21,15 @@ const USER_NAME_PWRD_DONT_MATCH = 21
 const SERVER_ERROR = 50
 const CLIENT_ALREADY_EXISTS = 10
 const INVALID_NAME = 20
+const BANNED = 23
+const LOCKED_OUT = 24
+const TWO_FACTOR_REQUIRED = 25
+const INVALID_TWO_FACTOR = 26
+const THROTTLED = 83
+const UPLOADS_DISABLED = 70
+
+//UploadTicketLifetime is how long an upload ticket from the uploadticket command can be used for.
+const UploadTicketLifetime = 5 * time.Minute
 
 var ERR_NOT_LOGIN = errors.New("You are not logged in.")
 
@@ -25,6 +37,9 @@ type Connection struct {
 	client    connections.Client
 	socket    Socket
 	writeLock *sync.Mutex
+	addr      string
+	name      string
+	uploads   *files.Signer
 }
 
 func New(client connections.Client, socket Socket) *Connection {
@@ -37,15 +52,23 @@ func New(client connections.Client, socket Socket) *Connection {
 	return c
 }
 
-func NewWithNewClient(factory connections.ClientFactory, name string, socket Socket) *Connection {
+//NewWithNewClient creates a connection for the socket from addr and a new client for it.
+func NewWithNewClient(factory connections.ClientFactory, name string, socket Socket, addr string) *Connection {
 	c := new(Connection)
 	c.socket = socket
 	c.writeLock = new(sync.Mutex)
+	c.addr = addr
+	c.name = name
 	c.client = factory.New(name, c)
 	go c.inputHandler()
 	return c
 }
 
+//RemoteAddr returns the address the socket was opened from.
+func (con *Connection) RemoteAddr() string {
+	return con.addr
+}
+
 func (con *Connection) Close() {
 	con.client.LeaveRoom()
 	con.socket.Close()
@@ -97,6 +120,13 @@ func Login(socket Socket, options *Options, cmd *Input) bool {
 	name := cmd.Args[0]
 	pwrd := cmd.Args[1]
 	cd := options.DataFactory.Create(name)
+	if banned(socket, options, cd, "Login", "") {
+		return false
+	}
+	if wait := options.Lockout.Check(name, options.RemoteAddr); wait > 0 {
+		_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: LOCKED_OUT, Data: "Too many failed login attempts.  Try again in " + lockout.WaitString(wait) + "."})
+		return false
+	}
 	logged, err := cd.Authenticate(pwrd)
 	if err != nil {
 		log.Println(err)
@@ -104,9 +134,17 @@ func Login(socket Socket, options *Options, cmd *Input) bool {
 		return false
 	}
 	if !logged {
+		options.Lockout.Failure(name, options.RemoteAddr)
 		_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: USER_NAME_PWRD_DONT_MATCH, Data: "User name and password do not match."})
 		return false
 	}
+	if !secondFactor(socket, options, cd, cmd) {
+		return false
+	}
+	options.Lockout.Success(name, options.RemoteAddr)
+	if banned(socket, options, cd, "Login", name) {
+		return false
+	}
 	if options.RoomList.GetClient(name) != nil {
 		_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: 0, Data: "That user is already logged in."})
 		return false
@@ -116,7 +154,9 @@ func Login(socket Socket, options *Options, cmd *Input) bool {
 		log.Println(err)
 		return false
 	}
-	NewWithNewClient(options.ClientFactory, name, socket)
+	c := &Connection{socket: socket, writeLock: new(sync.Mutex), addr: options.RemoteAddr, name: name, uploads: options.Uploads}
+	c.client = options.ClientFactory.New(name, c)
+	go c.inputHandler()
 	return true
 }
 
@@ -132,6 +172,13 @@ func Register(socket Socket, options *Options, cmd *Input) {
 		_ = sendMessage(socket, &Message{Type: "Register", Success: false, Code: INVALID_NAME, Data: "Invalid name.  Name can only contain alpha numeric characters."})
 		return
 	}
+	if banned(socket, options, cd, "Register", "") {
+		return
+	}
+	if wait := options.Lockout.Register(options.RemoteAddr); wait > 0 {
+		_ = sendMessage(socket, &Message{Type: "Register", Success: false, Code: THROTTLED, Data: "Too many new accounts.  Try again in " + lockout.WaitString(wait) + "."})
+		return
+	}
 	err := cd.NewClient(pwrd)
 	switch {
 	case err == clientdata.ErrClientExists:
@@ -149,6 +196,44 @@ func Register(socket Socket, options *Options, cmd *Input) {
 
 }
 
+//secondFactor returns true if the account doesn't use two-factor authentication or the third login argument is a valid code.  Otherwise it sends the failure message.
+func secondFactor(socket Socket, options *Options, cd clientdata.ClientData, cmd *Input) bool {
+	enabled, err := cd.TOTPEnabled()
+	if err == nil && enabled {
+		if len(cmd.Args) < 3 || cmd.Args[2] == "" {
+			_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: TWO_FACTOR_REQUIRED, Data: "Two-factor code required."})
+			return false
+		}
+		var ok bool
+		ok, err = cd.VerifyTOTP(cmd.Args[2])
+		if err == nil && !ok {
+			options.Lockout.Failure(cmd.Args[0], options.RemoteAddr)
+			_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: INVALID_TWO_FACTOR, Data: "Invalid two-factor code."})
+			return false
+		}
+	}
+	if err != nil {
+		log.Println(err)
+		_ = sendMessage(socket, &Message{Type: "Login", Success: false, Code: SERVER_ERROR, Data: "Server error please try again."})
+		return false
+	}
+	return true
+}
+
+//banned sends a banned message of type t and returns true if the account name or the connection's address is banned.  name may be empty to only check the address.
+func banned(socket Socket, options *Options, cd clientdata.ClientData, t, name string) bool {
+	ban, err := cd.CheckBan(name, options.RemoteAddr)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	if ban == nil {
+		return false
+	}
+	_ = sendMessage(socket, &Message{Type: t, Success: false, Code: BANNED, Data: "You are banned from this server."})
+	return true
+}
+
 func getInput(socket Socket) (string, error) {
 	messageType, input, err := socket.ReadMessage()
 	if err != nil {
@@ -200,6 +285,9 @@ type Options struct {
 	ChatLog       io.Writer
 	DataFactory   clientdata.Factory
 	ClientFactory connections.ClientFactory
+	RemoteAddr    string
+	Lockout       *lockout.Tracker
+	Uploads       *files.Signer //signs upload tickets, nil if uploads are off
 }
 
 func (c *Connection) inputHandler() {
@@ -211,8 +299,12 @@ func (c *Connection) inputHandler() {
 			return
 		}
 		com := parseCommand(input)
-		resp := c.HandleCommand(com)
-		respStr := parseResponse(com.Command, resp)
+		var respStr string
+		if com.Command == "uploadticket" {
+			respStr = c.uploadTicket()
+		} else {
+			respStr = parseResponse(com.Command, c.HandleCommand(com))
+		}
 		c.writeLock.Lock()
 		err = c.socket.WriteMessage(TEXT_MESSAGE, []byte(respStr))
 		c.writeLock.Unlock()
@@ -224,6 +316,20 @@ func (c *Connection) inputHandler() {
 	}
 }
 
+//uploadTicket returns the response to the uploadticket command.  The ticket lets the client upload a file over HTTP by passing it as the ticket query parameter to /upload.
+func (c *Connection) uploadTicket() string {
+	m := &Message{Type: "uploadticket", Success: false, Code: UPLOADS_DISABLED, String: "File uploads are disabled.", Data: "File uploads are disabled."}
+	if c.uploads != nil {
+		ticket := c.uploads.Ticket(c.name, UploadTicketLifetime)
+		m = &Message{Type: "uploadticket", Success: true, Code: 0, Data: ticket}
+	}
+	j, err := json.Marshal(m)
+	if err != nil {
+		log.Println(err)
+	}
+	return string(j)
+}
+
 type Input struct {
 	Command string
 	Args    []string
@@ -6,12 +6,14 @@ import (
 	"github.com/DavidAFox/Chat/clientdata"
 	"github.com/DavidAFox/Chat/clientdata/filedata"
 	"github.com/DavidAFox/Chat/connections"
+	"github.com/DavidAFox/Chat/files"
 	"github.com/DavidAFox/Chat/message"
 	"github.com/DavidAFox/Chat/room"
 	"io"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestHandleCommandNoArgs(t *testing.T) {
@@ -90,6 +92,22 @@ func TestLogin(t *testing.T) {
 	client.Close()
 }
 
+func TestLoginBanned(t *testing.T) {
+	server, client := NewTestSocket()
+	options := NewOptionsForTesting()
+	err := options.DataFactory.Create("").Ban(clientdata.BanAccount, "Fred", "", time.Time{})
+	if err != nil {
+		t.Fatal("Error banning in TestLoginBanned: ", err)
+	}
+	go Start(server, options)
+	go client.WriteMessage(TEXT_MESSAGE, []byte(newTestCommandString(t, "login", "Fred", "FredsPassword")))
+	<-client.read
+	if !strings.Contains(client.messages[0], "banned") {
+		t.Error("Banned message not found got: ", client.messages)
+	}
+	client.Close()
+}
+
 func sliceContains(slice []string, str string) bool {
 	for i := range slice {
 		if slice[i] == str {
@@ -111,6 +129,27 @@ func compareCommands(expected, got []string, t *testing.T) {
 	}
 }
 
+func TestUploadTicket(t *testing.T) {
+	con, _ := newConnectionForTesting()
+	con.name = "Fred"
+	m := new(Message)
+	if err := json.Unmarshal([]byte(con.uploadTicket()), m); err != nil {
+		t.Fatal("Error unmarshaling: ", err)
+	}
+	if m.Success || m.Code != UPLOADS_DISABLED {
+		t.Errorf("Expected uploads to be disabled, got: %+v", m)
+	}
+	con.uploads, _ = files.NewSigner(nil)
+	m = new(Message)
+	if err := json.Unmarshal([]byte(con.uploadTicket()), m); err != nil {
+		t.Fatal("Error unmarshaling: ", err)
+	}
+	ticket, _ := m.Data.(string)
+	if !m.Success || con.uploads.CheckTicket(ticket) != "Fred" {
+		t.Errorf("Expected a ticket for Fred, got: %+v", m)
+	}
+}
+
 func newConnectionForTesting() (*Connection, *testSocket) {
 	tcf := new(testClientFactory)
 	con := new(Connection)
@@ -0,0 +1,103 @@
+/*
+Package files stores files shared in chat rooms.  The http connection saves uploads through a Store so the files can be kept somewhere other than local disk by providing a different Store.
+*/
+package files
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrNotFound = errors.New("files: File not found.")
+var ErrTooLarge = errors.New("files: File is too large.")
+
+//Store saves and opens files by id.  Save picks the id.
+type Store interface {
+	Save(r io.Reader, limit int64) (id string, size int64, err error)
+	Open(id string) (io.ReadCloser, error)
+	Delete(id string) error
+}
+
+//DiskStore is a Store that keeps each file in a directory on local disk.
+type DiskStore struct {
+	dir string
+}
+
+//NewDiskStore returns a DiskStore that keeps files in dir.  dir is created if it doesn't exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+//newID returns a random id for a file.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+//validID returns true if id could have come from newID so it can't be used to reach outside the store's directory.
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+//Save writes r to a new file and returns its id and size.  If r has more than limit bytes nothing is saved and it returns ErrTooLarge.
+func (ds *DiskStore) Save(r io.Reader, limit int64) (string, int64, error) {
+	id, err := newID()
+	if err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(ds.dir, id)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", 0, err
+	}
+	size, err := io.Copy(f, io.LimitReader(r, limit+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && size > limit {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", 0, err
+	}
+	return id, size, nil
+}
+
+//Open returns the file id.  It will return ErrNotFound if there isn't one.
+func (ds *DiskStore) Open(id string) (io.ReadCloser, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(filepath.Join(ds.dir, id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+//Delete removes the file id.
+func (ds *DiskStore) Delete(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	err := os.Remove(filepath.Join(ds.dir, id))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package files
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskStore(t *testing.T) {
+	ds, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatal("Error creating store: ", err)
+	}
+	id, size, err := ds.Save(strings.NewReader("hello"), 5)
+	if err != nil || size != 5 {
+		t.Fatalf("Error saving: %v %v", size, err)
+	}
+	f, err := ds.Open(id)
+	if err != nil {
+		t.Fatal("Error opening: ", err)
+	}
+	b, _ := ioutil.ReadAll(f)
+	f.Close()
+	if string(b) != "hello" {
+		t.Errorf("Wrong contents: %q", b)
+	}
+	if _, _, err = ds.Save(strings.NewReader("too long"), 5); err != ErrTooLarge {
+		t.Error("Expected a file over the limit to fail, got: ", err)
+	}
+	if _, err = ds.Open("../files.go"); err != ErrNotFound {
+		t.Error("Expected an invalid id not to be opened, got: ", err)
+	}
+	if err = ds.Delete(id); err != nil {
+		t.Error("Error deleting: ", err)
+	}
+	if _, err = ds.Open(id); err != ErrNotFound {
+		t.Error("Expected the file to be gone, got: ", err)
+	}
+}
+
+func TestSigner(t *testing.T) {
+	s, err := NewSigner(nil)
+	if err != nil {
+		t.Fatal("Error creating signer: ", err)
+	}
+	other, _ := NewSigner([]byte("another key"))
+	token := s.Download("abc", time.Minute)
+	if !s.CheckDownload("abc", token) {
+		t.Error("Expected the download token to be valid")
+	}
+	if s.CheckDownload("abd", token) || other.CheckDownload("abc", token) || s.CheckDownload("abc", "") {
+		t.Error("Expected the download token to only work for its file and signer")
+	}
+	if s.CheckDownload("abc", s.Download("abc", -time.Minute)) {
+		t.Error("Expected an expired download token to be refused")
+	}
+	ticket := s.Ticket("Fred", time.Minute)
+	if name := s.CheckTicket(ticket); name != "Fred" {
+		t.Errorf("Expected the ticket to be for Fred, got: %q", name)
+	}
+	if name := s.CheckTicket("Bob" + strings.TrimPrefix(ticket, "Fred")); name != "" {
+		t.Errorf("Expected a ticket with another name to be refused, got: %q", name)
+	}
+	if s.CheckDownload("Fred", strings.TrimPrefix(ticket, "Fred.")) {
+		t.Error("Expected a ticket not to work as a download token")
+	}
+}
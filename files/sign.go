@@ -0,0 +1,72 @@
+package files
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//Signer makes and checks tokens that expire so files can be downloaded and uploaded without an HTTP session.  Download tokens go in the links posted to rooms so any connection can follow them.  Upload tickets let clients connected some other way, like a websocket, post a file over HTTP.
+type Signer struct {
+	key []byte
+}
+
+//NewSigner returns a Signer using key.  If key is empty a random one is used so tokens only work until the server restarts.
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Signer{key: key}, nil
+}
+
+//sign returns the token for subject that expires at expires.  It is the expiry as a unix time and a signature separated by a ".".
+func (s *Signer) sign(subject string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(subject + "\n" + exp))
+	return exp + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+//verify returns true if token was made by sign for subject and hasn't expired.
+func (s *Signer) verify(subject, token string) bool {
+	i := strings.Index(token, ".")
+	if i < 0 {
+		return false
+	}
+	exp, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(s.sign(subject, time.Unix(exp, 0))), []byte(token))
+}
+
+//Download returns a token that allows downloading file id for lifetime.
+func (s *Signer) Download(id string, lifetime time.Duration) string {
+	return s.sign("download "+id, time.Now().Add(lifetime))
+}
+
+//CheckDownload returns true if token allows downloading file id.
+func (s *Signer) CheckDownload(id, token string) bool {
+	return s.verify("download "+id, token)
+}
+
+//Ticket returns an upload ticket for the client name that lasts for lifetime.
+func (s *Signer) Ticket(name string, lifetime time.Duration) string {
+	return name + "." + s.sign("upload "+name, time.Now().Add(lifetime))
+}
+
+//CheckTicket returns the name of the client ticket was made for or "" if it isn't a valid ticket.
+func (s *Signer) CheckTicket(ticket string) string {
+	i := strings.Index(ticket, ".")
+	if i < 1 || !s.verify("upload "+ticket[:i], ticket[i+1:]) {
+		return ""
+	}
+	return ticket[:i]
+}
@@ -0,0 +1,75 @@
+package filter
+
+//Config describes a Pipeline so it can be loaded from the server's config file.  Empty actions default to mask for words and rules and reject for links.  A room in Rooms uses its config instead of this one.
+type Config struct {
+	Words      []string
+	WordAction string
+	Rules      []Rule
+	AllowLinks []string
+	DenyLinks  []string
+	LinkAction string
+	Rooms      map[string]Config
+}
+
+//Rule is a regular expression rule.  Replacement is used for masked matches and Reason is given for rejects and flags.
+type Rule struct {
+	Pattern     string
+	Action      string
+	Replacement string
+	Reason      string
+}
+
+//New builds the Pipeline described by c.  Words are checked first, then the rules in order, then links.
+func New(c Config) (*Pipeline, error) {
+	p, err := build(c)
+	if err != nil {
+		return nil, err
+	}
+	for room, rc := range c.Rooms {
+		rp, err := build(rc)
+		if err != nil {
+			return nil, err
+		}
+		p.SetRoom(room, rp)
+	}
+	return p, nil
+}
+
+//build builds the Pipeline for c without its rooms.
+func build(c Config) (*Pipeline, error) {
+	p := NewPipeline()
+	if len(c.Words) > 0 {
+		f, err := NewWordFilter(c.Words, withDefault(c.WordAction, ActionMask))
+		if err != nil {
+			return nil, err
+		}
+		p.Add(f)
+	}
+	for _, rule := range c.Rules {
+		reason := rule.Reason
+		if reason == "" {
+			reason = "That message isn't allowed."
+		}
+		f, err := NewRegexFilter(rule.Pattern, rule.Replacement, withDefault(rule.Action, ActionMask), reason)
+		if err != nil {
+			return nil, err
+		}
+		p.Add(f)
+	}
+	if len(c.AllowLinks) > 0 || len(c.DenyLinks) > 0 {
+		f, err := NewLinkFilter(c.AllowLinks, c.DenyLinks, withDefault(c.LinkAction, ActionReject))
+		if err != nil {
+			return nil, err
+		}
+		p.Add(f)
+	}
+	return p, nil
+}
+
+//withDefault returns action or def if action is empty.
+func withDefault(action, def string) string {
+	if action == "" {
+		return def
+	}
+	return action
+}
@@ -0,0 +1,205 @@
+/*
+Package filter checks the text of chat messages before they are sent.  A Pipeline runs a message through its filters in order.  Each filter can mask what it matches, reject the message or flag it for moderators.  Rooms can have their own pipeline that is used instead of the default one.
+*/
+package filter
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+//Actions a filter takes on what it matches.
+const (
+	ActionMask   = "mask"   //replace the match and send the message
+	ActionReject = "reject" //don't send the message
+	ActionFlag   = "flag"   //send the message and tell the moderators
+)
+
+var ErrInvalidAction = errors.New("filter: Invalid action.")
+
+//Result is the outcome of checking a message.  Text is the message after masking.  Reason says why it was rejected and Flags why it was flagged.
+type Result struct {
+	Text     string
+	Rejected bool
+	Reason   string
+	Flags    []string
+}
+
+//take applies action to a match in r.  It returns the text to put in place of match.
+func (r *Result) take(action, match, replacement, reason string) string {
+	switch action {
+	case ActionReject:
+		if !r.Rejected {
+			r.Rejected = true
+			r.Reason = reason
+		}
+		return match
+	case ActionFlag:
+		r.Flags = append(r.Flags, reason)
+		return match
+	}
+	if replacement == "" {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	}
+	return replacement
+}
+
+//Filter is one step of a Pipeline.  Apply checks r.Text and updates r.
+type Filter interface {
+	Apply(r *Result)
+}
+
+//Pipeline is a list of filters with optional per-room pipelines.
+type Pipeline struct {
+	filters []Filter
+	rooms   map[string]*Pipeline
+}
+
+//NewPipeline returns a Pipeline that runs filters in order.
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters, rooms: make(map[string]*Pipeline)}
+}
+
+//Add puts f at the end of the pipeline.
+func (p *Pipeline) Add(f Filter) {
+	p.filters = append(p.filters, f)
+}
+
+//SetRoom makes messages sent to room use rp instead of this pipeline.
+func (p *Pipeline) SetRoom(room string, rp *Pipeline) {
+	p.rooms[room] = rp
+}
+
+//Check runs text through the pipeline for room.  room is empty for private messages.  Filters after one that rejects the message aren't run.  A nil Pipeline allows everything.
+func (p *Pipeline) Check(room, text string) *Result {
+	r := &Result{Text: text}
+	if p == nil {
+		return r
+	}
+	if rp, ok := p.rooms[room]; ok && room != "" {
+		p = rp
+	}
+	for _, f := range p.filters {
+		f.Apply(r)
+		if r.Rejected {
+			break
+		}
+	}
+	return r
+}
+
+//validAction returns ErrInvalidAction unless action is one of the actions.
+func validAction(action string) error {
+	switch action {
+	case ActionMask, ActionReject, ActionFlag:
+		return nil
+	}
+	return ErrInvalidAction
+}
+
+//RegexFilter takes its action on text matching a regular expression.
+type RegexFilter struct {
+	pattern     *regexp.Regexp
+	replacement string
+	action      string
+	reason      string
+}
+
+//NewRegexFilter returns a RegexFilter for pattern.  Masked matches are replaced with replacement or with asterisks if it is empty.  reason is given to clients for rejects and moderators for flags.
+func NewRegexFilter(pattern, replacement, action, reason string) (*RegexFilter, error) {
+	if err := validAction(action); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexFilter{pattern: re, replacement: replacement, action: action, reason: reason}, nil
+}
+
+//Apply takes the filter's action on each match.
+func (f *RegexFilter) Apply(r *Result) {
+	r.Text = f.pattern.ReplaceAllStringFunc(r.Text, func(match string) string {
+		return r.take(f.action, match, f.replacement, f.reason)
+	})
+}
+
+//NewWordFilter returns a filter for a list of words.  Words are matched whole and ignoring case.
+func NewWordFilter(words []string, action string) (*RegexFilter, error) {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) == 0 {
+		quoted = append(quoted, `\z.`) //matches nothing
+	}
+	return NewRegexFilter(`(?i)\b(?:`+strings.Join(quoted, "|")+`)\b`, "", action, "That message has language that isn't allowed.")
+}
+
+//linkPattern finds links in text.
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"]+`)
+
+//LinkFilter takes its action on links to hosts on its deny list or, if it has an allow list, hosts that aren't on it.  A host matches an entry if it is the entry or a subdomain of it.
+type LinkFilter struct {
+	allow  []string
+	deny   []string
+	action string
+}
+
+//NewLinkFilter returns a LinkFilter.  Masked links are replaced with [link removed].
+func NewLinkFilter(allow, deny []string, action string) (*LinkFilter, error) {
+	if err := validAction(action); err != nil {
+		return nil, err
+	}
+	return &LinkFilter{allow: lowerAll(allow), deny: lowerAll(deny), action: action}, nil
+}
+
+//lowerAll returns list lower cased.
+func lowerAll(list []string) []string {
+	lower := make([]string, len(list))
+	for i := range list {
+		lower[i] = strings.ToLower(strings.TrimSpace(list[i]))
+	}
+	return lower
+}
+
+//hostMatches returns true if host is one of list or a subdomain of one.
+func hostMatches(host string, list []string) bool {
+	for _, entry := range list {
+		if entry != "" && (host == entry || strings.HasSuffix(host, "."+entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+//blocked returns true if the filter acts on link.
+func (f *LinkFilter) blocked(link string) bool {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	if hostMatches(host, f.deny) {
+		return true
+	}
+	return len(f.allow) > 0 && !hostMatches(host, f.allow)
+}
+
+//Apply takes the filter's action on each blocked link.
+func (f *LinkFilter) Apply(r *Result) {
+	r.Text = linkPattern.ReplaceAllStringFunc(r.Text, func(link string) string {
+		if !f.blocked(link) {
+			return link
+		}
+		return r.take(f.action, link, "[link removed]", "Links to that site aren't allowed.")
+	})
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	p, err := New(Config{
+		Words:      []string{"darn", "heck"},
+		Rules:      []Rule{{Pattern: `\d{3}-\d{4}`, Action: ActionFlag, Reason: "phone number"}, {Pattern: `(?i)buy now`, Action: ActionReject, Reason: "No ads."}},
+		AllowLinks: []string{"example.com"},
+		Rooms:      map[string]Config{"Casual": {}},
+	})
+	if err != nil {
+		t.Fatal("Error building pipeline: ", err)
+	}
+	var tests = []struct {
+		room     string
+		text     string
+		expected string
+		rejected bool
+		flags    int
+	}{
+		{"Lobby", "hello there", "hello there", false, 0},
+		{"Lobby", "Darn it, what the HECK", "**** it, what the ****", false, 0},
+		{"Lobby", "darned", "darned", false, 0},
+		{"Lobby", "call 555-1234", "call 555-1234", false, 1},
+		{"Lobby", "BUY NOW", "", true, 0},
+		{"Lobby", "see https://docs.example.com/a and www.spam.net", "see https://docs.example.com/a and www.spam.net", true, 0},
+		{"Casual", "darn BUY NOW www.spam.net", "darn BUY NOW www.spam.net", false, 0},
+		{"", "heck", "****", false, 0},
+	}
+	for _, tt := range tests {
+		r := p.Check(tt.room, tt.text)
+		if r.Rejected != tt.rejected || len(r.Flags) != tt.flags || (!tt.rejected && r.Text != tt.expected) {
+			t.Errorf("Check(%q, %q) => %+v", tt.room, tt.text, r)
+		}
+	}
+}
+
+func TestLinkMask(t *testing.T) {
+	f, err := NewLinkFilter(nil, []string{"spam.net"}, ActionMask)
+	if err != nil {
+		t.Fatal("Error creating filter: ", err)
+	}
+	r := NewPipeline(f).Check("Lobby", "go to http://www.spam.net/x now")
+	if r.Text != "go to [link removed] now" {
+		t.Errorf("Expected the link to be masked, got: %q", r.Text)
+	}
+	if _, err = NewLinkFilter(nil, nil, "explode"); err != ErrInvalidAction {
+		t.Error("Expected an invalid action to fail, got: ", err)
+	}
+}
+
+func TestNilPipeline(t *testing.T) {
+	var p *Pipeline
+	if r := p.Check("Lobby", "anything"); r.Text != "anything" || r.Rejected {
+		t.Errorf("Expected a nil pipeline to allow everything, got: %+v", r)
+	}
+}
@@ -0,0 +1,271 @@
+package lockout
+
+/*
+Package lockout tracks failed login attempts for the chat server.  Failures are counted per account and per remote address.  Each failure makes the next attempt wait twice as long as the last and once an account or address reaches its threshold it is locked out for a while.  A single Tracker is meant to be shared by all the connection types so an attacker can't get around it by switching transports.
+*/
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/ratelimit"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Event kinds passed to the audit function.
+const (
+	EventFailure = "login failure"
+	EventLockout = "login lockout"
+)
+
+//Event describes a repeated failure or lockout for auditing.
+type Event struct {
+	Kind     string
+	Name     string
+	Addr     string
+	Failures int
+	Until    time.Time
+	Time     time.Time
+}
+
+//String formats the event for logging.
+func (e Event) String() string {
+	s := fmt.Sprintf("%v: account %q from %v after %v failures", e.Kind, e.Name, e.Addr, e.Failures)
+	if !e.Until.IsZero() {
+		s += " until " + e.Until.Format(time.RFC3339)
+	}
+	return s
+}
+
+//Options configures a Tracker.  Zero values are replaced with the defaults.
+type Options struct {
+	BaseDelay        time.Duration  //delay after the first failure
+	MaxDelay         time.Duration  //cap on the exponential backoff
+	AccountThreshold int            //failures before an account is locked
+	AddressThreshold int            //failures before an address is locked
+	LockoutDuration  time.Duration  //how long a lockout lasts
+	Window           time.Duration  //failures older than this are forgotten
+	AuditThreshold   int            //failures before each further failure is audited
+	Audit            func(Event)    //called for repeated failures and lockouts
+	Registrations    ratelimit.Rate //new accounts per address, a negative PerMinute turns the limit off
+}
+
+//DefaultOptions are the options used for any values left zero.
+var DefaultOptions = Options{
+	BaseDelay:        time.Second,
+	MaxDelay:         time.Minute,
+	AccountThreshold: 10,
+	AddressThreshold: 30,
+	LockoutDuration:  15 * time.Minute,
+	Window:           time.Hour,
+	AuditThreshold:   3,
+	Registrations:    ratelimit.Rate{PerMinute: 0.1, Burst: 3},
+}
+
+//record is the failure history for one account or address.
+type record struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+//Tracker records failed logins.  A nil Tracker allows everything.
+type Tracker struct {
+	lock      *sync.Mutex
+	accounts  map[string]*record
+	addresses map[string]*record
+	registers *ratelimit.Limiter
+	options   Options
+	now       func() time.Time
+}
+
+//New returns a Tracker using options.
+func New(options Options) *Tracker {
+	t := new(Tracker)
+	t.lock = new(sync.Mutex)
+	t.accounts = make(map[string]*record)
+	t.addresses = make(map[string]*record)
+	if options.BaseDelay == 0 {
+		options.BaseDelay = DefaultOptions.BaseDelay
+	}
+	if options.MaxDelay == 0 {
+		options.MaxDelay = DefaultOptions.MaxDelay
+	}
+	if options.AccountThreshold == 0 {
+		options.AccountThreshold = DefaultOptions.AccountThreshold
+	}
+	if options.AddressThreshold == 0 {
+		options.AddressThreshold = DefaultOptions.AddressThreshold
+	}
+	if options.LockoutDuration == 0 {
+		options.LockoutDuration = DefaultOptions.LockoutDuration
+	}
+	if options.Window == 0 {
+		options.Window = DefaultOptions.Window
+	}
+	if options.AuditThreshold == 0 {
+		options.AuditThreshold = DefaultOptions.AuditThreshold
+	}
+	if options.Audit == nil {
+		options.Audit = func(e Event) { log.Println("audit:", e) }
+	}
+	if options.Registrations.PerMinute == 0 {
+		options.Registrations = DefaultOptions.Registrations
+	}
+	t.registers = ratelimit.New(options.Registrations)
+	t.options = options
+	t.now = time.Now
+	return t
+}
+
+//account returns the key for the account name.  Logins ignore case so Fred and fred are the same account.
+func account(name string) string {
+	return strings.ToLower(name)
+}
+
+//Check returns how long the account name or address must wait before trying to log in again.  It returns 0 if a login may be attempted now.
+func (t *Tracker) Check(name, addr string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	now := t.now()
+	wait := t.wait(t.accounts[account(name)], now)
+	if w := t.wait(t.addresses[host(addr)], now); w > wait {
+		wait = w
+	}
+	return wait
+}
+
+//Failure records a failed login for the account name from addr.
+func (t *Tracker) Failure(name, addr string) {
+	if t == nil {
+		return
+	}
+	addr = host(addr)
+	t.lock.Lock()
+	now := t.now()
+	t.prune(now)
+	events := make([]Event, 0, 2)
+	for _, f := range []struct {
+		m         map[string]*record
+		key       string
+		threshold int
+	}{{t.accounts, account(name), t.options.AccountThreshold}, {t.addresses, addr, t.options.AddressThreshold}} {
+		r, locked := t.fail(f.m, f.key, f.threshold, now)
+		switch {
+		case locked:
+			events = append(events, Event{Kind: EventLockout, Name: name, Addr: addr, Failures: r.failures, Until: r.until, Time: now})
+		case r != nil && r.failures >= t.options.AuditThreshold:
+			events = append(events, Event{Kind: EventFailure, Name: name, Addr: addr, Failures: r.failures, Time: now})
+		}
+	}
+	t.lock.Unlock()
+	if len(events) > 0 {
+		t.options.Audit(mostSevere(events))
+	}
+}
+
+//mostSevere returns the lockout event if there is one so a single failure is only audited once.
+func mostSevere(events []Event) Event {
+	for i := range events {
+		if events[i].Kind == EventLockout {
+			return events[i]
+		}
+	}
+	return events[0]
+}
+
+//Success clears the failure history for the account name.  The address history is kept so one good account can't be used to reset an address that is guessing at others.
+func (t *Tracker) Success(name, addr string) {
+	if t == nil {
+		return
+	}
+	t.lock.Lock()
+	delete(t.accounts, account(name))
+	t.lock.Unlock()
+}
+
+//wait returns the time left before r may try again.
+func (t *Tracker) wait(r *record, now time.Time) time.Duration {
+	if r == nil {
+		return 0
+	}
+	if now.Before(r.until) {
+		return r.until.Sub(now)
+	}
+	next := r.last.Add(t.delay(r.failures))
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+	return 0
+}
+
+//delay returns the exponential backoff after failures failures.
+func (t *Tracker) delay(failures int) time.Duration {
+	if failures < 1 {
+		return 0
+	}
+	d := t.options.BaseDelay
+	for i := 1; i < failures && d < t.options.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > t.options.MaxDelay {
+		d = t.options.MaxDelay
+	}
+	return d
+}
+
+//fail adds a failure to the record for key in m.  It returns true if the failure reached threshold and locked the record.
+func (t *Tracker) fail(m map[string]*record, key string, threshold int, now time.Time) (*record, bool) {
+	if key == "" {
+		return nil, false
+	}
+	r, ok := m[key]
+	if !ok || now.Sub(r.last) > t.options.Window {
+		r = new(record)
+		m[key] = r
+	}
+	r.failures++
+	r.last = now
+	if r.failures%threshold == 0 {
+		r.until = now.Add(t.options.LockoutDuration)
+		return r, true
+	}
+	return r, false
+}
+
+//prune removes records that are no longer locked and haven't failed within the window.
+func (t *Tracker) prune(now time.Time) {
+	for _, m := range []map[string]*record{t.accounts, t.addresses} {
+		for key, r := range m {
+			if now.Sub(r.last) > t.options.Window && now.After(r.until) {
+				delete(m, key)
+			}
+		}
+	}
+}
+
+//Register counts an account being created from addr.  It returns 0 if it is allowed or how long addr must wait before creating another account.
+func (t *Tracker) Register(addr string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	return t.registers.Take(host(addr))
+}
+
+//host strips the port from addr if one is present.
+func host(addr string) string {
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		return h
+	}
+	return strings.Trim(addr, "[]")
+}
+
+//WaitString formats a wait for showing to users rounded up to the second.
+func WaitString(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
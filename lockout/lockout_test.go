@@ -0,0 +1,116 @@
+package lockout
+
+import (
+	"github.com/DavidAFox/Chat/ratelimit"
+	"testing"
+	"time"
+)
+
+func newTestTracker(events *[]Event) (*Tracker, *time.Time) {
+	now := time.Date(2016, 4, 6, 12, 0, 0, 0, time.UTC)
+	t := New(Options{BaseDelay: time.Second, MaxDelay: 8 * time.Second, AccountThreshold: 5, AddressThreshold: 20, LockoutDuration: time.Minute, AuditThreshold: 3, Audit: func(e Event) { *events = append(*events, e) }})
+	t.now = func() time.Time { return now }
+	return t, &now
+}
+
+func TestBackoffDoubles(t *testing.T) {
+	events := make([]Event, 0)
+	tr, now := newTestTracker(&events)
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	for i := range expected {
+		tr.Failure("Fred", "10.0.0.1:4000")
+		if wait := tr.Check("Fred", "10.0.0.2:4000"); wait != expected[i] {
+			t.Errorf("Incorrect wait after %v failures expected: %v, got: %v", i+1, expected[i], wait)
+		}
+		*now = now.Add(expected[i])
+	}
+	if wait := tr.Check("Fred", "10.0.0.2:4000"); wait != 0 {
+		t.Error("Expected no wait after backoff elapsed got: ", wait)
+	}
+}
+
+func TestLockoutAfterThreshold(t *testing.T) {
+	events := make([]Event, 0)
+	tr, now := newTestTracker(&events)
+	for i := 0; i < 5; i++ {
+		tr.Failure("Fred", "10.0.0.1:4000")
+		*now = now.Add(10 * time.Second)
+	}
+	if wait := tr.Check("Fred", ""); wait != 50*time.Second {
+		t.Error("Expected account to be locked for the rest of the minute got: ", wait)
+	}
+	if len(events) != 3 || events[2].Kind != EventLockout {
+		t.Errorf("Expected two failure events and a lockout got: %v", events)
+	}
+	*now = now.Add(50 * time.Second)
+	if wait := tr.Check("Fred", ""); wait != 0 {
+		t.Error("Expected lockout to expire got: ", wait)
+	}
+}
+
+func TestAddressIsSharedAcrossAccounts(t *testing.T) {
+	events := make([]Event, 0)
+	tr, _ := newTestTracker(&events)
+	tr.Failure("Fred", "10.0.0.1:4000")
+	if wait := tr.Check("Bob", "10.0.0.1:5000"); wait == 0 {
+		t.Error("Expected address to be delayed for a different account")
+	}
+	if wait := tr.Check("Bob", "10.0.0.3:5000"); wait != 0 {
+		t.Error("Expected no wait for an unrelated account and address got: ", wait)
+	}
+}
+
+func TestAccountIgnoresCase(t *testing.T) {
+	events := make([]Event, 0)
+	tr, now := newTestTracker(&events)
+	for _, name := range []string{"Fred", "fred", "FRED", "fReD", "FrEd"} {
+		tr.Failure(name, "10.0.0.1:4000")
+		*now = now.Add(10 * time.Second)
+	}
+	if wait := tr.Check("fred", ""); wait != 50*time.Second {
+		t.Error("Expected failures with any case to lock the account got: ", wait)
+	}
+	tr.Success("FRED", "")
+	if wait := tr.Check("Fred", ""); wait != 0 {
+		t.Error("Expected success with any case to clear the account got: ", wait)
+	}
+}
+
+func TestSuccessClearsAccount(t *testing.T) {
+	events := make([]Event, 0)
+	tr, now := newTestTracker(&events)
+	tr.Failure("Fred", "10.0.0.1:4000")
+	*now = now.Add(time.Second)
+	tr.Success("Fred", "10.0.0.1:4000")
+	tr.Failure("Fred", "10.0.0.9:4000")
+	if wait := tr.Check("Fred", ""); wait != time.Second {
+		t.Error("Expected backoff to restart after a success got: ", wait)
+	}
+}
+
+func TestNilTracker(t *testing.T) {
+	var tr *Tracker
+	tr.Failure("Fred", "10.0.0.1:4000")
+	tr.Success("Fred", "10.0.0.1:4000")
+	if wait := tr.Check("Fred", "10.0.0.1:4000"); wait != 0 {
+		t.Error("Expected nil tracker to allow logins got: ", wait)
+	}
+}
+
+func TestRegistrationsLimitedPerAddress(t *testing.T) {
+	tr := New(Options{Registrations: ratelimit.Rate{PerMinute: 1, Burst: 2}})
+	for i := 0; i < 2; i++ {
+		if wait := tr.Register("10.0.0.1:4000"); wait != 0 {
+			t.Fatal("Expected registration to be allowed got: ", wait)
+		}
+	}
+	if wait := tr.Register("10.0.0.1:5000"); wait == 0 {
+		t.Error("Expected a third registration from the address to wait")
+	}
+	if wait := tr.Register("10.0.0.2:4000"); wait != 0 {
+		t.Error("Expected another address to be allowed got: ", wait)
+	}
+	if wait := New(Options{Registrations: ratelimit.Rate{PerMinute: -1}}).Register("10.0.0.1:4000"); wait != 0 {
+		t.Error("Expected a negative rate to turn the limit off got: ", wait)
+	}
+}
@@ -3,6 +3,7 @@ package message
 import (
 	"container/list"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,6 +19,13 @@ type ClientMessage interface {
 	Name() string //name of the client that sent the message
 }
 
+//Sequenced is a message that is numbered by the room it is sent to so it can be referred to later, as in a report.
+type Sequenced interface {
+	ClientMessage
+	SetSeq(seq int)
+	Sequence() int
+}
+
 //messageList is a mutex enhanced linked list of messages.
 type MessageList struct {
 	*list.List
@@ -52,19 +60,32 @@ func (m ServerMessage) String() string {
 	return m.Text
 }
 
-//SendMessage includes the text of the message, the time it was sent and the client who sent it.  It is used primarily for normal messages sent to the room with send.
+//SendMessage includes the text of the message, the time it was sent and the client who sent it.  It is used primarily for normal messages sent to the room with send.  Display is the sender's display name if they have one.
 type SendMessage struct {
 	Text       string
 	Time       time.Time
 	TimeString string
 	Sender     string
+	Display    string
 	Type       string
+	Seq        int
 }
 
 //String formats the clientMessage as time [Sender]: text.
 func (m SendMessage) String() string {
 	const layout = "3:04pm"
-	return fmt.Sprintf("%s [%v]: %v", m.Time.Format(layout), m.Sender, m.Text)
+	return fmt.Sprintf("%s [%v]: %v", m.Time.Format(layout), shownName(m.Sender, m.Display), m.Text)
+}
+
+//shownName returns the name to show for a client.  The login name is added after the display name unless they only differ in case.
+func shownName(name, display string) string {
+	if display == "" {
+		return name
+	}
+	if strings.EqualFold(name, display) {
+		return display
+	}
+	return fmt.Sprintf("%v (%v)", display, name)
 }
 
 //Name returns the name of the client that send the message.
@@ -72,6 +93,16 @@ func (m SendMessage) Name() string {
 	return m.Sender
 }
 
+//SetSeq sets the number the room gave the message.
+func (m *SendMessage) SetSeq(seq int) {
+	m.Seq = seq
+}
+
+//Sequence returns the number the room gave the message.
+func (m SendMessage) Sequence() int {
+	return m.Seq
+}
+
 //NewSendMessage creates a new client message
 func NewSendMessage(text string, sender string) *SendMessage {
 	msg := new(SendMessage)
@@ -83,10 +114,117 @@ func NewSendMessage(text string, sender string) *SendMessage {
 	return msg
 }
 
+//EmoteMessage is an action sent to the room with /me.
+type EmoteMessage struct {
+	Text       string
+	Time       time.Time
+	TimeString string
+	Sender     string
+	Display    string
+	Type       string
+	Seq        int
+}
+
+//NewEmoteMessage returns a new EmoteMessage.
+func NewEmoteMessage(text string, sender string) *EmoteMessage {
+	msg := new(EmoteMessage)
+	msg.Text = text
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Sender = sender
+	msg.Type = "Emote"
+	return msg
+}
+
+//String formats the EmoteMessage as time * Sender text.
+func (m EmoteMessage) String() string {
+	const layout = "3:04pm"
+	return fmt.Sprintf("%s * %v %v", m.Time.Format(layout), shownName(m.Sender, m.Display), m.Text)
+}
+
+//Name returns the name of the client that sent the emote.
+func (m EmoteMessage) Name() string {
+	return m.Sender
+}
+
+//SetSeq sets the number the room gave the emote.
+func (m *EmoteMessage) SetSeq(seq int) {
+	m.Seq = seq
+}
+
+//Sequence returns the number the room gave the emote.
+func (m EmoteMessage) Sequence() int {
+	return m.Seq
+}
+
+//AttachmentMessage is sent to a room when a client shares a file.  URL is where the file can be downloaded.
+type AttachmentMessage struct {
+	ID         string
+	Filename   string
+	MIME       string
+	Size       int64
+	URL        string
+	Time       time.Time
+	TimeString string
+	Sender     string
+	Display    string
+	Type       string
+	Seq        int
+}
+
+//NewAttachmentMessage returns a new AttachmentMessage.
+func NewAttachmentMessage(id, filename, mime string, size int64, url, sender string) *AttachmentMessage {
+	msg := new(AttachmentMessage)
+	msg.ID = id
+	msg.Filename = filename
+	msg.MIME = mime
+	msg.Size = size
+	msg.URL = url
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Sender = sender
+	msg.Type = "Attachment"
+	return msg
+}
+
+//String formats the AttachmentMessage as time [Sender]: shared filename (size) URL so text clients get a link.
+func (m AttachmentMessage) String() string {
+	const layout = "3:04pm"
+	return fmt.Sprintf("%s [%v]: shared %v (%v) %v", m.Time.Format(layout), shownName(m.Sender, m.Display), m.Filename, sizeString(m.Size), m.URL)
+}
+
+//Name returns the name of the client that shared the file.
+func (m AttachmentMessage) Name() string {
+	return m.Sender
+}
+
+//SetSeq sets the number the room gave the attachment.
+func (m *AttachmentMessage) SetSeq(seq int) {
+	m.Seq = seq
+}
+
+//Sequence returns the number the room gave the attachment.
+func (m AttachmentMessage) Sequence() int {
+	return m.Seq
+}
+
+//sizeString formats size in bytes as B, KB or MB.
+func sizeString(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
+//JoinMessage is sent to a room when a client joins or leaves it.  Left is true for leaves.
 type JoinMessage struct {
 	Subject string
 	Text    string
 	Type    string
+	Left    bool
 }
 
 func NewJoinMessage(subject string) *JoinMessage {
@@ -106,9 +244,39 @@ func NewLeaveMessage(subject string) *JoinMessage {
 	msg.Subject = subject
 	msg.Text = "has left the room."
 	msg.Type = "Join"
+	msg.Left = true
 	return msg
 }
 
+//TopicMessage is sent to a room when its topic changes.
+type TopicMessage struct {
+	Topic      string
+	Sender     string
+	Time       time.Time
+	TimeString string
+	Type       string
+}
+
+//NewTopicMessage returns a new TopicMessage.
+func NewTopicMessage(topic, sender string) *TopicMessage {
+	msg := new(TopicMessage)
+	msg.Topic = topic
+	msg.Sender = sender
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Type = "Topic"
+	return msg
+}
+
+//String formats the TopicMessage as time Sender changed the topic to: Topic.
+func (m TopicMessage) String() string {
+	const layout = "3:04pm"
+	if m.Topic == "" {
+		return fmt.Sprintf("%s %v cleared the topic.", m.Time.Format(layout), m.Sender)
+	}
+	return fmt.Sprintf("%s %v changed the topic to: %v", m.Time.Format(layout), m.Sender, m.Topic)
+}
+
 //TellMessage is a message sent by a tell.
 type TellMessage struct {
 	Text       string
@@ -145,6 +313,49 @@ func (m TellMessage) Name() string {
 	return m.Sender
 }
 
+//DirectMessage is a message in a direct message conversation.  Members is everyone in the conversation and Seq is the message's position in its history.
+type DirectMessage struct {
+	Conversation string
+	Members      []string
+	Seq          int
+	Text         string
+	Time         time.Time
+	TimeString   string
+	Sender       string
+	Type         string
+}
+
+//NewDirectMessage returns a new DirectMessage.
+func NewDirectMessage(conversation string, members []string, seq int, text, sender string) *DirectMessage {
+	msg := new(DirectMessage)
+	msg.Conversation = conversation
+	msg.Members = members
+	msg.Seq = seq
+	msg.Text = text
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Sender = sender
+	msg.Type = "Direct"
+	return msg
+}
+
+//String formats the DirectMessage as time [Sender to Others]: Text.
+func (m DirectMessage) String() string {
+	const layout = "3:04pm"
+	to := make([]string, 0, len(m.Members))
+	for _, member := range m.Members {
+		if member != m.Sender {
+			to = append(to, member)
+		}
+	}
+	return fmt.Sprintf("%s [%v to %v]: %v", m.Time.Format(layout), m.Sender, strings.Join(to, ", "), m.Text)
+}
+
+//Name returns the name of the client that sent the message.
+func (m DirectMessage) Name() string {
+	return m.Sender
+}
+
 //restMessage is a message sent from the REST API.
 type RestMessage struct {
 	Name string
@@ -157,3 +368,164 @@ func (m *RestMessage) String() string {
 	const layout = "3:04pm"
 	return fmt.Sprintf("%s [%v]: %v", m.Time.Format(layout), m.Name, m.Text)
 }
+
+//WebhookMessage is a message posted by an incoming webhook.  Name is the username given by the webhook and is not a client's name.
+type WebhookMessage struct {
+	Name       string
+	Text       string
+	Time       time.Time
+	TimeString string
+	Type       string
+}
+
+//NewWebhookMessage returns a new WebhookMessage.
+func NewWebhookMessage(text string, name string) *WebhookMessage {
+	msg := new(WebhookMessage)
+	msg.Name = name
+	msg.Text = text
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Type = "Webhook"
+	return msg
+}
+
+//String formats the WebhookMessage as time [Name (webhook)]: Text.
+func (m WebhookMessage) String() string {
+	const layout = "3:04pm"
+	return fmt.Sprintf("%s [%v (webhook)]: %v", m.Time.Format(layout), m.Name, m.Text)
+}
+
+//PollOption is one choice in a poll with its tally.  Voters is empty for anonymous polls.
+type PollOption struct {
+	Text   string
+	Votes  int
+	Voters []string
+}
+
+//PollMessage shows a poll and its current tallies.  It is sent when a poll opens, on every vote and when it closes.
+type PollMessage struct {
+	Question   string
+	Creator    string
+	Options    []PollOption
+	Anonymous  bool
+	Closed     bool
+	Closes     time.Time
+	Time       time.Time
+	TimeString string
+	Type       string
+}
+
+//NewPollMessage returns a new PollMessage.
+func NewPollMessage(question, creator string, options []PollOption, anonymous, closed bool, closes time.Time) *PollMessage {
+	msg := new(PollMessage)
+	msg.Question = question
+	msg.Creator = creator
+	msg.Options = options
+	msg.Anonymous = anonymous
+	msg.Closed = closed
+	msg.Closes = closes
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Type = "Poll"
+	return msg
+}
+
+//String formats the PollMessage as the question followed by a numbered line for each option with its votes.
+func (m PollMessage) String() string {
+	const layout = "3:04pm"
+	status := ""
+	switch {
+	case m.Closed:
+		status = " (closed)"
+	case !m.Closes.IsZero():
+		status = " (closes " + m.Closes.Format(layout) + ")"
+	}
+	s := fmt.Sprintf("%s Poll by %v: %v%v", m.Time.Format(layout), m.Creator, m.Question, status)
+	for i, o := range m.Options {
+		s += fmt.Sprintf("\r\n%v. %v - %v", i+1, o.Text, o.Votes)
+		if len(o.Voters) > 0 {
+			s += fmt.Sprintf(" (%v)", strings.Join(o.Voters, ", "))
+		}
+	}
+	return s
+}
+
+//FriendMessage tells a client that a friend logged in or out.  Type is FriendOnline or FriendOffline.
+type FriendMessage struct {
+	Friend     string
+	Online     bool
+	Time       time.Time
+	TimeString string
+	Type       string
+}
+
+//NewFriendOnlineMessage returns a FriendMessage saying friend logged in.
+func NewFriendOnlineMessage(friend string) *FriendMessage {
+	return newFriendMessage(friend, true, "FriendOnline")
+}
+
+//NewFriendOfflineMessage returns a FriendMessage saying friend logged out.
+func NewFriendOfflineMessage(friend string) *FriendMessage {
+	return newFriendMessage(friend, false, "FriendOffline")
+}
+
+func newFriendMessage(friend string, online bool, kind string) *FriendMessage {
+	msg := new(FriendMessage)
+	msg.Friend = friend
+	msg.Online = online
+	msg.Time = time.Now()
+	msg.TimeString = msg.Time.Format("3:04pm")
+	msg.Type = kind
+	return msg
+}
+
+//String formats the FriendMessage as time Friend is now online.
+func (m FriendMessage) String() string {
+	const layout = "3:04pm"
+	if m.Online {
+		return fmt.Sprintf("%s %v is now online.", m.Time.Format(layout), m.Friend)
+	}
+	return fmt.Sprintf("%s %v is now offline.", m.Time.Format(layout), m.Friend)
+}
+
+//Ephemeral is implemented by messages that are only useful as they happen like typing indicators.  They aren't kept in a room's messages, logged or sent to webhooks and connections that can't show them drop them.
+type Ephemeral interface {
+	Message
+	Expired(t time.Time) bool //true if the message is out of date at t
+}
+
+//TypingMessage tells a room that a client started or stopped typing.  A client that doesn't refresh it by Expires has stopped.
+type TypingMessage struct {
+	Sender  string
+	Typing  bool
+	Expires time.Time
+	Type    string
+}
+
+//NewTypingMessage returns a TypingMessage from sender that expires after ttl.
+func NewTypingMessage(sender string, typing bool, ttl time.Duration) *TypingMessage {
+	msg := new(TypingMessage)
+	msg.Sender = sender
+	msg.Typing = typing
+	msg.Expires = time.Now().Add(ttl)
+	msg.Type = "Typing"
+	return msg
+}
+
+//String formats the TypingMessage as Sender is typing... or Sender stopped typing.
+func (m TypingMessage) String() string {
+	if m.Typing {
+		return fmt.Sprintf("%v is typing...", m.Sender)
+	}
+	return fmt.Sprintf("%v stopped typing.", m.Sender)
+}
+
+//Name returns the name of the client that is typing.
+func (m TypingMessage) Name() string {
+	return m.Sender
+}
+
+//Expired returns true if t is after the message expires.
+func (m TypingMessage) Expired(t time.Time) bool {
+	return t.After(m.Expires)
+}
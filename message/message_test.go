@@ -76,3 +76,64 @@ func MTestClientMessageString(t *testing.T) {
 		}
 	}
 }
+
+func TestWebhookMessageString(t *testing.T) {
+	msg := NewWebhookMessage("build passed", "ci")
+	var err error
+	msg.Time, err = time.Parse("3:04pm", "1:00pm")
+	if err != nil {
+		fmt.Println("Error Parsing Time: ", err)
+	}
+	if expected := "1:00pm [ci (webhook)]: build passed"; msg.String() != expected {
+		t.Errorf("msg.String() => %q, want %q", msg.String(), expected)
+	}
+	if msg.Type != "Webhook" {
+		t.Errorf("Wrong message type expected: Webhook, got: %v", msg.Type)
+	}
+}
+
+func TestPollMessageString(t *testing.T) {
+	options := []PollOption{{"Pizza", 2, []string{"Bob", "Fred"}}, {"Tacos", 0, []string{}}}
+	msg := NewPollMessage("Lunch?", "Fred", options, false, true, time.Time{})
+	var err error
+	msg.Time, err = time.Parse("3:04pm", "1:00pm")
+	if err != nil {
+		fmt.Println("Error Parsing Time: ", err)
+	}
+	expected := "1:00pm Poll by Fred: Lunch? (closed)\r\n1. Pizza - 2 (Bob, Fred)\r\n2. Tacos - 0"
+	if msg.String() != expected {
+		t.Errorf("msg.String() => %q, want %q", msg.String(), expected)
+	}
+}
+
+func TestEmoteAndDisplayNames(t *testing.T) {
+	var tests = []struct {
+		msg      Message
+		expected string
+	}{
+		{&EmoteMessage{Text: "waves", Sender: "Fred"}, "* Fred waves"},
+		{&EmoteMessage{Text: "waves", Sender: "jose", Display: "José"}, "* José (jose) waves"},
+		{&SendMessage{Text: "hi", Sender: "fred", Display: "Fred"}, "[Fred]: hi"},
+		{&SendMessage{Text: "hi", Sender: "bob", Display: "Robert"}, "[Robert (bob)]: hi"},
+		{&AttachmentMessage{Filename: "cat.png", Size: 2560, URL: "http://example.com/files/1/cat.png", Sender: "Fred"}, "[Fred]: shared cat.png (2.5 KB) http://example.com/files/1/cat.png"},
+	}
+	var err error
+	for _, tt := range tests {
+		var at time.Time
+		at, err = time.Parse("3:04pm", "1:00pm")
+		if err != nil {
+			fmt.Println("Error Parsing Time: ", err)
+		}
+		switch msg := tt.msg.(type) {
+		case *EmoteMessage:
+			msg.Time = at
+		case *SendMessage:
+			msg.Time = at
+		case *AttachmentMessage:
+			msg.Time = at
+		}
+		if expected := "1:00pm " + tt.expected; tt.msg.String() != expected {
+			t.Errorf("msg.String() => %q, want %q", tt.msg.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Package ratelimit provides token bucket rate limits.  A Bucket limits one thing like a connection and a Limiter keeps a Bucket for each key like a user name.
+*/
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+//Rate is how fast a bucket refills and how many tokens it holds.  A Rate with PerMinute of zero or less is unlimited.
+type Rate struct {
+	PerMinute float64
+	Burst     int
+}
+
+//unlimited returns true if r doesn't limit anything.
+func (r Rate) unlimited() bool {
+	return r.PerMinute <= 0
+}
+
+//burst returns the bucket size which is at least one token.
+func (r Rate) burst() float64 {
+	if r.Burst < 1 {
+		return 1
+	}
+	return float64(r.Burst)
+}
+
+//Bucket is a token bucket.  It starts full.  A nil Bucket allows everything.
+type Bucket struct {
+	lock   sync.Mutex
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+//NewBucket returns a full Bucket for r or nil if r is unlimited.
+func NewBucket(r Rate) *Bucket {
+	if r.unlimited() {
+		return nil
+	}
+	return &Bucket{rate: r, tokens: r.burst(), last: time.Now()}
+}
+
+//refill adds the tokens earned since the last refill.
+func (b *Bucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Minutes() * b.rate.PerMinute
+	if b.tokens > b.rate.burst() {
+		b.tokens = b.rate.burst()
+	}
+	b.last = now
+}
+
+//Take takes a token and returns 0 or, if the bucket is empty, returns how long until there is one.
+func (b *Bucket) Take() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.take(time.Now())
+}
+
+//take is Take at now.  b.lock must be held.
+func (b *Bucket) take(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate.PerMinute * float64(time.Minute))
+}
+
+//full returns true if the bucket has refilled by now.  b.lock must be held.
+func (b *Bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.rate.burst()
+}
+
+//Limiter keeps a Bucket for each key.  Buckets that have refilled are dropped so it doesn't grow without bound.  A nil Limiter allows everything.
+type Limiter struct {
+	lock    sync.Mutex
+	rate    Rate
+	buckets map[string]*Bucket
+	pruned  time.Time
+}
+
+//New returns a Limiter for r or nil if r is unlimited.
+func New(r Rate) *Limiter {
+	if r.unlimited() {
+		return nil
+	}
+	return &Limiter{rate: r, buckets: make(map[string]*Bucket), pruned: time.Now()}
+}
+
+//Take takes a token from key's bucket and returns 0 or, if it is empty, returns how long until there is one.
+func (l *Limiter) Take(key string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now()
+	if now.Sub(l.pruned) > time.Minute {
+		l.prune(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &Bucket{rate: l.rate, tokens: l.rate.burst(), last: now}
+		l.buckets[key] = b
+	}
+	return b.take(now)
+}
+
+//prune drops the buckets that have refilled.  l.lock must be held.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, key)
+		}
+	}
+	l.pruned = now
+}
@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(Rate{PerMinute: 60, Burst: 3})
+	for i := 0; i < 3; i++ {
+		if wait := b.Take(); wait != 0 {
+			t.Fatalf("Expected token %v to be allowed, got wait: %v", i, wait)
+		}
+	}
+	wait := b.Take()
+	if wait <= 0 || wait > time.Second {
+		t.Errorf("Expected to wait up to a second, got: %v", wait)
+	}
+	b.last = b.last.Add(-2 * time.Second)
+	if wait = b.Take(); wait != 0 {
+		t.Errorf("Expected the bucket to refill, got wait: %v", wait)
+	}
+	var unlimited *Bucket = NewBucket(Rate{})
+	if unlimited != nil || unlimited.Take() != 0 {
+		t.Error("Expected an unlimited bucket to allow everything")
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := New(Rate{PerMinute: 1, Burst: 1})
+	if l.Take("Fred") != 0 || l.Take("Bob") != 0 {
+		t.Error("Expected each key to get its own bucket")
+	}
+	if l.Take("Fred") == 0 {
+		t.Error("Expected Fred to be limited")
+	}
+	l.buckets["Bob"].last = time.Now().Add(-time.Hour)
+	l.prune(time.Now())
+	if _, ok := l.buckets["Bob"]; ok {
+		t.Error("Expected the refilled bucket to be pruned")
+	}
+	if _, ok := l.buckets["Fred"]; !ok {
+		t.Error("Expected the empty bucket to be kept")
+	}
+}
@@ -0,0 +1,150 @@
+package room
+
+import (
+	"errors"
+	"github.com/DavidAFox/Chat/message"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrPollOpen = errors.New("room: A poll is already open in this room.")
+var ErrNoPoll = errors.New("room: There is no poll open in this room.")
+var ErrAlreadyVoted = errors.New("room: Already voted in this poll.")
+var ErrInvalidOption = errors.New("room: Invalid poll option.")
+
+//Poll is a question with options that clients in a room vote on.  Each account gets one vote.
+type Poll struct {
+	Question  string
+	Options   []string
+	Anonymous bool
+	Creator   string
+	Closes    time.Time //zero if the poll stays open until it is closed
+	votes     map[string]int
+	timer     *time.Timer
+}
+
+//NewPoll returns a poll.  If duration isn't zero the poll closes itself after that long.
+func NewPoll(question, creator string, options []string, anonymous bool, duration time.Duration) *Poll {
+	p := new(Poll)
+	p.Question = question
+	p.Options = options
+	p.Anonymous = anonymous
+	p.Creator = creator
+	if duration > 0 {
+		p.Closes = time.Now().Add(duration)
+	}
+	p.votes = make(map[string]int)
+	return p
+}
+
+//option returns the index of choice which may be an option's text or its number.  A choice matching an option's text is never read as a number.
+func (p *Poll) option(choice string) (int, error) {
+	for i := range p.Options {
+		if strings.EqualFold(p.Options[i], choice) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(choice)
+	if err != nil || n < 1 || n > len(p.Options) {
+		return 0, ErrInvalidOption
+	}
+	return n - 1, nil
+}
+
+//message returns a PollMessage with the current tallies.
+func (p *Poll) message(closed bool) *message.PollMessage {
+	options := make([]message.PollOption, len(p.Options))
+	for i := range options {
+		options[i] = message.PollOption{Text: p.Options[i], Voters: []string{}}
+	}
+	for name, i := range p.votes {
+		options[i].Votes++
+		if !p.Anonymous {
+			options[i].Voters = append(options[i].Voters, name)
+		}
+	}
+	for i := range options {
+		sort.Strings(options[i].Voters)
+	}
+	return message.NewPollMessage(p.Question, p.Creator, options, p.Anonymous, closed, p.Closes)
+}
+
+//Message returns a PollMessage with the poll's current tallies.
+func (p *Poll) Message() *message.PollMessage {
+	return p.message(false)
+}
+
+//Poll returns the room's open poll or nil if there isn't one.
+func (rm *Room) Poll() *Poll {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.poll
+}
+
+//StartPoll opens p in the room and sends it to the room.  Only one poll can be open at a time.
+func (rm *Room) StartPoll(p *Poll) error {
+	rm.lock.Lock()
+	if rm.poll != nil {
+		rm.lock.Unlock()
+		return ErrPollOpen
+	}
+	rm.poll = p
+	if !p.Closes.IsZero() {
+		p.timer = time.AfterFunc(p.Closes.Sub(time.Now()), func() { rm.closePoll(p) })
+	}
+	msg := p.message(false)
+	rm.lock.Unlock()
+	rm.Send(msg)
+	return nil
+}
+
+//Vote records name's vote for choice in the open poll and sends the new tallies to the room.
+func (rm *Room) Vote(name, choice string) error {
+	rm.lock.Lock()
+	p := rm.poll
+	if p == nil {
+		rm.lock.Unlock()
+		return ErrNoPoll
+	}
+	if _, ok := p.votes[name]; ok {
+		rm.lock.Unlock()
+		return ErrAlreadyVoted
+	}
+	i, err := p.option(choice)
+	if err != nil {
+		rm.lock.Unlock()
+		return err
+	}
+	p.votes[name] = i
+	msg := p.message(false)
+	rm.lock.Unlock()
+	rm.Send(msg)
+	return nil
+}
+
+//ClosePoll closes the open poll and sends the final tallies to the room.
+func (rm *Room) ClosePoll() error {
+	if !rm.closePoll(rm.Poll()) {
+		return ErrNoPoll
+	}
+	return nil
+}
+
+//closePoll closes p if it is still the room's open poll.  It returns false if it wasn't.
+func (rm *Room) closePoll(p *Poll) bool {
+	rm.lock.Lock()
+	if p == nil || rm.poll != p {
+		rm.lock.Unlock()
+		return false
+	}
+	rm.poll = nil
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	msg := p.message(true)
+	rm.lock.Unlock()
+	rm.Send(msg)
+	return true
+}
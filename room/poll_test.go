@@ -0,0 +1,180 @@
+package room
+
+import (
+	"github.com/DavidAFox/Chat/message"
+	"sync"
+	"testing"
+	"time"
+)
+
+//testClient is a room client that keeps the messages it recieves.
+type testClient struct {
+	name     string
+	lock     sync.Mutex
+	messages []message.Message
+}
+
+func newTestClient(name string) *testClient {
+	return &testClient{name: name}
+}
+
+func (cl *testClient) Equals(other Client) bool {
+	return cl.Name() == other.Name()
+}
+
+func (cl *testClient) Name() string {
+	return cl.name
+}
+
+func (cl *testClient) Recieve(m message.Message) {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	cl.messages = append(cl.messages, m)
+}
+
+//takeMessages returns the messages recieved since the last call.
+func (cl *testClient) takeMessages() []message.Message {
+	cl.lock.Lock()
+	defer cl.lock.Unlock()
+	m := cl.messages
+	cl.messages = nil
+	return m
+}
+
+//lastPoll returns the last poll message in m or nil if there isn't one.
+func lastPoll(m []message.Message) *message.PollMessage {
+	for i := len(m) - 1; i >= 0; i-- {
+		if p, ok := m[i].(*message.PollMessage); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+func TestPollVote(t *testing.T) {
+	rm := NewRoom("Lobby")
+	fred := newTestClient("Fred")
+	rm.Add(fred)
+	if err := rm.Vote("Fred", "1"); err != ErrNoPoll {
+		t.Errorf("Vote with no poll => %v, want %v", err, ErrNoPoll)
+	}
+	if err := rm.StartPoll(NewPoll("Lunch?", "Fred", []string{"Pizza", "Tacos"}, false, 0)); err != nil {
+		t.Fatalf("StartPoll => %v", err)
+	}
+	if err := rm.StartPoll(NewPoll("Dinner?", "Fred", []string{"Soup", "Salad"}, false, 0)); err != ErrPollOpen {
+		t.Errorf("second StartPoll => %v, want %v", err, ErrPollOpen)
+	}
+	var votes = []struct {
+		name   string
+		choice string
+		err    error
+	}{
+		{"Fred", "2", nil},
+		{"Bob", "pizza", nil},
+		{"Jim", "3", ErrInvalidOption},
+		{"Jim", "Burgers", ErrInvalidOption},
+		{"Jim", "0", ErrInvalidOption},
+		{"Jim", "1", nil},
+	}
+	for _, tt := range votes {
+		if err := rm.Vote(tt.name, tt.choice); err != tt.err {
+			t.Errorf("Vote(%q, %q) => %v, want %v", tt.name, tt.choice, err, tt.err)
+		}
+	}
+	p := lastPoll(fred.takeMessages())
+	if p == nil {
+		t.Fatalf("no poll message sent to the room")
+	}
+	if p.Options[0].Votes != 2 || p.Options[1].Votes != 1 {
+		t.Errorf("tallies => %d, %d, want 2, 1", p.Options[0].Votes, p.Options[1].Votes)
+	}
+	if len(p.Options[0].Voters) != 2 || p.Options[0].Voters[0] != "Bob" || p.Options[0].Voters[1] != "Jim" {
+		t.Errorf("voters for Pizza => %v, want [Bob Jim]", p.Options[0].Voters)
+	}
+}
+
+func TestPollDoubleVote(t *testing.T) {
+	rm := NewRoom("Lobby")
+	rm.StartPoll(NewPoll("Lunch?", "Fred", []string{"Pizza", "Tacos"}, true, 0))
+	if err := rm.Vote("Bob", "1"); err != nil {
+		t.Fatalf("Vote => %v", err)
+	}
+	for _, choice := range []string{"1", "2", "Tacos"} {
+		if err := rm.Vote("Bob", choice); err != ErrAlreadyVoted {
+			t.Errorf("second Vote(%q) => %v, want %v", choice, err, ErrAlreadyVoted)
+		}
+	}
+	fred := newTestClient("Fred")
+	rm.Add(fred)
+	if err := rm.ClosePoll(); err != nil {
+		t.Fatalf("ClosePoll => %v", err)
+	}
+	p := lastPoll(fred.takeMessages())
+	if p == nil || !p.Closed {
+		t.Fatalf("closed poll message not sent")
+	}
+	if p.Options[0].Votes != 1 || p.Options[1].Votes != 0 {
+		t.Errorf("tallies => %d, %d, want 1, 0", p.Options[0].Votes, p.Options[1].Votes)
+	}
+	if len(p.Options[0].Voters) != 0 {
+		t.Errorf("anonymous poll voters => %v, want none", p.Options[0].Voters)
+	}
+	if err := rm.ClosePoll(); err != ErrNoPoll {
+		t.Errorf("second ClosePoll => %v, want %v", err, ErrNoPoll)
+	}
+}
+
+func TestPollOptionTextBeforeNumber(t *testing.T) {
+	rm := NewRoom("Lobby")
+	fred := newTestClient("Fred")
+	rm.Add(fred)
+	rm.StartPoll(NewPoll("Which year?", "Fred", []string{"2024", "1", "2023"}, false, 0))
+	var votes = []struct {
+		name   string
+		choice string
+		option int
+	}{
+		{"Bob", "1", 1},
+		{"Jim", "2023", 2},
+		{"Sue", "3", 2},
+	}
+	for _, tt := range votes {
+		if err := rm.Vote(tt.name, tt.choice); err != nil {
+			t.Errorf("Vote(%q, %q) => %v", tt.name, tt.choice, err)
+		}
+		p := lastPoll(fred.takeMessages())
+		if p == nil {
+			t.Fatalf("no poll message sent to the room")
+		}
+		found := false
+		for _, name := range p.Options[tt.option].Voters {
+			found = found || name == tt.name
+		}
+		if !found {
+			t.Errorf("Vote(%q, %q) not counted for %q", tt.name, tt.choice, p.Options[tt.option].Text)
+		}
+	}
+}
+
+func TestPollAutoClose(t *testing.T) {
+	rm := NewRoom("Lobby")
+	fred := newTestClient("Fred")
+	rm.Add(fred)
+	rm.StartPoll(NewPoll("Lunch?", "Fred", []string{"Pizza", "Tacos"}, false, 50*time.Millisecond))
+	if p := rm.Poll(); p == nil || p.Closes.IsZero() {
+		t.Fatalf("poll with a duration has no closing time")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for rm.Poll() != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if rm.Poll() != nil {
+		t.Fatalf("poll still open after its duration")
+	}
+	if p := lastPoll(fred.takeMessages()); p == nil || !p.Closed {
+		t.Errorf("closed poll message not sent to the room")
+	}
+	if err := rm.Vote("Bob", "1"); err != ErrNoPoll {
+		t.Errorf("Vote after close => %v, want %v", err, ErrNoPoll)
+	}
+}
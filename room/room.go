@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/DavidAFox/Chat/message"
 	"sort"
+	"strconv"
 	"sync"
+	"time"
 )
 
 //Client interface for working with the Room type.
@@ -89,11 +91,32 @@ func (c *clientList) GetClient(name string) Client {
 	return nil
 }
 
+//Hook is given every message sent to a room.  Send must not block since it is called from Room.Send.
+type Hook interface {
+	Send(room string, m message.Message)
+}
+
+//Shadow decides which clients are shadowbanned.  Messages from shadowbanned clients are only sent back to them and aren't kept in the room's messages or passed to the hook.
+type Shadow interface {
+	Shadowbanned(name string) bool
+}
+
 //Room is a room name and a linked list of clients in the room.
 type Room struct {
 	name     string
 	clients  *clientList
 	messages *message.MessageList
+	topic    string
+	poll     *Poll
+	seq      int                  //number of messages other than joins and leaves sent to the room
+	stored   int                  //number of messages including joins and leaves sent to the room
+	members  int                  //number of times someone joined or left the room
+	epoch    string               //identifies this room so read positions from before it was closed and reopened aren't used
+	slow     time.Duration        //how long clients must wait between messages, 0 if slow mode is off
+	lastSent map[string]time.Time //when each client last sent a message while slow mode was on
+	lock     *sync.Mutex          //guards topic, poll, seq, stored, members, slow and lastSent
+	hook     Hook
+	shadow   Shadow
 }
 
 //NewRoom creates a room with name.
@@ -102,9 +125,56 @@ func NewRoom(name string) *Room {
 	newRoom.name = name
 	newRoom.clients = NewClientList()
 	newRoom.messages = message.NewMessageList()
+	newRoom.lock = new(sync.Mutex)
+	newRoom.epoch = strconv.FormatInt(time.Now().UnixNano(), 36)
 	return newRoom
 }
 
+//Topic returns the room's topic.
+func (rm *Room) Topic() string {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.topic
+}
+
+//SetTopic changes the room's topic and announces the change to the room.
+func (rm *Room) SetTopic(topic, by string) {
+	rm.lock.Lock()
+	rm.topic = topic
+	rm.lock.Unlock()
+	rm.Send(message.NewTopicMessage(topic, by))
+}
+
+//SlowMode returns how long clients must wait between messages in the room.  It is 0 if slow mode is off.
+func (rm *Room) SlowMode() time.Duration {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.slow
+}
+
+//SetSlowMode makes clients wait d between messages in the room.  0 turns slow mode off.
+func (rm *Room) SetSlowMode(d time.Duration) {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	rm.slow = d
+	rm.lastSent = make(map[string]time.Time)
+}
+
+//SlowWait returns how long name must wait before sending another message to the room.  If it returns 0 the message is counted so call it only when a message is being sent.
+func (rm *Room) SlowWait(name string) time.Duration {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	if rm.slow <= 0 {
+		return 0
+	}
+	now := time.Now()
+	if wait := rm.lastSent[name].Add(rm.slow).Sub(now); wait > 0 {
+		return wait
+	}
+	rm.lastSent[name] = now
+	return 0
+}
+
 //Equals returns true if the rooms have the same name.
 func (rm *Room) Equals(other Client) bool {
 	if c, ok := other.(*Room); ok {
@@ -129,12 +199,33 @@ func (rm *Room) Present(name string) bool {
 
 //Remove removes a client from the room.
 func (rm *Room) Remove(cl Client) bool {
-	return rm.clients.Rem(cl)
+	found := rm.clients.Rem(cl)
+	rm.lock.Lock()
+	rm.members++
+	rm.lock.Unlock()
+	return found
 }
 
 //Add adds a client to a room.
 func (rm *Room) Add(cl Client) {
 	rm.clients.Add(cl)
+	rm.lock.Lock()
+	rm.members++
+	rm.lock.Unlock()
+}
+
+//MemberVersion returns a version of the room's client list.  It changes whenever someone joins or leaves the room.
+func (rm *Room) MemberVersion() string {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.epoch + "." + strconv.Itoa(rm.members)
+}
+
+//MessageVersion returns a version of the room's messages.  It changes whenever a message is sent to the room.
+func (rm *Room) MessageVersion() string {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.epoch + "." + strconv.Itoa(rm.stored)
 }
 
 //Tell sends a string to the room from the server.
@@ -143,24 +234,122 @@ func (rm Room) Tell(s string) {
 	rm.Send(msg)
 }
 
-//Send puts the message into each client in the room's recieve function.
+//Send puts the message into each client in the room's recieve function and passes it to the room's hook if there is one.  Messages from shadowbanned clients only go back to them.
 func (rm *Room) Send(m message.Message) {
+	if rm.shadowed(m) {
+		return
+	}
+	rm.store(m)
+	for i := rm.clients.Front(); i != nil; i = i.Next() {
+		i.Value.(Client).Recieve(m)
+	}
+	if rm.hook != nil {
+		rm.hook.Send(rm.name, m)
+	}
+}
+
+//Broadcast sends m to each client in the room without keeping it in the room's messages or passing it to the hook.  It is used for ephemeral messages like typing indicators.
+func (rm *Room) Broadcast(m message.Message) {
+	if rm.shadowed(m) {
+		return
+	}
 	for i := rm.clients.Front(); i != nil; i = i.Next() {
 		i.Value.(Client).Recieve(m)
 	}
-	rm.messages.Lock()
-	rm.messages.PushBack(m)
-	rm.messages.Unlock()
 }
 
 //Recieve passes messages the room recieves to all clients in the room's client list.
 func (rm *Room) Recieve(m message.Message) {
+	if rm.shadowed(m) {
+		return
+	}
+	rm.store(m)
 	for i := rm.clients.Front(); i != nil; i = i.Next() {
 		i.Value.(Client).Recieve(m)
 	}
+}
+
+//shadowed returns true if m is from a shadowbanned client and sends it back to them if they're in the room.
+func (rm *Room) shadowed(m message.Message) bool {
+	cm, ok := m.(message.ClientMessage)
+	if !ok || rm.shadow == nil || !rm.shadow.Shadowbanned(cm.Name()) {
+		return false
+	}
+	if cl := rm.clients.GetClient(cm.Name()); cl != nil {
+		cl.Recieve(m)
+	}
+	return true
+}
+
+//store keeps m in the room's messages and counts it for unread counts unless it is a join or leave.  Sequenced messages are given the count as their number.
+func (rm *Room) store(m message.Message) {
 	rm.messages.Lock()
+	defer rm.messages.Unlock()
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
 	rm.messages.PushBack(m)
-	rm.messages.Unlock()
+	rm.stored++
+	if _, ok := m.(*message.JoinMessage); ok {
+		return
+	}
+	rm.seq++
+	if sm, ok := m.(message.Sequenced); ok {
+		sm.SetSeq(rm.seq)
+	}
+}
+
+//Context returns the message numbered seq and up to n of the messages sent before and after it.  It returns nil if the message is no longer in the room's history.
+func (rm *Room) Context(seq, n int) (msg message.Sequenced, context []message.Message) {
+	rm.messages.Lock()
+	defer rm.messages.Unlock()
+	list := make([]message.Message, 0, rm.messages.Len())
+	at := -1
+	for i := rm.messages.Front(); i != nil; i = i.Next() {
+		if sm, ok := i.Value.(message.Sequenced); ok && sm.Sequence() == seq {
+			msg, at = sm, len(list)
+		}
+		list = append(list, i.Value.(message.Message))
+	}
+	if msg == nil {
+		return nil, nil
+	}
+	start, end := at-n, at+n+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(list) {
+		end = len(list)
+	}
+	return msg, list[start:end]
+}
+
+//Latest returns the number of the last message name sent that is still in the room's history, 0 if there isn't one.
+func (rm *Room) Latest(name string) int {
+	rm.messages.Lock()
+	defer rm.messages.Unlock()
+	for i := rm.messages.Back(); i != nil; i = i.Prev() {
+		if sm, ok := i.Value.(message.Sequenced); ok && sm.Name() == name {
+			return sm.Sequence()
+		}
+	}
+	return 0
+}
+
+//Position returns the room's epoch and how many messages have been sent to it.  Clients save it to mark the room read.
+func (rm *Room) Position() (epoch string, seq int) {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	return rm.epoch, rm.seq
+}
+
+//Unread returns how many messages were sent to the room since the position seq in epoch.  If epoch isn't this room's every message is unread.
+func (rm *Room) Unread(epoch string, seq int) int {
+	rm.lock.Lock()
+	defer rm.lock.Unlock()
+	if epoch != rm.epoch || seq > rm.seq {
+		return rm.seq
+	}
+	return rm.seq - seq
 }
 
 //IsEmpty returns true if the room is empty.
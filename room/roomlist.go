@@ -3,6 +3,8 @@ package room
 import (
 	"errors"
 	"log"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,9 +13,12 @@ var ERR_ROOM_EXISTS = errors.New("A room with that name already exits.")
 
 //RoomList is a linked list of rooms with a mutex.
 type RoomList struct {
+	changes  uint64 //number of times a room was opened or closed.  Used atomically so it is kept first for alignment.
 	maxRooms int
 	*clientList
 	closeChannel chan bool
+	hook         Hook
+	shadow       Shadow
 }
 
 //NewRoomList returns an empty RoomList.
@@ -21,7 +26,7 @@ func NewRoomList(maxRooms int) *RoomList {
 	if maxRooms < 1 {
 		maxRooms = 1
 	}
-	rl := &RoomList{maxRooms, NewClientList(), make(chan bool, 1)}
+	rl := &RoomList{maxRooms: maxRooms, clientList: NewClientList(), closeChannel: make(chan bool, 1)}
 	err := rl.Add(NewRoom("Lobby")) //create default room
 	if err != nil {
 		log.Println(err)
@@ -70,6 +75,7 @@ func (rml *RoomList) CloseEmpty() {
 		entry = entry.Next()
 		if x.Value.(*Room).IsEmpty() {
 			rml.Remove(x)
+			atomic.AddUint64(&rml.changes, 1)
 		}
 	}
 }
@@ -81,10 +87,55 @@ func (rml *RoomList) Add(cl Client) error {
 	if rml.clientList.Present(cl.Name()) {
 		return ERR_ROOM_EXISTS
 	}
+	if rm, ok := cl.(*Room); ok {
+		rm.hook = rml.hook
+		rm.shadow = rml.shadow
+	}
 	rml.clientList.Add(cl)
+	atomic.AddUint64(&rml.changes, 1)
 	return nil
 }
 
+//Version returns a version of the list of rooms and who is in them.  It changes whenever a room is opened or closed or someone joins or leaves a room.
+func (rml *RoomList) Version() string {
+	members := 0
+	for i := rml.Front(); i != nil; i = i.Next() {
+		rm := i.Value.(*Room)
+		rm.lock.Lock()
+		members += rm.members
+		rm.lock.Unlock()
+	}
+	return strconv.FormatUint(atomic.LoadUint64(&rml.changes), 10) + "." + strconv.Itoa(members)
+}
+
+//MessageVersion returns a version of the messages in every room.  It changes whenever a message is sent to any room or a room is opened or closed.
+func (rml *RoomList) MessageVersion() string {
+	stored := 0
+	for i := rml.Front(); i != nil; i = i.Next() {
+		rm := i.Value.(*Room)
+		rm.lock.Lock()
+		stored += rm.stored
+		rm.lock.Unlock()
+	}
+	return strconv.FormatUint(atomic.LoadUint64(&rml.changes), 10) + "." + strconv.Itoa(stored)
+}
+
+//SetHook sets the hook given the messages sent to every room in the list including rooms added later.  It should be set before clients start using the rooms.
+func (rml *RoomList) SetHook(h Hook) {
+	rml.hook = h
+	for i := rml.Front(); i != nil; i = i.Next() {
+		i.Value.(*Room).hook = h
+	}
+}
+
+//SetShadow sets what decides which clients are shadowbanned for every room in the list including rooms added later.  It should be set before clients start using the rooms.
+func (rml *RoomList) SetShadow(s Shadow) {
+	rml.shadow = s
+	for i := rml.Front(); i != nil; i = i.Next() {
+		i.Value.(*Room).shadow = s
+	}
+}
+
 func (rml *RoomList) roomManager() {
 	for {
 		for i := rml.clientList.Front(); i != nil; {
@@ -94,6 +145,7 @@ func (rml *RoomList) roomManager() {
 					i = i.Next()
 					rml.clientList.Remove(x)
 					rml.clientList.count--
+					atomic.AddUint64(&rml.changes, 1)
 				} else {
 					i = i.Next()
 				}
@@ -0,0 +1,51 @@
+package room
+
+import (
+	"github.com/DavidAFox/Chat/message"
+	"testing"
+)
+
+//testShadow shadowbans the names in it.
+type testShadow map[string]bool
+
+func (s testShadow) Shadowbanned(name string) bool {
+	return s[name]
+}
+
+//testHook keeps the messages it is given.
+type testHook []message.Message
+
+func (h *testHook) Send(room string, m message.Message) {
+	*h = append(*h, m)
+}
+
+func TestShadowedSend(t *testing.T) {
+	rml := NewRoomList(10)
+	hook := new(testHook)
+	rml.SetHook(hook)
+	rml.SetShadow(testShadow{"Bob": true})
+	rm := rml.FindRoom("Lobby")
+	fred, bob := newTestClient("Fred"), newTestClient("Bob")
+	rm.Add(fred)
+	rm.Add(bob)
+	rm.Send(message.NewSendMessage("buy my stuff", "Bob"))
+	if m := bob.takeMessages(); len(m) != 1 {
+		t.Errorf("shadowbanned sender recieved %v, want their message", m)
+	}
+	if m := fred.takeMessages(); len(m) != 0 {
+		t.Errorf("other client recieved %v from a shadowbanned sender", m)
+	}
+	if m := rm.GetMessages(); len(m) != 0 {
+		t.Errorf("shadowed message kept in the room: %v", m)
+	}
+	if len(*hook) != 0 {
+		t.Errorf("shadowed message passed to the hook: %v", *hook)
+	}
+	if _, seq := rm.Position(); seq != 0 {
+		t.Errorf("shadowed message counted as unread, position %d", seq)
+	}
+	rm.Send(message.NewSendMessage("hi", "Fred"))
+	if len(fred.takeMessages()) != 1 || len(bob.takeMessages()) != 1 || len(*hook) != 1 {
+		t.Errorf("message from a client who isn't shadowbanned not sent to everyone")
+	}
+}
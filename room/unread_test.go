@@ -0,0 +1,45 @@
+package room
+
+import (
+	"github.com/DavidAFox/Chat/message"
+	"testing"
+)
+
+func TestUnreadPosition(t *testing.T) {
+	rm := NewRoom("Lobby")
+	epoch, seq := rm.Position()
+	if seq != 0 {
+		t.Errorf("Position of a new room => %d, want 0", seq)
+	}
+	rm.Send(message.NewSendMessage("hi", "Fred"))
+	rm.Send(message.NewSendMessage("hello", "Bob"))
+	rm.Send(message.NewJoinMessage("Jim"))
+	rm.Send(message.NewLeaveMessage("Jim"))
+	if n := rm.Unread(epoch, seq); n != 2 {
+		t.Errorf("Unread after two messages and a join and leave => %d, want 2", n)
+	}
+	epoch, seq = rm.Position()
+	if seq != 2 {
+		t.Errorf("Position => %d, want 2", seq)
+	}
+	if n := rm.Unread(epoch, seq); n != 0 {
+		t.Errorf("Unread at the current position => %d, want 0", n)
+	}
+	rm.Send(message.NewSendMessage("are you there?", "Fred"))
+	if n := rm.Unread(epoch, seq); n != 1 {
+		t.Errorf("Unread after one more message => %d, want 1", n)
+	}
+}
+
+func TestUnreadOtherEpoch(t *testing.T) {
+	rm := NewRoom("Lobby")
+	rm.Send(message.NewSendMessage("hi", "Fred"))
+	rm.Send(message.NewSendMessage("hello", "Bob"))
+	epoch, _ := rm.Position()
+	if n := rm.Unread("closed"+epoch, 1); n != 2 {
+		t.Errorf("Unread with a position from a closed room => %d, want 2", n)
+	}
+	if n := rm.Unread(epoch, 5); n != 2 {
+		t.Errorf("Unread with a position past the room's messages => %d, want 2", n)
+	}
+}
@@ -0,0 +1,176 @@
+package scheduler
+
+/*
+Package scheduler sends reminders and scheduled room messages when they come due.  Items are stored through clientdata so they survive restarts, anything that came due while the server was down is sent as soon as it starts again.  Reminders are sent to the owner as a tell if they are online and saved to their mailbox if they aren't.
+*/
+
+import (
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+//ReminderSender is the name reminders are sent from.
+const ReminderSender = "Reminder"
+
+//DefaultInterval is how often the scheduler checks for items that are due.
+const DefaultInterval = time.Second
+
+//Gate checks a scheduled room message when it comes due the way messages clients send are checked.  It returns the text to send, which the content filter may have changed, or an error saying why the message can't be sent.
+type Gate interface {
+	CheckScheduled(owner string, rm *room.Room, text string) (string, error)
+}
+
+//Scheduler sends scheduled items when they are due.
+type Scheduler struct {
+	rooms    *room.RoomList
+	chatlog  io.Writer
+	data     clientdata.Factory
+	gate     Gate
+	interval time.Duration
+	now      func() time.Time
+	done     chan bool
+	once     *sync.Once
+}
+
+//New returns a Scheduler that checks for due items every interval.  A zero interval uses DefaultInterval.
+func New(rooms *room.RoomList, chatlog io.Writer, data clientdata.Factory, interval time.Duration) *Scheduler {
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+	s := new(Scheduler)
+	s.rooms = rooms
+	s.chatlog = chatlog
+	s.data = data
+	s.interval = interval
+	s.now = time.Now
+	s.done = make(chan bool)
+	s.once = new(sync.Once)
+	return s
+}
+
+//SetGate sets the Gate scheduled room messages must pass.  It must be called before Start.
+func (s *Scheduler) SetGate(g Gate) {
+	s.gate = g
+}
+
+//Start reserves ReminderSender and starts checking for due items.
+func (s *Scheduler) Start() {
+	s.reserve()
+	go s.run()
+}
+
+//reserve reserves ReminderSender as a bot account of the server so nobody can register it and forge reminders.
+func (s *Scheduler) reserve() {
+	err := s.data.Create(clientdata.ServerRecord).CreateBot(ReminderSender)
+	if err == clientdata.ErrClientExists {
+		if owner, _ := s.data.Create("").BotOwner(ReminderSender); owner != clientdata.ServerRecord {
+			log.Printf("scheduler: %v is also the name of an account", ReminderSender)
+		}
+	} else if err != nil {
+		log.Printf("scheduler: Error reserving name %v: %v", ReminderSender, err)
+	}
+}
+
+//Stop stops the scheduler.  Items that haven't been sent stay stored.
+func (s *Scheduler) Stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+//run sends due items every interval until the scheduler is stopped.
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.fire()
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//fire sends every item that is due.  Items are removed before they are sent so they are never sent twice.
+func (s *Scheduler) fire() {
+	items, err := s.data.Create("").ScheduledItems("")
+	if err != nil {
+		log.Println("Error getting scheduled items: ", err)
+		return
+	}
+	now := s.now()
+	for _, item := range items {
+		if item.At.After(now) {
+			break
+		}
+		err = s.data.Create(item.Owner).Unschedule(item.ID)
+		if err == clientdata.ErrScheduleNotFound {
+			continue
+		}
+		if err != nil {
+			log.Println("Error removing scheduled item: ", err)
+			continue
+		}
+		switch item.Kind {
+		case clientdata.ScheduleRemind:
+			s.remind(item)
+		case clientdata.ScheduleRoom:
+			s.post(item)
+		default:
+			log.Println("Unknown scheduled item kind: ", item.Kind)
+		}
+	}
+}
+
+//remind sends a reminder to its owner as a tell or to their mailbox if they are offline.
+func (s *Scheduler) remind(item clientdata.ScheduledItem) {
+	if cl := s.rooms.GetClient(item.Owner); cl != nil {
+		cl.Recieve(message.NewTellMessage(item.Text, ReminderSender, item.Owner, true))
+		return
+	}
+	err := s.data.Create(ReminderSender).SendMail(item.Owner, item.Text)
+	if err != nil {
+		log.Println("Error saving reminder: ", err)
+	}
+}
+
+//post sends a scheduled message to its room as the owner.  If the room has closed, the owner has been banned or the message doesn't pass the gate the owner is told it wasn't sent.
+func (s *Scheduler) post(item clientdata.ScheduledItem) {
+	rm := s.rooms.FindRoom(item.Target)
+	if rm == nil {
+		s.notSent(item, "the room is closed")
+		return
+	}
+	ban, err := s.data.Create("").CheckBan(item.Owner, "")
+	if err != nil {
+		log.Println("Error checking ban: ", err)
+		return
+	}
+	if ban != nil {
+		return
+	}
+	text := item.Text
+	if s.gate != nil {
+		text, err = s.gate.CheckScheduled(item.Owner, rm, item.Text)
+		if err != nil {
+			s.notSent(item, err.Error())
+			return
+		}
+	}
+	msg := message.NewSendMessage(text, item.Owner)
+	rm.Send(msg)
+	_, err = io.WriteString(s.chatlog, msg.String()+"\n")
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+//notSent tells the owner of a scheduled message why it wasn't sent.
+func (s *Scheduler) notSent(item clientdata.ScheduledItem, reason string) {
+	s.remind(clientdata.ScheduledItem{Owner: item.Owner, Text: fmt.Sprintf("Your scheduled message to %v was not sent because %v: %v", item.Target, reason, item.Text)})
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/room"
+	"strings"
+	"testing"
+	"time"
+)
+
+//testClient records the messages it recieves.
+type testClient struct {
+	name     string
+	messages []message.Message
+}
+
+func (cl *testClient) Equals(other room.Client) bool { return cl.Name() == other.Name() }
+func (cl *testClient) Name() string                  { return cl.name }
+func (cl *testClient) Recieve(m message.Message)     { cl.messages = append(cl.messages, m) }
+
+func newTestScheduler() (*Scheduler, *room.RoomList, clientdata.Factory, *time.Time) {
+	rooms := room.NewRoomList(10)
+	df := filedata.NewMemDataFactory()
+	s := New(rooms, new(bytes.Buffer), df, 0)
+	now := time.Date(2016, 4, 6, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, rooms, df, &now
+}
+
+func TestRemindOnlineClient(t *testing.T) {
+	s, rooms, df, now := newTestScheduler()
+	fred := &testClient{name: "Fred"}
+	rooms.FindRoom("Lobby").Add(fred)
+	_, err := df.Create("Fred").Schedule(clientdata.ScheduleRemind, "Fred", "stand up", now.Add(time.Minute))
+	if err != nil {
+		t.Fatal("Error scheduling: ", err)
+	}
+	s.fire()
+	if len(fred.messages) != 0 {
+		t.Fatal("Reminder sent before it was due")
+	}
+	*now = now.Add(time.Minute)
+	s.fire()
+	s.fire()
+	if len(fred.messages) != 1 {
+		t.Fatalf("Expected one reminder, got: %v", fred.messages)
+	}
+	tell, ok := fred.messages[0].(*message.TellMessage)
+	if !ok || tell.Text != "stand up" || tell.Sender != ReminderSender {
+		t.Errorf("Wrong reminder: %+v", fred.messages[0])
+	}
+	items, _ := df.Create("Fred").ScheduledItems("Fred")
+	if len(items) != 0 {
+		t.Error("Expected the reminder to be removed once sent")
+	}
+}
+
+func TestRemindOfflineClientGoesToMailbox(t *testing.T) {
+	s, _, df, now := newTestScheduler()
+	_, _ = df.Create("Fred").Schedule(clientdata.ScheduleRemind, "Fred", "stand up", now.Add(-time.Hour))
+	s.fire()
+	mail, err := df.Create("Fred").TakeMail()
+	if err != nil || len(mail) != 1 || mail[0].Text != "stand up" || mail[0].From != ReminderSender {
+		t.Fatalf("Expected the reminder in the mailbox, got: %v %v", mail, err)
+	}
+	if mail, _ = df.Create("Fred").TakeMail(); len(mail) != 0 {
+		t.Error("Expected TakeMail to empty the mailbox")
+	}
+}
+
+func TestScheduledRoomMessage(t *testing.T) {
+	s, rooms, df, now := newTestScheduler()
+	bob := &testClient{name: "Bob"}
+	rooms.FindRoom("Lobby").Add(bob)
+	_, _ = df.Create("Fred").Schedule(clientdata.ScheduleRoom, "Lobby", "standup time", *now)
+	_, _ = df.Create("Fred").Schedule(clientdata.ScheduleRoom, "Closed", "hello?", *now)
+	s.fire()
+	if len(bob.messages) != 1 {
+		t.Fatalf("Expected one message in the room, got: %v", bob.messages)
+	}
+	msg, ok := bob.messages[0].(*message.SendMessage)
+	if !ok || msg.Text != "standup time" || msg.Sender != "Fred" {
+		t.Errorf("Wrong room message: %+v", bob.messages[0])
+	}
+	if mail, _ := df.Create("Fred").TakeMail(); len(mail) != 1 {
+		t.Errorf("Expected Fred to be told the message to the closed room wasn't sent, got: %v", mail)
+	}
+}
+
+func TestUnschedule(t *testing.T) {
+	_, _, df, now := newTestScheduler()
+	item, _ := df.Create("Fred").Schedule(clientdata.ScheduleRemind, "Fred", "stand up", *now)
+	if err := df.Create("Bob").Unschedule(item.ID); err != clientdata.ErrScheduleNotFound {
+		t.Error("Expected only the owner to be able to unschedule, got: ", err)
+	}
+	if err := df.Create("Fred").Unschedule(item.ID); err != nil {
+		t.Error("Error unscheduling: ", err)
+	}
+}
+
+//refuseGate refuses every message with the same reason.
+type refuseGate struct{}
+
+func (refuseGate) CheckScheduled(owner string, rm *room.Room, text string) (string, error) {
+	return "", errors.New("you are muted")
+}
+
+func TestScheduledRoomMessageChecked(t *testing.T) {
+	s, rooms, df, now := newTestScheduler()
+	bob := &testClient{name: "Bob"}
+	rooms.FindRoom("Lobby").Add(bob)
+	s.SetGate(refuseGate{})
+	_, _ = df.Create("Fred").Schedule(clientdata.ScheduleRoom, "Lobby", "spam", *now)
+	_, _ = df.Create("Sue").Schedule(clientdata.ScheduleRoom, "Lobby", "more spam", *now)
+	_ = df.Create("Admin").Ban(clientdata.BanAccount, "Sue", "", time.Time{})
+	s.fire()
+	if len(bob.messages) != 0 {
+		t.Fatalf("Expected nothing to be sent, got: %v", bob.messages)
+	}
+	if mail, _ := df.Create("Fred").TakeMail(); len(mail) != 1 || !strings.Contains(mail[0].Text, "you are muted") {
+		t.Errorf("Expected Fred to be told why the message wasn't sent, got: %v", mail)
+	}
+}
+
+func TestStartReservesReminderSender(t *testing.T) {
+	s, _, df, _ := newTestScheduler()
+	s.Start()
+	defer s.Stop()
+	if owner, err := df.Create("").BotOwner(ReminderSender); err != nil || owner != clientdata.ServerRecord {
+		t.Errorf("Expected %v to be reserved by the server, got: %q %v", ReminderSender, owner, err)
+	}
+	if err := df.Create(ReminderSender).NewClient("password"); err != clientdata.ErrClientExists {
+		t.Error("Expected the name to be taken, got: ", err)
+	}
+}
@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
+	"github.com/DavidAFox/Chat/room"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//IncomingPath is the path incoming webhooks are served under.  A webhook's URL is IncomingPath followed by its token.
+const IncomingPath = "/hooks/"
+
+//maxIncomingSize is the largest request body accepted from an incoming webhook.
+const maxIncomingSize = 64 * 1024
+
+//maxUsernameLength is the longest username an incoming webhook can post as.
+const maxUsernameLength = 32
+
+//SlackField is a field in a Slack attachment.
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+//SlackAttachment is a Slack message attachment.  Only the text parts are used.
+type SlackAttachment struct {
+	Fallback  string       `json:"fallback"`
+	Pretext   string       `json:"pretext"`
+	Title     string       `json:"title"`
+	TitleLink string       `json:"title_link"`
+	Text      string       `json:"text"`
+	Fields    []SlackField `json:"fields"`
+}
+
+//SlackPayload is the body of a Slack style incoming webhook request.
+type SlackPayload struct {
+	Text        string            `json:"text"`
+	Username    string            `json:"username"`
+	Attachments []SlackAttachment `json:"attachments"`
+}
+
+//String renders the payload and its attachments as plain text.
+func (p *SlackPayload) String() string {
+	lines := make([]string, 0, 1+len(p.Attachments))
+	if p.Text != "" {
+		lines = append(lines, p.Text)
+	}
+	for _, a := range p.Attachments {
+		if s := a.String(); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+//String renders the attachment as plain text.  The fallback text is used if there is one since it is meant for clients that can't show attachments.
+func (a *SlackAttachment) String() string {
+	if a.Fallback != "" {
+		return a.Fallback
+	}
+	lines := make([]string, 0, 3+len(a.Fields))
+	if a.Pretext != "" {
+		lines = append(lines, a.Pretext)
+	}
+	switch {
+	case a.Title != "" && a.TitleLink != "":
+		lines = append(lines, a.Title+" ("+a.TitleLink+")")
+	case a.Title != "":
+		lines = append(lines, a.Title)
+	}
+	if a.Text != "" {
+		lines = append(lines, a.Text)
+	}
+	for _, f := range a.Fields {
+		lines = append(lines, f.Title+": "+f.Value)
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+//IncomingHandler is the http.Handler for incoming webhooks.  It accepts Slack style payloads posted to a webhook's secret URL and sends them to the webhook's room.
+type IncomingHandler struct {
+	rooms   *room.RoomList
+	chatlog io.Writer
+	data    clientdata.Factory
+	filter  Filter
+	limit   *ratelimit.Limiter
+}
+
+//Filter checks the text name is sending to room.  It returns the text to send or an error if the message is rejected.
+type Filter func(name, room, text string) (string, error)
+
+//NewIncomingHandler returns a new IncomingHandler.
+func NewIncomingHandler(rooms *room.RoomList, chatlog io.Writer, data clientdata.Factory) *IncomingHandler {
+	h := new(IncomingHandler)
+	h.rooms = rooms
+	h.chatlog = chatlog
+	h.data = data
+	return h
+}
+
+//SetLimiter sets the limiter for how often each webhook can post.
+func (h *IncomingHandler) SetLimiter(l *ratelimit.Limiter) {
+	h.limit = l
+}
+
+//SetFilter sets the filter webhook messages are checked with before they are sent.
+func (h *IncomingHandler) SetFilter(f Filter) {
+	h.filter = f
+}
+
+//reply writes a Slack style plain text response.
+func reply(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	_, err := io.WriteString(w, body)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+//ServeHTTP handles incoming webhook requests.  The payload may be sent as a JSON body or as the payload field of a form like Slack allows.
+func (h *IncomingHandler) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
+	if rq.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		reply(w, http.StatusMethodNotAllowed, "invalid_method")
+		return
+	}
+	hook, err := h.data.Create("").AuthenticateIncomingWebhook(strings.TrimPrefix(rq.URL.Path, IncomingPath))
+	if err != nil {
+		log.Println("Error authenticating incoming webhook: ", err)
+		reply(w, http.StatusInternalServerError, "server_error")
+		return
+	}
+	if hook == nil {
+		reply(w, http.StatusNotFound, "no_service")
+		return
+	}
+	if wait := h.limit.Take("hook " + hook.ID); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+		reply(w, http.StatusTooManyRequests, "rate_limited")
+		return
+	}
+	rq.Body = http.MaxBytesReader(w, rq.Body, maxIncomingSize)
+	payload := new(SlackPayload)
+	if strings.HasPrefix(rq.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		err = json.Unmarshal([]byte(rq.PostFormValue("payload")), payload)
+	} else {
+		err = json.NewDecoder(rq.Body).Decode(payload)
+	}
+	if err != nil {
+		reply(w, http.StatusBadRequest, "invalid_payload")
+		return
+	}
+	text := payload.String()
+	if text == "" {
+		reply(w, http.StatusBadRequest, "no_text")
+		return
+	}
+	rm := h.rooms.FindRoom(hook.Room)
+	if rm == nil {
+		reply(w, http.StatusNotFound, "channel_not_found")
+		return
+	}
+	name := username(payload.Username, hook)
+	if h.filter != nil {
+		if text, err = h.filter(name, rm.Name(), text); err != nil {
+			reply(w, http.StatusUnprocessableEntity, "message_blocked")
+			return
+		}
+	}
+	msg := message.NewWebhookMessage(text, name)
+	rm.Send(msg)
+	_, err = io.WriteString(h.chatlog, msg.String()+"\n")
+	if err != nil {
+		log.Println(err)
+	}
+	reply(w, http.StatusOK, "ok")
+}
+
+//username returns the name to post as.  The payload's username is used if it has one, otherwise the webhook's.
+func username(name string, hook *clientdata.IncomingWebhook) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = hook.Username
+	}
+	if name == "" {
+		name = "webhook"
+	}
+	if r := []rune(name); len(r) > maxUsernameLength {
+		name = string(r[:maxUsernameLength])
+	}
+	return name
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"github.com/DavidAFox/Chat/ratelimit"
+	"github.com/DavidAFox/Chat/room"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//testClient records the messages it recieves.
+type testClient struct {
+	messages []message.Message
+}
+
+func (cl *testClient) Equals(other room.Client) bool { return cl.Name() == other.Name() }
+func (cl *testClient) Name() string                  { return "listener" }
+func (cl *testClient) Recieve(m message.Message)     { cl.messages = append(cl.messages, m) }
+
+//last returns the last message recieved.
+func (cl *testClient) last() message.Message {
+	if len(cl.messages) == 0 {
+		return nil
+	}
+	return cl.messages[len(cl.messages)-1]
+}
+
+func newTestIncomingHandler(t *testing.T) (*IncomingHandler, *testClient, string) {
+	df := filedata.NewMemDataFactory()
+	token, _, err := df.Create("Fred").AddIncomingWebhook("Lobby", "alerts")
+	if err != nil {
+		t.Fatal("Error adding incoming webhook: ", err)
+	}
+	rooms := room.NewRoomList(10)
+	cl := new(testClient)
+	rooms.FindRoom("Lobby").Add(cl)
+	return NewIncomingHandler(rooms, new(bytes.Buffer), df), cl, token
+}
+
+func TestIncomingPostsToRoom(t *testing.T) {
+	h, cl, token := newTestIncomingHandler(t)
+	body := `{"text":"Build failed","username":"ci","attachments":[{"title":"Build 42","title_link":"http://ci/42","fields":[{"title":"Branch","value":"master"}]}]}`
+	rq := httptest.NewRequest("POST", IncomingPath+token, strings.NewReader(body))
+	rq.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, rq)
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("Wrong response expected: 200 ok, got: %v %v", w.Code, w.Body.String())
+	}
+	msg, ok := cl.last().(*message.WebhookMessage)
+	if !ok {
+		t.Fatal("Expected a WebhookMessage in the room")
+	}
+	expected := "Build failed\r\nBuild 42 (http://ci/42)\r\nBranch: master"
+	if msg.Name != "ci" || msg.Text != expected {
+		t.Errorf("Wrong message expected: ci %q, got: %v %q", expected, msg.Name, msg.Text)
+	}
+}
+
+func TestIncomingFormPayload(t *testing.T) {
+	h, cl, token := newTestIncomingHandler(t)
+	form := url.Values{"payload": {`{"attachments":[{"fallback":"Disk full","text":"ignored"}]}`}}
+	rq := httptest.NewRequest("POST", IncomingPath+token, strings.NewReader(form.Encode()))
+	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, rq)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Wrong status expected: 200, got: %v %v", w.Code, w.Body.String())
+	}
+	msg, ok := cl.last().(*message.WebhookMessage)
+	if !ok || msg.Name != "alerts" || msg.Text != "Disk full" {
+		t.Errorf("Wrong message expected: alerts Disk full, got: %+v", msg)
+	}
+}
+
+func TestIncomingRejectsBadRequests(t *testing.T) {
+	h, _, token := newTestIncomingHandler(t)
+	var tests = []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"POST", IncomingPath + "nothere.abc", `{"text":"hi"}`, http.StatusNotFound},
+		{"POST", IncomingPath + token + "x", `{"text":"hi"}`, http.StatusNotFound},
+		{"GET", IncomingPath + token, "", http.StatusMethodNotAllowed},
+		{"POST", IncomingPath + token, `not json`, http.StatusBadRequest},
+		{"POST", IncomingPath + token, `{"text":""}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if w.Code != tt.status {
+			t.Errorf("%v %v %q expected: %v, got: %v", tt.method, tt.path, tt.body, tt.status, w.Code)
+		}
+	}
+}
+
+func TestIncomingFiltered(t *testing.T) {
+	h, cl, token := newTestIncomingHandler(t)
+	h.SetFilter(func(name, room, text string) (string, error) {
+		if strings.Contains(text, "spam") {
+			return "", errors.New("blocked")
+		}
+		return strings.ToUpper(text), nil
+	})
+	post := func(text string) int {
+		rq := httptest.NewRequest("POST", IncomingPath+token, strings.NewReader(`{"text":"`+text+`"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, rq)
+		return w.Code
+	}
+	if code := post("buy spam"); code != http.StatusUnprocessableEntity || cl.last() != nil {
+		t.Errorf("Expected a rejected message not to be sent, got: %v %v", code, cl.last())
+	}
+	if code := post("deploy done"); code != http.StatusOK {
+		t.Fatalf("Wrong status expected: 200, got: %v", code)
+	}
+	if msg, ok := cl.last().(*message.WebhookMessage); !ok || msg.Text != "DEPLOY DONE" {
+		t.Errorf("Expected the filtered text to be sent, got: %v", cl.last())
+	}
+}
+
+func TestIncomingRateLimited(t *testing.T) {
+	h, _, token := newTestIncomingHandler(t)
+	h.SetLimiter(ratelimit.New(ratelimit.Rate{PerMinute: 1, Burst: 1}))
+	codes := make([]int, 0, 2)
+	for i := 0; i < 2; i++ {
+		rq := httptest.NewRequest("POST", IncomingPath+token, strings.NewReader(`{"text":"hi"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, rq)
+		codes = append(codes, w.Code)
+		if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Error("Expected a Retry-After header")
+		}
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected the second post to be limited, got: %v", codes)
+	}
+}
@@ -0,0 +1,256 @@
+package webhook
+
+/*
+Package webhook posts room events to outgoing webhook subscriptions.  A Dispatcher is set as the hook on the RoomList so it is given every message sent to a room.  Events are put on a bounded queue and delivered by a pool of workers so a slow or dead endpoint never blocks Room.Send.  When the queue is full events are dropped and logged.  Failed deliveries are retried with exponential backoff.  Each request body is signed with HMAC-SHA256 using the subscription's secret and the signature is sent in the X-Chat-Signature header as sha256=<hex>.
+
+The package also serves incoming webhooks.  An IncomingHandler accepts Slack style payloads posted to a secret URL and sends them to the webhook's room as a WebhookMessage.
+*/
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/message"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//Headers set on every delivery.
+const (
+	SignatureHeader = "X-Chat-Signature"
+	EventHeader     = "X-Chat-Event"
+)
+
+//Events that are posted to webhooks.
+const (
+	EventMessage = "message"
+	EventJoin    = "join"
+	EventLeave   = "leave"
+	EventTopic   = "topic"
+)
+
+//Payload is the JSON body posted to a webhook.
+type Payload struct {
+	Event   string
+	Room    string
+	Time    time.Time
+	Message message.Message
+}
+
+//Options configures a Dispatcher.  Zero values are replaced with the defaults.
+type Options struct {
+	QueueSize     int           //events and retries waiting for delivery
+	Workers       int           //number of concurrent deliveries
+	MaxAttempts   int           //attempts before a delivery is given up
+	RetryDelay    time.Duration //delay before the first retry
+	MaxRetryDelay time.Duration //cap on the exponential backoff
+	Timeout       time.Duration //timeout for each request
+	Client        *http.Client  //client used for requests
+}
+
+//DefaultOptions are the options used for any values left zero.
+var DefaultOptions = Options{
+	QueueSize:     1000,
+	Workers:       4,
+	MaxAttempts:   5,
+	RetryDelay:    time.Second,
+	MaxRetryDelay: 5 * time.Minute,
+	Timeout:       10 * time.Second,
+}
+
+//job is an event waiting to be delivered.  hook is nil until the room's subscriptions have been looked up.
+type job struct {
+	room    string
+	event   string
+	body    []byte
+	hook    *clientdata.Webhook
+	attempt int
+}
+
+//Dispatcher delivers room events to the webhooks subscribed to the room.
+type Dispatcher struct {
+	data    clientdata.Factory
+	queue   chan *job
+	done    chan bool
+	once    *sync.Once
+	options Options
+}
+
+//New returns a Dispatcher that looks up subscriptions in data and starts its workers.
+func New(data clientdata.Factory, options Options) *Dispatcher {
+	if options.QueueSize == 0 {
+		options.QueueSize = DefaultOptions.QueueSize
+	}
+	if options.Workers == 0 {
+		options.Workers = DefaultOptions.Workers
+	}
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = DefaultOptions.MaxAttempts
+	}
+	if options.RetryDelay == 0 {
+		options.RetryDelay = DefaultOptions.RetryDelay
+	}
+	if options.MaxRetryDelay == 0 {
+		options.MaxRetryDelay = DefaultOptions.MaxRetryDelay
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultOptions.Timeout
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: options.Timeout}
+	}
+	d := new(Dispatcher)
+	d.data = data
+	d.queue = make(chan *job, options.QueueSize)
+	d.done = make(chan bool)
+	d.once = new(sync.Once)
+	d.options = options
+	for i := 0; i < options.Workers; i++ {
+		go d.worker()
+	}
+	return d
+}
+
+//Close stops the workers.  Events still in the queue are dropped.
+func (d *Dispatcher) Close() {
+	d.once.Do(func() { close(d.done) })
+}
+
+//EventType returns the webhook event for m or "" if m isn't posted to webhooks.
+func EventType(m message.Message) string {
+	switch msg := m.(type) {
+	case *message.SendMessage, *message.EmoteMessage, *message.AttachmentMessage, *message.RestMessage, *message.WebhookMessage:
+		return EventMessage
+	case *message.JoinMessage:
+		if msg.Left {
+			return EventLeave
+		}
+		return EventJoin
+	case *message.TopicMessage:
+		return EventTopic
+	}
+	return ""
+}
+
+//Sign returns the signature header value for body signed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//Verify returns true if signature is a valid signature of body for secret.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+//Send queues m for delivery to the webhooks subscribed to room.  It never blocks.
+func (d *Dispatcher) Send(room string, m message.Message) {
+	event := EventType(m)
+	if event == "" {
+		return
+	}
+	body, err := json.Marshal(Payload{Event: event, Room: room, Time: time.Now(), Message: m})
+	if err != nil {
+		log.Println("Error encoding webhook payload: ", err)
+		return
+	}
+	d.enqueue(&job{room: room, event: event, body: body})
+}
+
+//enqueue puts j on the queue unless the queue is full or the Dispatcher is closed.
+func (d *Dispatcher) enqueue(j *job) {
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+	select {
+	case d.queue <- j:
+	default:
+		log.Printf("webhook: queue full, dropping %v event for room %v", j.event, j.room)
+	}
+}
+
+//worker delivers jobs from the queue until the Dispatcher is closed.
+func (d *Dispatcher) worker() {
+	for {
+		select {
+		case <-d.done:
+			return
+		case j := <-d.queue:
+			if j.hook == nil {
+				d.fanOut(j)
+			} else {
+				d.attempt(j)
+			}
+		}
+	}
+}
+
+//fanOut delivers j to each webhook subscribed to its room.
+func (d *Dispatcher) fanOut(j *job) {
+	hooks, err := d.data.Create("").Webhooks(j.room)
+	if err != nil {
+		log.Println("Error getting webhooks: ", err)
+		return
+	}
+	for i := range hooks {
+		d.attempt(&job{room: j.room, event: j.event, body: j.body, hook: &hooks[i]})
+	}
+}
+
+//attempt delivers j and schedules a retry if it fails.
+func (d *Dispatcher) attempt(j *job) {
+	err := d.deliver(j)
+	if err == nil {
+		return
+	}
+	j.attempt++
+	if j.attempt >= d.options.MaxAttempts {
+		log.Printf("webhook: giving up on %v after %v attempts: %v", j.hook.ID, j.attempt, err)
+		return
+	}
+	time.AfterFunc(d.backoff(j.attempt), func() { d.enqueue(j) })
+}
+
+//backoff returns how long to wait before the retry following attempt failures.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.options.RetryDelay
+	for i := 1; i < attempt && delay < d.options.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.options.MaxRetryDelay {
+		delay = d.options.MaxRetryDelay
+	}
+	return delay
+}
+
+//deliver posts j to its webhook.  Any response other than 2xx is an error.
+func (d *Dispatcher) deliver(j *job) error {
+	rq, err := http.NewRequest("POST", j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return err
+	}
+	rq.Header.Set("Content-Type", "application/json")
+	rq.Header.Set(EventHeader, j.event)
+	rq.Header.Set(SignatureHeader, Sign(j.hook.Secret, j.body))
+	resp, err := d.options.Client.Do(rq)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %v returned %v", j.hook.URL, resp.Status)
+	}
+	return nil
+}
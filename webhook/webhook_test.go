@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"encoding/json"
+	"github.com/DavidAFox/Chat/clientdata"
+	"github.com/DavidAFox/Chat/clientdata/filedata"
+	"github.com/DavidAFox/Chat/message"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type delivery struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+//newTestServer returns a server that sends each delivery on the returned channel and replies with the next status from statuses, then 200.
+func newTestServer(statuses ...int) (*httptest.Server, chan delivery) {
+	deliveries := make(chan delivery, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		body, _ := ioutil.ReadAll(rq.Body)
+		deliveries <- delivery{rq.Header.Get(EventHeader), rq.Header.Get(SignatureHeader), body}
+		if len(statuses) > 0 {
+			w.WriteHeader(statuses[0])
+			statuses = statuses[1:]
+		}
+	}))
+	return srv, deliveries
+}
+
+func newTestDispatcher(t *testing.T, url string, options Options) (*Dispatcher, *clientdata.Webhook) {
+	df := filedata.NewMemDataFactory()
+	hook, err := df.Create("Fred").AddWebhook("Lobby", url)
+	if err != nil {
+		t.Fatal("Error adding webhook: ", err)
+	}
+	return New(df, options), hook
+}
+
+func waitDelivery(t *testing.T, deliveries chan delivery) delivery {
+	select {
+	case d := <-deliveries:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for webhook delivery")
+	}
+	return delivery{}
+}
+
+func TestDeliverSignedPayload(t *testing.T) {
+	srv, deliveries := newTestServer()
+	defer srv.Close()
+	d, hook := newTestDispatcher(t, srv.URL, Options{Workers: 1})
+	defer d.Close()
+	d.Send("Lobby", message.NewSendMessage("hello", "Fred"))
+	d.Send("Other", message.NewSendMessage("not subscribed", "Fred"))
+	d.Send("Lobby", message.NewLeaveMessage("Fred"))
+	got := waitDelivery(t, deliveries)
+	if got.event != EventMessage {
+		t.Errorf("Wrong event expected: %v, got: %v", EventMessage, got.event)
+	}
+	if !Verify(hook.Secret, got.body, got.signature) {
+		t.Error("Signature did not verify: ", got.signature)
+	}
+	payload := new(struct {
+		Event   string
+		Room    string
+		Message message.SendMessage
+	})
+	err := json.Unmarshal(got.body, payload)
+	if err != nil {
+		t.Fatal("Error decoding payload: ", err)
+	}
+	if payload.Room != "Lobby" || payload.Message.Text != "hello" || payload.Message.Sender != "Fred" {
+		t.Errorf("Wrong payload: %+v", payload)
+	}
+	if got = waitDelivery(t, deliveries); got.event != EventLeave {
+		t.Errorf("Wrong event expected: %v, got: %v", EventLeave, got.event)
+	}
+}
+
+func TestRetryAfterFailure(t *testing.T) {
+	srv, deliveries := newTestServer(http.StatusInternalServerError, http.StatusBadGateway)
+	defer srv.Close()
+	d, _ := newTestDispatcher(t, srv.URL, Options{RetryDelay: time.Millisecond})
+	defer d.Close()
+	d.Send("Lobby", message.NewTopicMessage("news", "Fred"))
+	for i := 0; i < 3; i++ {
+		if got := waitDelivery(t, deliveries); got.event != EventTopic {
+			t.Errorf("Wrong event on attempt %v expected: %v, got: %v", i+1, EventTopic, got.event)
+		}
+	}
+	select {
+	case <-deliveries:
+		t.Error("Expected no more attempts after a successful delivery")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSendDoesNotBlock(t *testing.T) {
+	release := make(chan bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+	d, _ := newTestDispatcher(t, srv.URL, Options{QueueSize: 1, Workers: 1})
+	defer d.Close()
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 20; i++ {
+			d.Send("Lobby", message.NewSendMessage("spam", "Fred"))
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Send blocked on a slow endpoint")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := New(filedata.NewMemDataFactory(), Options{RetryDelay: time.Second, MaxRetryDelay: 5 * time.Second})
+	defer d.Close()
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	for i := range expected {
+		if got := d.backoff(i + 1); got != expected[i] {
+			t.Errorf("Wrong backoff for attempt %v expected: %v, got: %v", i+1, expected[i], got)
+		}
+	}
+}